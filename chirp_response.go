@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// viewerID returns the user making a request to a public endpoint, or
+// uuid.Nil if the request is anonymous or its token is not valid.
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.UUID {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		return uuid.Nil
+	}
+	return userID
+}
+
+// chirpResponses converts database chirps into their API representation and
+// attaches everything that lives outside the chirps table. viewerID may be
+// uuid.Nil for anonymous requests.
+func (cfg *apiConfig) chirpResponses(ctx context.Context, chirps []database.Chirp, viewerID uuid.UUID) ([]ChirpResponse, error) {
+	responses := make([]ChirpResponse, 0, len(chirps))
+	ids := make([]uuid.UUID, 0, len(chirps))
+	index := make(map[uuid.UUID]int, len(chirps))
+	for i, chirp := range chirps {
+		responses = append(responses, ChirpResponse{
+			ID:             chirp.ID,
+			CreatedAt:      chirp.CreatedAt,
+			UpdatedAt:      chirp.UpdatedAt,
+			Body:           chirp.Body,
+			UserID:         chirp.UserID,
+			ContentWarning: chirp.ContentWarning,
+			Sensitive:      chirp.Sensitive,
+			Media:          []mediaResponse{},
+			Hidden:         chirp.HiddenAt.Valid,
+		})
+		if chirp.EditedAt.Valid {
+			editedAt := chirp.EditedAt.Time
+			responses[i].EditedAt = &editedAt
+		}
+		if chirp.DeletedAt.Valid {
+			deletedAt := chirp.DeletedAt.Time
+			responses[i].DeletedAt = &deletedAt
+		}
+		if chirp.ReplyToID.Valid {
+			replyToID := chirp.ReplyToID.UUID
+			responses[i].ReplyToID = &replyToID
+		}
+		ids = append(ids, chirp.ID)
+		index[chirp.ID] = i
+	}
+	if len(chirps) == 0 {
+		return responses, nil
+	}
+
+	objects, err := cfg.dbQueries.GetMediaForChirps(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, object := range objects {
+		i := index[object.ChirpID.UUID]
+		responses[i].Media = append(responses[i].Media, newMediaResponse(object))
+	}
+
+	err = cfg.attachPolls(ctx, responses, index, ids, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cfg.attachLikes(ctx, responses, index, ids, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Authors see their own chirps without the interstitial.
+	warned, err := cfg.dbQueries.GetWarnedChirpIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range warned {
+		i := index[id]
+		responses[i].Warned = responses[i].UserID != viewerID
+	}
+
+	err = cfg.collapseSensitive(ctx, responses, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	return responses, nil
+}
+
+// collapseSensitive sets Collapsed on chirps with a content warning or
+// sensitive media, unless the viewer wrote them or prefers them expanded.
+// Anonymous viewers always see them collapsed. The viewer's preference is
+// only looked up once a chirp needs it.
+func (cfg *apiConfig) collapseSensitive(ctx context.Context, responses []ChirpResponse, viewerID uuid.UUID) error {
+	checked := false
+	for i := range responses {
+		resp := &responses[i]
+		if resp.ContentWarning == "" && !resp.Sensitive || resp.UserID == viewerID {
+			continue
+		}
+		if viewerID != uuid.Nil && !checked {
+			viewer, err := cfg.dbQueries.GetUserByID(ctx, viewerID)
+			if err != nil {
+				return err
+			}
+			if viewer.ExpandSensitive {
+				return nil
+			}
+			checked = true
+		}
+		resp.Collapsed = true
+	}
+	return nil
+}
+
+func (cfg *apiConfig) chirpResponse(ctx context.Context, chirp database.Chirp, viewerID uuid.UUID) (ChirpResponse, error) {
+	responses, err := cfg.chirpResponses(ctx, []database.Chirp{chirp}, viewerID)
+	if err != nil {
+		return ChirpResponse{}, err
+	}
+	return responses[0], nil
+}
+
+func (cfg *apiConfig) attachLikes(ctx context.Context, responses []ChirpResponse, index map[uuid.UUID]int, chirpIDs []uuid.UUID, viewerID uuid.UUID) error {
+	counts, err := cfg.dbQueries.GetLikeCountsForChirps(ctx, chirpIDs)
+	if err != nil || len(counts) == 0 {
+		return err
+	}
+	for _, count := range counts {
+		responses[index[count.ChirpID]].Likes = count.Likes
+	}
+	if viewerID == uuid.Nil {
+		return nil
+	}
+	liked, err := cfg.dbQueries.GetLikedChirpIDs(ctx, database.GetLikedChirpIDsParams{
+		UserID:   viewerID,
+		ChirpIds: chirpIDs,
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range liked {
+		responses[index[id]].Liked = true
+	}
+	return nil
+}
+
+func (cfg *apiConfig) attachPolls(ctx context.Context, responses []ChirpResponse, index map[uuid.UUID]int, chirpIDs []uuid.UUID, viewerID uuid.UUID) error {
+	polls, err := cfg.dbQueries.GetPollsForChirps(ctx, chirpIDs)
+	if err != nil || len(polls) == 0 {
+		return err
+	}
+	pollIDs := make([]uuid.UUID, 0, len(polls))
+	byID := make(map[uuid.UUID]*pollResponse, len(polls))
+	now := time.Now().UTC()
+	for _, poll := range polls {
+		resp := &pollResponse{
+			ID:       poll.ID,
+			ClosesAt: poll.ClosesAt,
+			Closed:   !now.Before(poll.ClosesAt),
+			Options:  []pollOptionResponse{},
+		}
+		resp.ResultsVisible = resp.Closed
+		responses[index[poll.ChirpID]].Poll = resp
+		byID[poll.ID] = resp
+		pollIDs = append(pollIDs, poll.ID)
+	}
+
+	if viewerID != uuid.Nil {
+		votes, err := cfg.dbQueries.GetUserPollVotes(ctx, database.GetUserPollVotesParams{
+			UserID:  viewerID,
+			PollIds: pollIDs,
+		})
+		if err != nil {
+			return err
+		}
+		for _, vote := range votes {
+			optionID := vote.OptionID
+			byID[vote.PollID].ViewerVote = &optionID
+			byID[vote.PollID].ResultsVisible = true
+		}
+	}
+
+	results, err := cfg.dbQueries.GetPollResults(ctx, pollIDs)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		poll := byID[result.PollID]
+		option := pollOptionResponse{
+			ID:    result.ID,
+			Label: result.Label,
+		}
+		if poll.ResultsVisible {
+			votes := result.Votes
+			option.Votes = &votes
+			if poll.TotalVotes == nil {
+				poll.TotalVotes = new(int64)
+			}
+			*poll.TotalVotes += votes
+		}
+		poll.Options = append(poll.Options, option)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/digest"
+	"github.com/Throne-of-Doom/chirpy/internal/mail"
+	"github.com/google/uuid"
+)
+
+const (
+	digestInterval  = 15 * time.Minute
+	digestBatchSize = 20
+	// Users active more recently than digestInactiveAfter get no digest.
+	digestInactiveAfter = 24 * time.Hour
+	// digestSlack lets a digest go out a little early, so that send times do
+	// not drift later by up to an interval every period.
+	digestSlack            = time.Hour
+	digestMaxNotifications = 10
+	digestMaxChirps        = 5
+	digestSendTimeout      = 30 * time.Second
+)
+
+// runDigests sends the email digests that are due every interval until ctx
+// is cancelled.
+func (cfg *apiConfig) runDigests(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for {
+			claimed, err := cfg.sendDueDigests(ctx)
+			if err != nil {
+				log.Printf("couldn't send digests: %v", err)
+				break
+			}
+			if claimed < digestBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// sendDueDigests sends one batch of due digests and returns how many users
+// it claimed. Users are claimed with FOR UPDATE SKIP LOCKED and marked as
+// sent before anything is sent, so instances running at once never email
+// the same user twice. A digest that then fails to send is logged and not
+// retried; the next one picks up from there.
+func (cfg *apiConfig) sendDueDigests(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	due, err := qtx.ClaimDueDigests(ctx, database.ClaimDueDigestsParams{
+		DefaultFrequency: string(digest.Default),
+		ActiveBefore:     now.Add(-digestInactiveAfter),
+		DailyBefore:      now.Add(-digest.Daily.Period() + digestSlack),
+		WeeklyBefore:     now.Add(-digest.Weekly.Period() + digestSlack),
+		RowLimit:         digestBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, row := range due {
+		err = qtx.MarkDigestSent(ctx, database.MarkDigestSentParams{
+			UserID:    row.ID,
+			Frequency: row.Frequency,
+			SentAt:    now,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, row := range due {
+		err = cfg.sendDigest(ctx, row, now)
+		if err != nil {
+			log.Printf("couldn't send digest to user %s: %v", row.ID, err)
+		}
+	}
+	return len(due), nil
+}
+
+// sendDigest emails a user what happened since their last digest: their
+// new unread notifications and the most liked chirps from the users they
+// follow. Nothing is sent if there is neither.
+func (cfg *apiConfig) sendDigest(ctx context.Context, row database.ClaimDueDigestsRow, now time.Time) error {
+	frequency := digest.Frequency(row.Frequency)
+	since := now.Add(-frequency.Period())
+	if row.LastSentAt.Valid {
+		since = row.LastSentAt.Time
+	}
+
+	unread, err := cfg.dbQueries.ListNotifications(ctx, database.ListNotificationsParams{
+		UserID:     row.ID,
+		UnreadOnly: true,
+		RowLimit:   digestMaxNotifications,
+	})
+	if err != nil {
+		return err
+	}
+	fresh := make([]database.ListNotificationsRow, 0, len(unread))
+	for _, n := range unread {
+		if n.UpdatedAt.After(since) {
+			fresh = append(fresh, n)
+		}
+	}
+	notifications, err := cfg.notificationResponses(ctx, fresh)
+	if err != nil {
+		return err
+	}
+	unreadCount, err := cfg.dbQueries.CountUnreadNotifications(ctx, row.ID)
+	if err != nil {
+		return err
+	}
+
+	mutes, err := cfg.loadKeywordMutes(ctx, row.ID)
+	if err != nil {
+		return err
+	}
+	// Fetch extra chirps so that a few muted ones still leave enough.
+	top, err := cfg.dbQueries.ListTopFolloweeChirps(ctx, database.ListTopFolloweeChirpsParams{
+		ViewerID: row.ID,
+		Since:    since,
+		RowLimit: 2 * digestMaxChirps,
+	})
+	if err != nil {
+		return err
+	}
+
+	d := digest.Digest{
+		Handle:         row.Handle,
+		Frequency:      frequency,
+		UnreadCount:    unreadCount,
+		AppURL:         cfg.publicURL + "/app/",
+		UnsubscribeURL: cfg.unsubscribeURL(row.ID),
+	}
+	for _, n := range notifications {
+		d.Notifications = append(d.Notifications, n.Summary)
+	}
+	for _, chirp := range top {
+		if len(d.Chirps) == digestMaxChirps {
+			break
+		}
+		if mutes.hides(chirp.UserID, chirp.Body, chirp.ContentWarning) {
+			continue
+		}
+		body := chirp.Body
+		// Chirps behind a content warning stay behind it in email.
+		if chirp.ContentWarning != "" {
+			body = "Content warning: " + chirp.ContentWarning
+		}
+		d.Chirps = append(d.Chirps, digest.Chirp{Handle: chirp.Handle, Body: body, Likes: chirp.Likes})
+	}
+	if d.Empty() {
+		return nil
+	}
+
+	text, html, err := digest.Render(d)
+	if err != nil {
+		return err
+	}
+	sendCtx, cancel := context.WithTimeout(ctx, digestSendTimeout)
+	defer cancel()
+	return cfg.mailer.Send(sendCtx, mail.Message{
+		To:      row.Email,
+		Subject: d.Subject(),
+		Text:    text,
+		HTML:    html,
+		// Lets mail clients offer their own unsubscribe button, which
+		// POSTs to the URL (RFC 8058).
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + d.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	})
+}
+
+// touchUserActivity records that a user is around, which holds back their
+// digests. Failing to record it is only logged, since it must not stop
+// anyone signing in.
+func (cfg *apiConfig) touchUserActivity(ctx context.Context, userID uuid.UUID) {
+	err := cfg.dbQueries.TouchUserActivity(ctx, userID)
+	if err != nil {
+		log.Printf("couldn't record activity for user %s: %v", userID, err)
+	}
+}
+
+// unsubscribeURL is the one-click unsubscribe link for a user's digests.
+func (cfg *apiConfig) unsubscribeURL(userID uuid.UUID) string {
+	return cfg.publicURL + "/api/digest/unsubscribe?token=" + url.QueryEscape(auth.MakeUnsubscribeToken(userID, cfg.SECRET))
+}
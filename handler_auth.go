@@ -27,6 +27,9 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, 401, "incorrect email or password", err)
 		return
 	}
+	if !cfg.checkNotSuspended(w, r, dbUser.ID) {
+		return
+	}
 
 	expiresIn := time.Hour
 	token, err := auth.MakeJWT(dbUser.ID, cfg.SECRET, expiresIn)
@@ -52,14 +55,21 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, 500, "couldn't save refresh token", err)
 		return
 	}
+	isChirpyRed, _, err := chirpyRed(r.Context(), cfg.dbQueries, dbUser.ID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load subscription", err)
+		return
+	}
+	cfg.touchUserActivity(r.Context(), dbUser.ID)
 	resp := loginResponse{
 		ID:           dbUser.ID,
 		CreatedAt:    dbUser.CreatedAt,
 		UpdatedAt:    dbUser.UpdatedAt,
 		Email:        dbUser.Email,
+		Handle:       dbUser.Handle,
 		Token:        token,
 		RefreshToken: refreshToken,
-		IsChirpyRed:  dbUser.IsChirpyRed,
+		IsChirpyRed:  isChirpyRed,
 	}
 	respondWithJSON(w, 200, resp)
 }
@@ -79,12 +89,16 @@ func (cfg *apiConfig) refreshHandler(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, 401, "invalid or expired refresh token", err)
 		return
 	}
+	if !cfg.checkNotSuspended(w, r, user.ID) {
+		return
+	}
 
 	newToken, err := auth.MakeJWT(user.ID, cfg.SECRET, time.Hour)
 	if err != nil {
 		respondWithError(w, 500, "couldn't create token", err)
 		return
 	}
+	cfg.touchUserActivity(r.Context(), user.ID)
 
 	resp := refreshResponse{
 		Token: newToken,
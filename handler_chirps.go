@@ -1,18 +1,40 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
-	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Throne-of-Doom/chirpy/internal/auth"
 	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/events"
+	"github.com/Throne-of-Doom/chirpy/internal/paging"
+	"github.com/Throne-of-Doom/chirpy/internal/spam"
 	"github.com/google/uuid"
 )
 
+const (
+	maxContentWarningLength = 100
+	// maxChirpPageBatches bounds how many times a chirp listing fetches more
+	// chirps to make up for ones the viewer muted.
+	maxChirpPageBatches = 5
+)
+
+// validateContentWarning trims a content warning and checks its length. The
+// result still needs to go through the content filter like the body does.
+func validateContentWarning(warning string) (string, error) {
+	warning = strings.TrimSpace(warning)
+	if utf8.RuneCountInString(warning) > maxContentWarningLength {
+		return "", fmt.Errorf("content warnings are limited to %d characters", maxContentWarningLength)
+	}
+	return warning, nil
+}
+
 func (cfg *apiConfig) createChirpsHandler(w http.ResponseWriter, r *http.Request) {
 	token, err := auth.GetBearerToken(r.Header)
 	if err != nil {
@@ -25,13 +47,16 @@ func (cfg *apiConfig) createChirpsHandler(w http.ResponseWriter, r *http.Request
 		respondWithError(w, 401, "invalid or expired token", err)
 		return
 	}
-	type data struct {
-		Body string `json:"body"`
+	if !cfg.checkNotSuspended(w, r, userID) {
+		return
 	}
-	profaneWords := map[string]struct{}{
-		"kerfuffle": {},
-		"sharbert":  {},
-		"fornax":    {},
+	type data struct {
+		Body           string          `json:"body"`
+		ContentWarning string          `json:"content_warning"`
+		Sensitive      bool            `json:"sensitive"`
+		Poll           *pollParameters `json:"poll"`
+		PublishAt      *time.Time      `json:"publish_at"`
+		ReplyTo        *uuid.UUID      `json:"reply_to"`
 	}
 	decoder := json.NewDecoder(r.Body)
 	params := data{}
@@ -40,87 +65,336 @@ func (cfg *apiConfig) createChirpsHandler(w http.ResponseWriter, r *http.Request
 		respondWithError(w, 500, "couldn't decode parameters", err)
 		return
 	}
-	if len(params.Body) > 140 {
-		err := errors.New("chirp is too long, limit 140 characters")
-		respondWithError(w, 400, "Chirp is too long, Limit 140 Characters", err)
+	dbUser, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "user not found", err)
+		return
+	}
+	limits, err := userEntitlements(r.Context(), cfg.dbQueries, dbUser)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load entitlements", err)
+		return
+	}
+	err = limits.CheckChirpLength(params.Body)
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+	warning, err := validateContentWarning(params.ContentWarning)
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
 		return
 	}
 
-	cleaned := profaneReplace(params.Body, profaneWords)
+	var (
+		pollOptions  []string
+		pollClosesAt time.Time
+	)
+	if params.Poll != nil {
+		pollOptions, pollClosesAt, err = validatePoll(params.Poll, time.Now().UTC())
+		if err != nil {
+			respondWithError(w, 400, err.Error(), err)
+			return
+		}
+	}
+
+	checked := cfg.moderation.Check(params.Body)
+	cleaned := checked.Text
+	matches := checked.Matches
+	rejected := checked.Rejected()
+	checkedWarning := cfg.moderation.Check(warning)
+	warning = checkedWarning.Text
+	matches = append(matches, checkedWarning.Matches...)
+	rejected = rejected || checkedWarning.Rejected()
+	for i, option := range pollOptions {
+		checked := cfg.moderation.Check(option)
+		pollOptions[i] = checked.Text
+		matches = append(matches, checked.Matches...)
+		rejected = rejected || checked.Rejected()
+	}
+	if rejected {
+		respondWithError(w, 400, "chirp contains disallowed content", nil)
+		return
+	}
+
+	// Replies can only be made to chirps the author can see.
+	var replyToID uuid.NullUUID
+	if params.ReplyTo != nil {
+		parent, err := cfg.dbQueries.GetChirp(r.Context(), database.GetChirpParams{
+			ID:       *params.ReplyTo,
+			ViewerID: userID,
+		})
+		if err != nil {
+			respondWithError(w, 404, "chirp being replied to not found", err)
+			return
+		}
+		replyToID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
+
+	if params.PublishAt != nil && params.PublishAt.After(time.Now().UTC()) {
+		if params.Poll != nil {
+			respondWithError(w, 400, "chirps with polls cannot be scheduled", nil)
+			return
+		}
+		if replyToID.Valid {
+			respondWithError(w, 400, "replies cannot be scheduled", nil)
+			return
+		}
+		draft, err := cfg.dbQueries.CreateDraft(r.Context(), database.CreateDraftParams{
+			UserID:         userID,
+			Body:           cleaned,
+			PublishAt:      sql.NullTime{Time: params.PublishAt.UTC(), Valid: true},
+			ContentWarning: warning,
+			Sensitive:      params.Sensitive,
+		})
+		if err != nil {
+			respondWithError(w, 500, "couldn't schedule chirp", err)
+			return
+		}
+		respondWithJSON(w, http.StatusAccepted, newDraftResponse(draft))
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	scored, err := cfg.scoreChirp(r.Context(), qtx, userID, uuid.Nil, cleaned)
+	if err != nil {
+		respondWithError(w, 500, "couldn't check chirp for spam", err)
+		return
+	}
+	if scored.Decision == spam.Reject {
+		err = recordSpamScore(r.Context(), qtx, userID, uuid.NullUUID{}, cleaned, scored)
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			respondWithError(w, 500, "couldn't call database", err)
+			return
+		}
+		respondWithError(w, 400, "chirp was rejected as spam", nil)
+		return
+	}
+	var hiddenAt sql.NullTime
+	if scored.Decision == spam.Hold {
+		hiddenAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	}
 
-	dbChirp, err := cfg.dbQueries.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   cleaned,
-		UserID: userID,
+	dbChirp, err := qtx.CreateChirp(r.Context(), database.CreateChirpParams{
+		Body:           cleaned,
+		UserID:         userID,
+		HiddenAt:       hiddenAt,
+		ContentWarning: warning,
+		Sensitive:      params.Sensitive,
+		ReplyToID:      replyToID,
 	})
 	if err != nil {
 		respondWithError(w, 500, "couldn't call database", err)
 		return
 	}
+	err = recordSpamScore(r.Context(), qtx, userID, uuid.NullUUID{UUID: dbChirp.ID, Valid: true}, cleaned, scored)
+	if err != nil {
+		respondWithError(w, 500, "couldn't call database", err)
+		return
+	}
+	err = recordFlags(r.Context(), qtx, dbChirp.ID, matches)
+	if err != nil {
+		respondWithError(w, 500, "couldn't call database", err)
+		return
+	}
+	// Held chirps are announced if a moderator approves them.
+	if !dbChirp.HiddenAt.Valid {
+		err = events.Append(r.Context(), qtx, events.ChirpCreated, userID, newChirpEvent(dbChirp))
+		if err != nil {
+			respondWithError(w, 500, "couldn't call database", err)
+			return
+		}
+	}
+	if pollOptions != nil {
+		err = createPoll(r.Context(), qtx, dbChirp.ID, pollOptions, pollClosesAt)
+		if err != nil {
+			respondWithError(w, 500, "couldn't create poll", err)
+			return
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "couldn't call database", err)
+		return
+	}
 
-	type chirp struct {
-		ID        uuid.UUID `json:"id"`
-		CreatedAt time.Time `json:"created_at"`
-		UpdatedAt time.Time `json:"updated_at"`
-		Body      string    `json:"body"`
-		UserID    uuid.UUID `json:"user_id"`
+	resp, err := cfg.chirpResponse(r.Context(), dbChirp, userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load chirp", err)
+		return
 	}
+	respondWithJSON(w, createdChirpStatus(dbChirp), resp)
+}
 
-	resp := chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID,
+// createdChirpStatus is 202 for a chirp held for spam review, since it was
+// saved but is not public yet, and 201 otherwise.
+func createdChirpStatus(chirp database.Chirp) int {
+	if chirp.HiddenAt.Valid {
+		return http.StatusAccepted
 	}
-	respondWithJSON(w, http.StatusCreated, resp)
+	return http.StatusCreated
 }
 
+// getChirpsHandler lists chirps a page at a time, oldest first, or newest
+// first with sort=desc. The Link header points at the neighbouring pages:
+// rel="next" carries on with ?cursor= and rel="prev" goes back with ?before=.
+// since and until narrow the listing to a time range. An author's listing
+// without a time range starts with their pinned chirps on the first page,
+// counted against the limit, and leaves them out of the pages after.
 func (cfg *apiConfig) getChirpsHandler(w http.ResponseWriter, r *http.Request) {
-	chirps, err := cfg.dbQueries.GetChirps(r.Context())
-	if err != nil {
-		respondWithError(w, 500, "an error has occured", err)
-		return
-	}
-	urlSort := ""
-	authorIDStr := r.URL.Query().Get("author_id")
-	urlSort = r.URL.Query().Get("sort")
 	var authorUUID uuid.UUID
-	var filterByAuthor bool = false
-
-	if authorIDStr != "" {
+	filterByAuthor := false
+	var err error
+	if authorIDStr := r.URL.Query().Get("author_id"); authorIDStr != "" {
 		authorUUID, err = uuid.Parse(authorIDStr)
-
 		if err != nil {
 			respondWithError(w, 400, "Invalid author_id", err)
 			return
 		}
 		filterByAuthor = true
 	}
+	descending := false
+	switch r.URL.Query().Get("sort") {
+	case "", "asc":
+	case "desc":
+		descending = true
+	default:
+		respondWithError(w, 400, `sort must be "asc" or "desc"`, nil)
+		return
+	}
+	limit, err := parseLimit(r, defaultListLimit, maxListLimit)
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+	since, hasSince, err := parseTimeParam(r, "since")
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+	until, hasUntil, err := parseTimeParam(r, "until")
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+	if hasSince && hasUntil && !since.Before(until) {
+		respondWithError(w, 400, "since must be before until", nil)
+		return
+	}
+	cursor, hasCursor, err := parseCursor(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+	before, hasBefore, err := parseCursorParam(r, "before")
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+	if hasCursor && hasBefore {
+		respondWithError(w, 400, "cursor and before can't be used together", nil)
+		return
+	}
+	if hasBefore {
+		cursor = before
+	}
 
-	responseChirps := []ChirpResponse{}
+	viewerID := cfg.viewerID(r)
+	mutes, err := cfg.loadKeywordMutes(r.Context(), viewerID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load muted keywords", err)
+		return
+	}
+	// Pinned chirps are left out of the timeline itself. On the first page
+	// they come first and take their share of the limit.
+	isPinned := map[uuid.UUID]struct{}{}
+	var pinned []ChirpResponse
+	if filterByAuthor && !hasSince && !hasUntil {
+		all, err := cfg.pinnedChirpResponses(r.Context(), authorUUID, viewerID)
+		if err != nil {
+			respondWithError(w, 500, "couldn't load pinned chirps", err)
+			return
+		}
+		for _, chirp := range all {
+			isPinned[chirp.ID] = struct{}{}
+			if !hasCursor && !hasBefore && len(pinned) < int(limit) && !mutes.hides(chirp.UserID, chirp.Body, chirp.ContentWarning) {
+				pinned = append(pinned, chirp)
+			}
+		}
+	}
+	slots := int(limit) - len(pinned)
 
-	for _, chirp := range chirps {
-		if filterByAuthor && chirp.UserID != authorUUID {
-			continue
+	// The global timeline is discovery, so limited chirps are left out of it;
+	// an author's own listing is not. Chirps the viewer muted are skipped,
+	// and more are fetched in their place until the page is full.
+	params := database.GetChirpsAscendingParams{
+		ViewerID:  viewerID,
+		Discovery: !filterByAuthor,
+		HasAuthor: filterByAuthor,
+		AuthorID:  authorUUID,
+		HasSince:  hasSince,
+		Since:     since,
+		HasUntil:  hasUntil,
+		Until:     until,
+	}
+	fetch := func(after paging.Cursor, hasAfter, ascending bool, n int) ([]database.Chirp, error) {
+		params.HasCursor, params.CursorTime, params.CursorID = hasAfter, after.Time, after.ID
+		params.RowLimit = int32(n)
+		// The two queries differ only in their order and take the same
+		// parameters.
+		if ascending {
+			return cfg.dbQueries.GetChirpsAscending(r.Context(), params)
 		}
-		responseChirps = append(responseChirps, ChirpResponse{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-		})
+		return cfg.dbQueries.GetChirpsDescending(r.Context(), database.GetChirpsDescendingParams(params))
 	}
-	if urlSort == "asc" || urlSort == "" {
-		sort.Slice(responseChirps, func(i, j int) bool {
-			return responseChirps[i].CreatedAt.Before(responseChirps[j].CreatedAt)
-		})
-	} else if urlSort == "desc" {
-		sort.Slice(responseChirps, func(i, j int) bool {
-			return responseChirps[i].CreatedAt.After(responseChirps[j].CreatedAt)
-		})
+	page, err := paging.Fill(paging.Request{
+		Descending: descending,
+		Cursor:     paging.Cursor(cursor),
+		HasCursor:  hasCursor || hasBefore,
+		Before:     hasBefore,
+		Size:       slots,
+		Batch:      int(limit) + 1,
+		MaxBatches: maxChirpPageBatches,
+	}, func(chirp database.Chirp) paging.Cursor {
+		return paging.Cursor{Time: chirp.CreatedAt, ID: chirp.ID}
+	}, func(chirp database.Chirp) bool {
+		_, ok := isPinned[chirp.ID]
+		return ok || mutes.hides(chirp.UserID, chirp.Body, chirp.ContentWarning)
+	}, fetch)
+	if err != nil {
+		respondWithError(w, 500, "an error has occured", err)
+		return
+	}
+
+	var links []string
+	if page.HasNext {
+		links = append(links, pageLink(r, "next", "cursor", listCursor(page.Next)))
+	}
+	if page.HasPrev {
+		links = append(links, pageLink(r, "prev", "before", listCursor(page.Prev)))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+
+	responseChirps, err := cfg.chirpResponses(r.Context(), page.Items, viewerID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load chirps", err)
+		return
 	}
-	respondWithJSON(w, 200, responseChirps)
+	resp := make([]ChirpResponse, 0, len(pinned)+len(responseChirps))
+	resp = append(resp, pinned...)
+	respondWithJSON(w, 200, append(resp, responseChirps...))
 }
 
 func (cfg *apiConfig) getChirpHandler(w http.ResponseWriter, r *http.Request) {
@@ -130,33 +404,170 @@ func (cfg *apiConfig) getChirpHandler(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, 400, "invalid chirp ID", err)
 		return
 	}
-	chirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+	viewerID := cfg.viewerID(r)
+	chirp, err := cfg.dbQueries.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       chirpID,
+		ViewerID: viewerID,
+	})
 	if err != nil {
 		respondWithError(w, 404, "chirp not found", err)
 		return
 	}
-	response := ChirpResponse{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
+	response, err := cfg.chirpResponse(r.Context(), chirp, viewerID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load chirp", err)
+		return
 	}
 
 	respondWithJSON(w, 200, response)
 }
 
-func profaneReplace(msg string, profaneWords map[string]struct{}) string {
-	censor := "****"
-	words := strings.Split(msg, " ")
-	for i, word := range words {
-		lowered := strings.ToLower(word)
-		if _, ok := profaneWords[lowered]; ok {
-			words[i] = censor
+// editChirpHandler replaces the body of one of the caller's chirps. Only
+// plans with an edit window can edit, and only until the window has passed
+// since the chirp was posted. Edits are spam checked like new chirps, and
+// respond 202 if the chirp is held for review.
+func (cfg *apiConfig) editChirpHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	if !cfg.checkNotSuspended(w, r, userID) {
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid chirp ID", err)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, "couldn't decode parameters", err)
+		return
+	}
+
+	chirp, err := cfg.dbQueries.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 404, "chirp not found", err)
+		return
+	}
+	if chirp.UserID != userID {
+		respondWithError(w, 403, "cannot edit chirp", nil)
+		return
+	}
+	limits, err := cfg.entitlementsForUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load entitlements", err)
+		return
+	}
+	if limits.EditWindow == 0 {
+		respondWithError(w, 403, "editing chirps requires Chirpy Red", nil)
+		return
+	}
+	if !limits.CanEdit(chirp.CreatedAt, time.Now().UTC()) {
+		respondWithError(w, 403, fmt.Sprintf("chirps can only be edited for %s after posting", limits.EditWindow), nil)
+		return
+	}
+	err = limits.CheckChirpLength(params.Body)
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+	checked := cfg.moderation.Check(params.Body)
+	if checked.Rejected() {
+		respondWithError(w, 400, "chirp contains disallowed content", nil)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	// Edits go through the spam check too, or a harmless chirp could be
+	// edited into one that would have been held or rejected.
+	scored, err := cfg.scoreChirp(r.Context(), qtx, userID, chirp.ID, checked.Text)
+	if err != nil {
+		respondWithError(w, 500, "couldn't check chirp for spam", err)
+		return
+	}
+	if scored.Decision == spam.Reject {
+		err = recordSpamScore(r.Context(), qtx, userID, uuid.NullUUID{}, checked.Text, scored)
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			respondWithError(w, 500, "couldn't call database", err)
+			return
+		}
+		respondWithError(w, 400, "chirp was rejected as spam", nil)
+		return
+	}
+
+	// The chirp may have been deleted since it was read above.
+	edited, err := qtx.EditChirp(r.Context(), database.EditChirpParams{
+		ID:   chirp.ID,
+		Body: checked.Text,
+		Hold: scored.Decision == spam.Hold,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "chirp not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "couldn't edit chirp", err)
+		return
+	}
+	err = recordSpamScore(r.Context(), qtx, userID, uuid.NullUUID{UUID: edited.ID, Valid: true}, checked.Text, scored)
+	if err != nil {
+		respondWithError(w, 500, "couldn't edit chirp", err)
+		return
+	}
+	err = recordFlags(r.Context(), qtx, edited.ID, checked.Matches)
+	if err != nil {
+		respondWithError(w, 500, "couldn't edit chirp", err)
+		return
+	}
+	// Held edits are announced if a moderator approves them.
+	if !edited.HiddenAt.Valid {
+		err = events.Append(r.Context(), qtx, events.ChirpEdited, userID, newChirpEvent(edited))
+		if err != nil {
+			respondWithError(w, 500, "couldn't edit chirp", err)
+			return
 		}
 	}
-	cleaned := strings.Join(words, " ")
-	return cleaned
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "couldn't edit chirp", err)
+		return
+	}
+
+	resp, err := cfg.chirpResponse(r.Context(), edited, userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load chirp", err)
+		return
+	}
+	status := 200
+	if edited.HiddenAt.Valid {
+		status = http.StatusAccepted
+	}
+	respondWithJSON(w, status, resp)
 }
 
 func (cfg *apiConfig) deleteChirpsHandler(w http.ResponseWriter, r *http.Request) {
@@ -177,7 +588,10 @@ func (cfg *apiConfig) deleteChirpsHandler(w http.ResponseWriter, r *http.Request
 		respondWithError(w, 400, "invalid chirp ID", err)
 		return
 	}
-	chirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+	chirp, err := cfg.dbQueries.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
 	if err != nil {
 		respondWithError(w, 404, "chirp not found", err)
 		return
@@ -186,9 +600,36 @@ func (cfg *apiConfig) deleteChirpsHandler(w http.ResponseWriter, r *http.Request
 		respondWithError(w, 403, "cannot delete chirp", nil)
 		return
 	}
-	err = cfg.dbQueries.DeleteChirp(r.Context(), chirpID)
+	// A chirp stays unpinned if it is restored from the trash.
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	err = qtx.TrashChirp(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, 500, "cannot delete chirp", err)
+		return
+	}
+	err = events.Append(r.Context(), qtx, events.ChirpDeleted, userID, chirpDeletedEvent{
+		ID:        chirp.ID,
+		UserID:    chirp.UserID,
+		DeletedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot delete chirp", err)
+		return
+	}
+	_, err = qtx.UnpinChirp(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, 500, "cannot delete chirp", err)
+		return
+	}
+	err = tx.Commit()
 	if err != nil {
-		respondWithError(w, 404, "cannot delete chirp", err)
+		respondWithError(w, 500, "cannot delete chirp", err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/digest"
+	"github.com/google/uuid"
+)
+
+// digestPreference returns how often userID gets a digest and when the last
+// one was sent.
+func (cfg *apiConfig) digestPreference(r *http.Request, userID uuid.UUID) (digestPreferenceResponse, error) {
+	resp := digestPreferenceResponse{Frequency: string(digest.Default)}
+	pref, err := cfg.dbQueries.GetDigestPreference(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return resp, nil
+	}
+	if err != nil {
+		return resp, err
+	}
+	resp.Frequency = pref.Frequency
+	if pref.LastSentAt.Valid {
+		lastSentAt := pref.LastSentAt.Time
+		resp.LastSentAt = &lastSentAt
+	}
+	return resp, nil
+}
+
+func (cfg *apiConfig) getDigestPreferenceHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	resp, err := cfg.digestPreference(r, userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load digest preferences", err)
+		return
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+// updateDigestPreferenceHandler sets how often the caller gets a digest:
+// daily, weekly or off.
+func (cfg *apiConfig) updateDigestPreferenceHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	type parameters struct {
+		Frequency string `json:"frequency"`
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, "couldn't decode parameters", err)
+		return
+	}
+	if !digest.ValidFrequency(digest.Frequency(params.Frequency)) {
+		respondWithError(w, 400, "frequency must be daily, weekly or off", nil)
+		return
+	}
+	_, err = cfg.dbQueries.SetDigestFrequency(r.Context(), database.SetDigestFrequencyParams{
+		UserID:    userID,
+		Frequency: params.Frequency,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't update digest preferences", err)
+		return
+	}
+	resp, err := cfg.digestPreference(r, userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load digest preferences", err)
+		return
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+// unsubscribePageHandler is where the unsubscribe link in a digest leads. It
+// only shows a button, since mail scanners open links in emails and must
+// not unsubscribe anyone by doing so.
+func (cfg *apiConfig) unsubscribePageHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	_, err := auth.ValidateUnsubscribeToken(token, cfg.SECRET)
+	if err != nil {
+		respondWithHTML(w, 400, "This unsubscribe link is not valid.")
+		return
+	}
+	action := "/api/digest/unsubscribe?token=" + token
+	respondWithHTML(w, 200, fmt.Sprintf(
+		`<form method="post" action="%s"><p>Stop getting Chirpy digest emails?</p><button type="submit">Unsubscribe</button></form>`,
+		html.EscapeString(action),
+	))
+}
+
+// unsubscribeHandler turns a user's digests off. It takes the token from the
+// link instead of an access token, and is what mail clients POST to for
+// one-click unsubscribes (RFC 8058).
+func (cfg *apiConfig) unsubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ValidateUnsubscribeToken(r.URL.Query().Get("token"), cfg.SECRET)
+	if err != nil {
+		respondWithHTML(w, 400, "This unsubscribe link is not valid.")
+		return
+	}
+	_, err = cfg.dbQueries.SetDigestFrequency(r.Context(), database.SetDigestFrequencyParams{
+		UserID:    userID,
+		Frequency: string(digest.Off),
+	})
+	if err != nil {
+		respondWithHTML(w, 500, "Something went wrong. Please try again later.")
+		return
+	}
+	respondWithHTML(w, 200, "You will no longer get Chirpy digest emails. You can turn them back on in your settings.")
+}
+
+// respondWithHTML writes a minimal page for the people who follow links from
+// emails in a browser. body is not escaped.
+func respondWithHTML(w http.ResponseWriter, code int, body string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>Chirpy</title></head><body>%s</body></html>\n", body)
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/entitlements"
+	"github.com/Throne-of-Doom/chirpy/internal/events"
+	"github.com/Throne-of-Doom/chirpy/internal/moderation"
+	"github.com/Throne-of-Doom/chirpy/internal/spam"
+	"github.com/google/uuid"
+)
+
+type draftParameters struct {
+	Body           string     `json:"body"`
+	ContentWarning string     `json:"content_warning"`
+	Sensitive      bool       `json:"sensitive"`
+	PublishAt      *time.Time `json:"publish_at"`
+}
+
+// validDraft is a draft that has passed validation, with its body and
+// content warning filtered.
+type validDraft struct {
+	Body           string
+	ContentWarning string
+	Sensitive      bool
+	PublishAt      sql.NullTime
+}
+
+// validate checks a draft the same way a chirp is checked when it is posted,
+// against the limits of the author's plan.
+func (params draftParameters) validate(filter *moderation.Filter, limits entitlements.Entitlements) (validDraft, error) {
+	err := limits.CheckChirpLength(params.Body)
+	if err != nil {
+		return validDraft{}, err
+	}
+	warning, err := validateContentWarning(params.ContentWarning)
+	if err != nil {
+		return validDraft{}, err
+	}
+	publishAt := sql.NullTime{}
+	if params.PublishAt != nil {
+		if !params.PublishAt.After(time.Now().UTC()) {
+			return validDraft{}, errors.New("publish_at must be in the future")
+		}
+		publishAt = sql.NullTime{Time: params.PublishAt.UTC(), Valid: true}
+	}
+	checked := filter.Check(params.Body)
+	checkedWarning := filter.Check(warning)
+	if checked.Rejected() || checkedWarning.Rejected() {
+		return validDraft{}, errors.New("chirp contains disallowed content")
+	}
+	return validDraft{
+		Body:           checked.Text,
+		ContentWarning: checkedWarning.Text,
+		Sensitive:      params.Sensitive,
+		PublishAt:      publishAt,
+	}, nil
+}
+
+func (cfg *apiConfig) listDraftsHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+
+	var drafts []database.Draft
+	if r.URL.Query().Get("scheduled") == "true" {
+		drafts, err = cfg.dbQueries.ListScheduledDrafts(r.Context(), userID)
+	} else {
+		drafts, err = cfg.dbQueries.ListDrafts(r.Context(), userID)
+	}
+	if err != nil {
+		respondWithError(w, 500, "couldn't list drafts", err)
+		return
+	}
+
+	resp := []draftResponse{}
+	for _, draft := range drafts {
+		resp = append(resp, newDraftResponse(draft))
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+func (cfg *apiConfig) createDraftHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	if !cfg.checkNotSuspended(w, r, userID) {
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := draftParameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, "couldn't decode parameters", err)
+		return
+	}
+	limits, err := cfg.entitlementsForUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load entitlements", err)
+		return
+	}
+	valid, err := params.validate(cfg.moderation, limits)
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+
+	draft, err := cfg.dbQueries.CreateDraft(r.Context(), database.CreateDraftParams{
+		UserID:         userID,
+		Body:           valid.Body,
+		PublishAt:      valid.PublishAt,
+		ContentWarning: valid.ContentWarning,
+		Sensitive:      valid.Sensitive,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't create draft", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, newDraftResponse(draft))
+}
+
+// updateDraftHandler replaces a draft's body and schedule. Sending a null or
+// missing publish_at turns a scheduled chirp back into a plain draft.
+func (cfg *apiConfig) updateDraftHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid draft ID", err)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := draftParameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, "couldn't decode parameters", err)
+		return
+	}
+	limits, err := cfg.entitlementsForUser(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load entitlements", err)
+		return
+	}
+	valid, err := params.validate(cfg.moderation, limits)
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+
+	draft, err := cfg.dbQueries.GetDraft(r.Context(), draftID)
+	if err != nil {
+		respondWithError(w, 404, "draft not found", err)
+		return
+	}
+	if draft.UserID != userID {
+		respondWithError(w, 403, "cannot edit draft", nil)
+		return
+	}
+
+	// The scheduler may publish the draft between the read above and this
+	// update, in which case the row is gone and there is nothing to edit.
+	updated, err := cfg.dbQueries.UpdateDraft(r.Context(), database.UpdateDraftParams{
+		ID:             draft.ID,
+		Body:           valid.Body,
+		PublishAt:      valid.PublishAt,
+		ContentWarning: valid.ContentWarning,
+		Sensitive:      valid.Sensitive,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "draft not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "couldn't update draft", err)
+		return
+	}
+	respondWithJSON(w, 200, newDraftResponse(updated))
+}
+
+func (cfg *apiConfig) deleteDraftHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid draft ID", err)
+		return
+	}
+	draft, err := cfg.dbQueries.GetDraft(r.Context(), draftID)
+	if err != nil {
+		respondWithError(w, 404, "draft not found", err)
+		return
+	}
+	if draft.UserID != userID {
+		respondWithError(w, 403, "cannot delete draft", nil)
+		return
+	}
+	err = cfg.dbQueries.DeleteDraft(r.Context(), draft.ID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't delete draft", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) publishDraftHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	if !cfg.checkNotSuspended(w, r, userID) {
+		return
+	}
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid draft ID", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	draft, err := qtx.GetDraftForUpdate(r.Context(), draftID)
+	if err != nil {
+		respondWithError(w, 404, "draft not found", err)
+		return
+	}
+	if draft.UserID != userID {
+		respondWithError(w, 403, "cannot publish draft", nil)
+		return
+	}
+	chirp, err := cfg.publishDraft(r.Context(), qtx, draft)
+	if err != nil {
+		respondWithError(w, 500, "couldn't publish draft", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "couldn't publish draft", err)
+		return
+	}
+
+	resp, err := cfg.chirpResponse(r.Context(), chirp, userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load chirp", err)
+		return
+	}
+	respondWithJSON(w, createdChirpStatus(chirp), resp)
+}
+
+// publishDraft turns a locked draft into a chirp and removes the draft. It
+// must run inside the transaction that locked the draft row. The draft was
+// checked when it was saved; it is checked again so rules added since then
+// can mask or flag it, but it is not rejected at this point. The spam check
+// runs here too, but since nobody may be around to see a rejection when the
+// scheduler publishes, a draft that would be rejected is held instead.
+func (cfg *apiConfig) publishDraft(ctx context.Context, qtx *database.Queries, draft database.Draft) (database.Chirp, error) {
+	checked := cfg.moderation.Check(draft.Body)
+	checkedWarning := cfg.moderation.Check(draft.ContentWarning)
+	scored, err := cfg.scoreChirp(ctx, qtx, draft.UserID, uuid.Nil, checked.Text)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	var hiddenAt sql.NullTime
+	if scored.Decision != spam.Allow {
+		hiddenAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	}
+	chirp, err := qtx.CreateChirp(ctx, database.CreateChirpParams{
+		Body:           checked.Text,
+		UserID:         draft.UserID,
+		HiddenAt:       hiddenAt,
+		ContentWarning: checkedWarning.Text,
+		Sensitive:      draft.Sensitive,
+	})
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	err = recordSpamScore(ctx, qtx, draft.UserID, uuid.NullUUID{UUID: chirp.ID, Valid: true}, checked.Text, scored)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	err = recordFlags(ctx, qtx, chirp.ID, append(checked.Matches, checkedWarning.Matches...))
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if !chirp.HiddenAt.Valid {
+		err = events.Append(ctx, qtx, events.ChirpCreated, chirp.UserID, newChirpEvent(chirp))
+		if err != nil {
+			return database.Chirp{}, err
+		}
+	}
+	err = qtx.DeleteDraft(ctx, draft.ID)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	return chirp, nil
+}
+
+func newDraftResponse(draft database.Draft) draftResponse {
+	resp := draftResponse{
+		ID:             draft.ID,
+		CreatedAt:      draft.CreatedAt,
+		UpdatedAt:      draft.UpdatedAt,
+		Body:           draft.Body,
+		ContentWarning: draft.ContentWarning,
+		Sensitive:      draft.Sensitive,
+		UserID:         draft.UserID,
+		PublishError:   draft.LastError,
+	}
+	if draft.PublishAt.Valid {
+		publishAt := draft.PublishAt.Time
+		resp.PublishAt = &publishAt
+	}
+	return resp
+}
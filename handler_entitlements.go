@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/entitlements"
+	"github.com/Throne-of-Doom/chirpy/internal/subscription"
+	"github.com/google/uuid"
+)
+
+// chirpyRed reports whether a user has Chirpy Red right now, going by their
+// subscription, and which plan the subscription is on. It is the Go side of
+// the is_user_chirpy_red SQL function. q may be a transaction.
+func chirpyRed(ctx context.Context, q *database.Queries, userID uuid.UUID) (bool, string, error) {
+	dbSub, err := q.GetSubscriptionByUserID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	return subscription.Entitled(subscriptionState(dbSub), time.Now().UTC()), dbSub.Plan, nil
+}
+
+// userEntitlements returns what dbUser's plan allows. q may be a
+// transaction.
+func userEntitlements(ctx context.Context, q *database.Queries, dbUser database.User) (entitlements.Entitlements, error) {
+	subscribed, plan, err := chirpyRed(ctx, q, dbUser.ID)
+	if err != nil {
+		return entitlements.Entitlements{}, err
+	}
+	return entitlements.Resolve(subscribed, plan), nil
+}
+
+// entitlementsForUser looks up the user and returns what their plan allows.
+func (cfg *apiConfig) entitlementsForUser(ctx context.Context, userID uuid.UUID) (entitlements.Entitlements, error) {
+	dbUser, err := cfg.dbQueries.GetUserByID(ctx, userID)
+	if err != nil {
+		return entitlements.Entitlements{}, err
+	}
+	return userEntitlements(ctx, cfg.dbQueries, dbUser)
+}
+
+// getEntitlementsHandler tells clients what the caller's plan allows, so they
+// can adapt their UI instead of hard-coding limits.
+func (cfg *apiConfig) getEntitlementsHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	dbUser, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "user not found", err)
+		return
+	}
+	subscribed, plan, err := chirpyRed(r.Context(), cfg.dbQueries, dbUser.ID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load entitlements", err)
+		return
+	}
+	limits := entitlements.Resolve(subscribed, plan)
+
+	resp := entitlementsResponse{
+		Plan:              limits.Plan,
+		IsChirpyRed:       subscribed,
+		MaxChirpLength:    limits.MaxChirpLength,
+		MaxMediaPerChirp:  limits.MaxMediaPerChirp,
+		MaxUploadBytes:    limits.MaxUploadBytes,
+		MaxPinnedChirps:   limits.MaxPinnedChirps,
+		EditWindowSeconds: int(limits.EditWindow.Seconds()),
+		RateLimits:        map[string]rateLimitResponse{},
+		Features:          limits.Features,
+	}
+	for class, limit := range limits.RateLimits {
+		resp.RateLimits[class] = rateLimitResponse{
+			Burst:         limit.Burst,
+			PeriodSeconds: int(limit.Period.Seconds()),
+		}
+	}
+	respondWithJSON(w, 200, resp)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/events"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) likeChirpHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid chirp ID", err)
+		return
+	}
+	chirp, err := cfg.dbQueries.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 404, "chirp not found", err)
+		return
+	}
+	liker, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "user not found", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	liked, err := qtx.LikeChirp(r.Context(), database.LikeChirpParams{
+		ChirpID: chirp.ID,
+		UserID:  userID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't like chirp", err)
+		return
+	}
+	// Liking a chirp already liked changes nothing and sends nothing.
+	if liked > 0 {
+		err = events.Append(r.Context(), qtx, events.ChirpLiked, chirp.UserID, chirpLikedEvent{
+			ChirpID:     chirp.ID,
+			UserID:      chirp.UserID,
+			LikerID:     liker.ID,
+			LikerHandle: liker.Handle,
+		})
+		if err != nil {
+			respondWithError(w, 500, "couldn't like chirp", err)
+			return
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "couldn't like chirp", err)
+		return
+	}
+
+	resp, err := cfg.chirpResponse(r.Context(), chirp, userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load chirp", err)
+		return
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+func (cfg *apiConfig) unlikeChirpHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid chirp ID", err)
+		return
+	}
+	_, err = cfg.dbQueries.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		ChirpID: chirpID,
+		UserID:  userID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't unlike chirp", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"path"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/media"
+	"github.com/google/uuid"
+)
+
+const thumbnailSize = 320
+
+func (cfg *apiConfig) uploadAvatarHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	dbUser, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "user not found", err)
+		return
+	}
+	limits, err := userEntitlements(r.Context(), cfg.dbQueries, dbUser)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load entitlements", err)
+		return
+	}
+
+	img, ok := cfg.readImageUpload(w, r, limits.MaxUploadBytes)
+	if !ok {
+		return
+	}
+	object, err := cfg.saveImage(r.Context(), cfg.dbQueries, "avatars", dbUser.ID, uuid.NullUUID{}, img)
+	if err != nil {
+		respondWithError(w, 500, "couldn't save avatar", err)
+		return
+	}
+
+	err = cfg.dbQueries.SetUserAvatar(r.Context(), database.SetUserAvatarParams{
+		ID:       dbUser.ID,
+		AvatarID: uuid.NullUUID{UUID: object.ID, Valid: true},
+	})
+	if err != nil {
+		cfg.deleteMedia(r.Context(), object)
+		respondWithError(w, 500, "couldn't set avatar", err)
+		return
+	}
+	if dbUser.AvatarID.Valid {
+		previous, err := cfg.dbQueries.GetMediaObject(r.Context(), dbUser.AvatarID.UUID)
+		if err == nil {
+			cfg.deleteMedia(r.Context(), previous)
+		}
+	}
+
+	respondWithJSON(w, 200, newMediaResponse(object))
+}
+
+func (cfg *apiConfig) uploadChirpMediaHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid chirp ID", err)
+		return
+	}
+	chirp, err := cfg.dbQueries.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 404, "chirp not found", err)
+		return
+	}
+	if chirp.UserID != userID {
+		respondWithError(w, 403, "cannot add media to chirp", nil)
+		return
+	}
+	dbUser, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "user not found", err)
+		return
+	}
+	limits, err := userEntitlements(r.Context(), cfg.dbQueries, dbUser)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load entitlements", err)
+		return
+	}
+
+	img, ok := cfg.readImageUpload(w, r, limits.MaxUploadBytes)
+	if !ok {
+		return
+	}
+
+	// Locking the chirp makes concurrent uploads to it count and attach one
+	// at a time, so together they cannot go over the limit.
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	_, err = qtx.GetChirpForUpdate(r.Context(), chirp.ID)
+	if err != nil {
+		respondWithError(w, 404, "chirp not found", err)
+		return
+	}
+	count, err := qtx.CountChirpMedia(r.Context(), uuid.NullUUID{UUID: chirp.ID, Valid: true})
+	if err != nil {
+		respondWithError(w, 500, "couldn't count chirp media", err)
+		return
+	}
+	if count >= int64(limits.MaxMediaPerChirp) {
+		respondWithError(w, 400, "chirp already has the maximum number of attachments", nil)
+		return
+	}
+	object, err := cfg.saveImage(r.Context(), qtx, "chirps/"+chirp.ID.String(), dbUser.ID, uuid.NullUUID{UUID: chirp.ID, Valid: true}, img)
+	if err != nil {
+		respondWithError(w, 500, "couldn't save attachment", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		cfg.deleteBlobs(r.Context(), object.StorageKey, object.ThumbnailKey)
+		respondWithError(w, 500, "couldn't save attachment", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, newMediaResponse(object))
+}
+
+// serveMediaHandler serves a stored image or thumbnail. Attachments are only
+// served while the viewer can see their chirp, so they disappear when the
+// chirp is deleted, hidden or made author_only, or its author is suspended.
+func (cfg *apiConfig) serveMediaHandler(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	object, err := cfg.dbQueries.GetServableMedia(r.Context(), database.GetServableMediaParams{
+		Key:      key,
+		ViewerID: cfg.viewerID(r),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "media not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "couldn't load media", err)
+		return
+	}
+	rc, err := cfg.blobs.Open(r.Context(), key)
+	if errors.Is(err, media.ErrNotFound) {
+		respondWithError(w, 404, "media not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, 400, "invalid media key", err)
+		return
+	}
+	defer rc.Close()
+
+	// Keys are never reused, so the content behind a URL never changes, but
+	// whether an attachment may be seen does. Those are only cached briefly,
+	// and only by the viewer, so moderation takes effect.
+	w.Header().Set("Content-Type", media.ContentTypeForExt(path.Ext(key)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if object.ChirpID.Valid {
+		w.Header().Set("Cache-Control", "private, max-age=300")
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	}
+	w.WriteHeader(http.StatusOK)
+	_, err = io.Copy(w, rc)
+	if err != nil {
+		log.Printf("couldn't serve media %s: %v", key, err)
+	}
+}
+
+// readImageUpload reads the "file" field of a multipart upload, enforcing the
+// uploader's size limit, and processes it. It writes an error response and
+// returns false if the upload is unusable.
+func (cfg *apiConfig) readImageUpload(w http.ResponseWriter, r *http.Request, limit int64) (*media.Image, bool) {
+	// Leave some room for the multipart headers around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, limit+64<<10)
+	file, _, err := r.FormFile("file")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "file is too large", err)
+		return nil, false
+	}
+	if err != nil {
+		respondWithError(w, 400, "missing file upload", err)
+		return nil, false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil {
+		respondWithError(w, 400, "couldn't read upload", err)
+		return nil, false
+	}
+	if int64(len(data)) > limit {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "file is too large", nil)
+		return nil, false
+	}
+
+	img, err := media.Process(data, thumbnailSize)
+	if errors.Is(err, media.ErrUnsupportedType) {
+		respondWithError(w, http.StatusUnsupportedMediaType, "only JPEG, PNG and GIF images are supported", err)
+		return nil, false
+	}
+	if errors.Is(err, media.ErrTooLarge) {
+		respondWithError(w, 400, "image dimensions are too large", err)
+		return nil, false
+	}
+	if err != nil {
+		respondWithError(w, 500, "couldn't process image", err)
+		return nil, false
+	}
+	return img, true
+}
+
+// saveImage writes an image and its thumbnail to the blob store and records
+// them in the database. q may be a transaction, in which case the caller
+// deletes the blobs if it does not commit.
+func (cfg *apiConfig) saveImage(ctx context.Context, q *database.Queries, prefix string, userID uuid.UUID, chirpID uuid.NullUUID, img *media.Image) (database.MediaObject, error) {
+	id := uuid.New()
+	key := prefix + "/" + id.String() + img.Ext
+	thumbKey := prefix + "/" + id.String() + "_thumb" + img.ThumbnailExt
+
+	err := cfg.blobs.Put(ctx, key, bytes.NewReader(img.Data))
+	if err != nil {
+		return database.MediaObject{}, err
+	}
+	err = cfg.blobs.Put(ctx, thumbKey, bytes.NewReader(img.Thumbnail))
+	if err != nil {
+		cfg.blobs.Delete(ctx, key)
+		return database.MediaObject{}, err
+	}
+
+	object, err := q.CreateMediaObject(ctx, database.CreateMediaObjectParams{
+		ID:           id,
+		UserID:       userID,
+		ChirpID:      chirpID,
+		ContentType:  img.ContentType,
+		SizeBytes:    int64(len(img.Data)),
+		Width:        int32(img.Width),
+		Height:       int32(img.Height),
+		StorageKey:   key,
+		ThumbnailKey: thumbKey,
+	})
+	if err != nil {
+		cfg.blobs.Delete(ctx, key)
+		cfg.blobs.Delete(ctx, thumbKey)
+		return database.MediaObject{}, err
+	}
+	return object, nil
+}
+
+func (cfg *apiConfig) deleteMedia(ctx context.Context, object database.MediaObject) {
+	err := cfg.dbQueries.DeleteMediaObject(ctx, object.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("couldn't delete media object %s: %v", object.ID, err)
+		return
+	}
+	cfg.deleteBlobs(ctx, object.StorageKey, object.ThumbnailKey)
+}
+
+// deleteBlobs deletes blobs from the blob store, logging any that fail.
+func (cfg *apiConfig) deleteBlobs(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		err := cfg.blobs.Delete(ctx, key)
+		if err != nil {
+			log.Printf("couldn't delete blob %s: %v", key, err)
+		}
+	}
+}
+
+func newMediaResponse(object database.MediaObject) mediaResponse {
+	return mediaResponse{
+		ID:           object.ID,
+		ContentType:  object.ContentType,
+		Width:        object.Width,
+		Height:       object.Height,
+		URL:          mediaURL(object.StorageKey),
+		ThumbnailURL: mediaURL(object.ThumbnailKey),
+	}
+}
+
+func mediaURL(key string) string {
+	return "/media/" + key
+}
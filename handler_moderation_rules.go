@@ -0,0 +1,206 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/moderation"
+	"github.com/google/uuid"
+)
+
+type moderationRuleParameters struct {
+	Term   string            `json:"term"`
+	Action moderation.Action `json:"action"`
+}
+
+// requireAdmin checks the request carries the admin API key. It writes an
+// error response and returns false if it does not.
+func (cfg *apiConfig) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	key, err := auth.GetAPIKey(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid authorization header", err)
+		return false
+	}
+	if !auth.KeysEqual(key, cfg.ADMIN_KEY) {
+		respondWithError(w, http.StatusUnauthorized, "unauthorized", nil)
+		return false
+	}
+	return true
+}
+
+// listModerationRulesHandler returns the rules the filter is using, including
+// those loaded from a file, which cannot be edited through the API.
+func (cfg *apiConfig) listModerationRulesHandler(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+	resp := []moderationRuleResponse{}
+	for _, rule := range cfg.moderation.Rules() {
+		_, err := uuid.Parse(rule.ID)
+		resp = append(resp, moderationRuleResponse{
+			ID:       rule.ID,
+			Term:     rule.Term,
+			Action:   rule.Action,
+			Editable: err == nil,
+		})
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+func (cfg *apiConfig) createModerationRuleHandler(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := moderationRuleParameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, "couldn't decode parameters", err)
+		return
+	}
+	rule := moderation.Rule{Term: strings.TrimSpace(params.Term), Action: params.Action}
+	err = rule.Validate()
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+
+	dbRule, err := cfg.dbQueries.CreateModerationRule(r.Context(), database.CreateModerationRuleParams{
+		Term:   rule.Term,
+		Action: string(rule.Action),
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't create rule", err)
+		return
+	}
+	err = cfg.reloadModerationRules(r.Context())
+	if err != nil {
+		respondWithError(w, 500, "couldn't reload rules", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, newModerationRuleResponse(dbRule))
+}
+
+func (cfg *apiConfig) updateModerationRuleHandler(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+	ruleID, err := uuid.Parse(r.PathValue("ruleID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid rule ID", err)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := moderationRuleParameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, "couldn't decode parameters", err)
+		return
+	}
+	rule := moderation.Rule{Term: strings.TrimSpace(params.Term), Action: params.Action}
+	err = rule.Validate()
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+
+	dbRule, err := cfg.dbQueries.UpdateModerationRule(r.Context(), database.UpdateModerationRuleParams{
+		ID:     ruleID,
+		Term:   rule.Term,
+		Action: string(rule.Action),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "rule not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "couldn't update rule", err)
+		return
+	}
+	err = cfg.reloadModerationRules(r.Context())
+	if err != nil {
+		respondWithError(w, 500, "couldn't reload rules", err)
+		return
+	}
+	respondWithJSON(w, 200, newModerationRuleResponse(dbRule))
+}
+
+func (cfg *apiConfig) deleteModerationRuleHandler(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+	ruleID, err := uuid.Parse(r.PathValue("ruleID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid rule ID", err)
+		return
+	}
+	deleted, err := cfg.dbQueries.DeleteModerationRule(r.Context(), ruleID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't delete rule", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "rule not found", nil)
+		return
+	}
+	err = cfg.reloadModerationRules(r.Context())
+	if err != nil {
+		respondWithError(w, 500, "couldn't reload rules", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reloadModerationRulesHandler re-reads the rules file and the database, for
+// when the file has been edited.
+func (cfg *apiConfig) reloadModerationRulesHandler(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+	err := cfg.reloadModerationRules(r.Context())
+	if err != nil {
+		respondWithError(w, 500, "couldn't reload rules", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) listChirpFlagsHandler(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+	limit, err := parseLimit(r, defaultListLimit, maxListLimit)
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+	flags, err := cfg.dbQueries.ListChirpFlags(r.Context(), limit)
+	if err != nil {
+		respondWithError(w, 500, "couldn't list flags", err)
+		return
+	}
+	resp := []chirpFlagResponse{}
+	for _, flag := range flags {
+		resp = append(resp, chirpFlagResponse{
+			ChirpID:   flag.ChirpID,
+			RuleID:    flag.RuleID,
+			Term:      flag.Term,
+			CreatedAt: flag.CreatedAt,
+		})
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+func newModerationRuleResponse(rule database.ModerationRule) moderationRuleResponse {
+	return moderationRuleResponse{
+		ID:       rule.ID.String(),
+		Term:     rule.Term,
+		Action:   moderation.Action(rule.Action),
+		Editable: true,
+	}
+}
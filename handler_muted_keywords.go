@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/textutil"
+	"github.com/google/uuid"
+)
+
+const (
+	maxMutedKeywords      = 100
+	maxMutedKeywordLength = 100
+)
+
+// keywordMutes is the set of keywords a viewer has muted.
+type keywordMutes struct {
+	viewerID uuid.UUID
+	phrases  *textutil.PhraseSet
+}
+
+// loadKeywordMutes returns the keywords viewerID has muted. Anonymous viewers
+// have none.
+func (cfg *apiConfig) loadKeywordMutes(ctx context.Context, viewerID uuid.UUID) (keywordMutes, error) {
+	mutes := keywordMutes{viewerID: viewerID, phrases: textutil.NewPhraseSet(nil)}
+	if viewerID == uuid.Nil {
+		return mutes, nil
+	}
+	keywords, err := cfg.dbQueries.ListMutedKeywords(ctx, viewerID)
+	if err != nil {
+		return mutes, err
+	}
+	phrases := make([]string, 0, len(keywords))
+	for _, keyword := range keywords {
+		phrases = append(phrases, keyword.Keyword)
+	}
+	mutes.phrases = textutil.NewPhraseSet(phrases)
+	return mutes, nil
+}
+
+// hides reports whether a chirp's body or content warning contains a muted
+// keyword. Viewers never mute their own chirps.
+func (m keywordMutes) hides(authorID uuid.UUID, body, contentWarning string) bool {
+	if authorID == m.viewerID || m.phrases.Empty() {
+		return false
+	}
+	return m.phrases.Contains(body) || m.phrases.Contains(contentWarning)
+}
+
+func (cfg *apiConfig) listMutedKeywordsHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	keywords, err := cfg.dbQueries.ListMutedKeywords(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't list muted keywords", err)
+		return
+	}
+	resp := []mutedKeywordResponse{}
+	for _, keyword := range keywords {
+		resp = append(resp, newMutedKeywordResponse(keyword))
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+// muteKeywordHandler mutes a word or phrase, optionally until expires_at.
+// Muting a keyword that is already muted replaces its expiry.
+func (cfg *apiConfig) muteKeywordHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Keyword   string     `json:"keyword"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, "couldn't decode parameters", err)
+		return
+	}
+	keyword := strings.TrimSpace(params.Keyword)
+	if utf8.RuneCountInString(keyword) > maxMutedKeywordLength {
+		respondWithError(w, 400, fmt.Sprintf("keywords are limited to %d characters", maxMutedKeywordLength), nil)
+		return
+	}
+	normalized := textutil.Key(keyword)
+	if normalized == "" {
+		respondWithError(w, 400, "keyword must contain at least one word", nil)
+		return
+	}
+	expiresAt := sql.NullTime{}
+	if params.ExpiresAt != nil {
+		if !params.ExpiresAt.After(time.Now().UTC()) {
+			respondWithError(w, 400, "expires_at must be in the future", nil)
+			return
+		}
+		expiresAt = sql.NullTime{Time: params.ExpiresAt.UTC(), Valid: true}
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	// Lock the user row so concurrent requests cannot both pass the limit.
+	_, err = qtx.GetUserByIDForUpdate(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "user not found", err)
+		return
+	}
+	err = qtx.DeleteExpiredMutedKeywords(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't mute keyword", err)
+		return
+	}
+	muted, err := qtx.MuteKeyword(r.Context(), database.MuteKeywordParams{
+		UserID:     userID,
+		Keyword:    keyword,
+		Normalized: normalized,
+		ExpiresAt:  expiresAt,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't mute keyword", err)
+		return
+	}
+	// Counting after the insert lets an already muted keyword be updated even
+	// when the user is at the limit.
+	count, err := qtx.CountMutedKeywords(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't mute keyword", err)
+		return
+	}
+	if count > maxMutedKeywords {
+		respondWithError(w, 400, fmt.Sprintf("you can mute up to %d keywords", maxMutedKeywords), nil)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "couldn't mute keyword", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, newMutedKeywordResponse(muted))
+}
+
+func (cfg *apiConfig) unmuteKeywordHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	keywordID, err := uuid.Parse(r.PathValue("keywordID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid keyword ID", err)
+		return
+	}
+	deleted, err := cfg.dbQueries.DeleteMutedKeyword(r.Context(), database.DeleteMutedKeywordParams{
+		ID:     keywordID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't unmute keyword", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "muted keyword not found", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newMutedKeywordResponse(keyword database.MutedKeyword) mutedKeywordResponse {
+	resp := mutedKeywordResponse{
+		ID:        keyword.ID,
+		CreatedAt: keyword.CreatedAt,
+		Keyword:   keyword.Keyword,
+	}
+	if keyword.ExpiresAt.Valid {
+		expiresAt := keyword.ExpiresAt.Time
+		resp.ExpiresAt = &expiresAt
+	}
+	return resp
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/notifications"
+	"github.com/google/uuid"
+)
+
+// listNotificationsHandler returns the caller's notifications, most recently
+// updated first. A grouped notification moves to the top when it gains an
+// actor, so it can show up again on a later page.
+func (cfg *apiConfig) listNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	limit, err := parseLimit(r, defaultListLimit, maxListLimit)
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+	cursor, hasCursor, err := parseCursor(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+
+	rows, err := cfg.dbQueries.ListNotifications(r.Context(), database.ListNotificationsParams{
+		UserID:     userID,
+		UnreadOnly: r.URL.Query().Get("unread") == "true",
+		HasCursor:  hasCursor,
+		CursorTime: cursor.Time,
+		CursorID:   cursor.ID,
+		RowLimit:   limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't list notifications", err)
+		return
+	}
+	resp := notificationListResponse{Notifications: []notificationResponse{}}
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		resp.NextCursor = listCursor{Time: last.UpdatedAt, ID: last.ID}.String()
+	}
+	resp.Notifications, err = cfg.notificationResponses(r.Context(), rows)
+	if err != nil {
+		respondWithError(w, 500, "couldn't list notifications", err)
+		return
+	}
+	resp.UnreadCount, err = cfg.dbQueries.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't count notifications", err)
+		return
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+func (cfg *apiConfig) notificationResponses(ctx context.Context, rows []database.ListNotificationsRow) ([]notificationResponse, error) {
+	responses := make([]notificationResponse, 0, len(rows))
+	ids := make([]uuid.UUID, 0, len(rows))
+	index := make(map[uuid.UUID]int, len(rows))
+	for i, row := range rows {
+		resp := notificationResponse{
+			ID:         row.ID,
+			CreatedAt:  row.CreatedAt,
+			UpdatedAt:  row.UpdatedAt,
+			Type:       row.Type,
+			Actors:     []notificationActorResponse{},
+			ActorCount: row.ActorCount,
+		}
+		if row.ChirpID.Valid {
+			chirpID := row.ChirpID.UUID
+			resp.ChirpID = &chirpID
+		}
+		if row.ReadAt.Valid {
+			readAt := row.ReadAt.Time
+			resp.ReadAt = &readAt
+		}
+		responses = append(responses, resp)
+		ids = append(ids, row.ID)
+		index[row.ID] = i
+	}
+	if len(rows) > 0 {
+		actors, err := cfg.dbQueries.ListNotificationActors(ctx, database.ListNotificationActorsParams{
+			NotificationIds: ids,
+			PerNotification: notificationActorsShown,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, actor := range actors {
+			i := index[actor.NotificationID]
+			responses[i].Actors = append(responses[i].Actors, notificationActorResponse{
+				ID:     actor.ActorID,
+				Handle: actor.Handle,
+			})
+		}
+	}
+	for i := range responses {
+		resp := &responses[i]
+		handles := make([]string, 0, len(resp.Actors))
+		for _, actor := range resp.Actors {
+			handles = append(handles, actor.Handle)
+		}
+		resp.Summary = notifications.Summary(notifications.Type(resp.Type), handles, int(resp.ActorCount))
+	}
+	return responses, nil
+}
+
+func (cfg *apiConfig) unreadNotificationCountHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	count, err := cfg.dbQueries.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't count notifications", err)
+		return
+	}
+	respondWithJSON(w, 200, map[string]int64{"unread_count": count})
+}
+
+func (cfg *apiConfig) markNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	notificationID, err := uuid.Parse(r.PathValue("notificationID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid notification ID", err)
+		return
+	}
+	marked, err := cfg.dbQueries.MarkNotificationRead(r.Context(), database.MarkNotificationReadParams{
+		ID:     notificationID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't mark notification read", err)
+		return
+	}
+	if marked == 0 {
+		respondWithError(w, 404, "notification not found", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) markAllNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	_, err = cfg.dbQueries.MarkAllNotificationsRead(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't mark notifications read", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getNotificationPreferencesHandler returns whether each notification type is
+// on for the caller.
+func (cfg *apiConfig) getNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	prefs, err := cfg.notificationPreferences(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load notification preferences", err)
+		return
+	}
+	respondWithJSON(w, 200, prefs)
+}
+
+// updateNotificationPreferencesHandler turns notification types on or off.
+// Types left out of the request keep their current setting.
+func (cfg *apiConfig) updateNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	params := map[string]bool{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, "couldn't decode parameters", err)
+		return
+	}
+	for t := range params {
+		if !notifications.ValidType(notifications.Type(t)) {
+			respondWithError(w, 400, fmt.Sprintf("unknown notification type %q", t), nil)
+			return
+		}
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+	for t, enabled := range params {
+		err = qtx.SetNotificationPreference(r.Context(), database.SetNotificationPreferenceParams{
+			UserID:  userID,
+			Type:    t,
+			Enabled: enabled,
+		})
+		if err != nil {
+			respondWithError(w, 500, "couldn't update notification preferences", err)
+			return
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "couldn't update notification preferences", err)
+		return
+	}
+
+	prefs, err := cfg.notificationPreferences(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load notification preferences", err)
+		return
+	}
+	respondWithJSON(w, 200, prefs)
+}
+
+// notificationPreferences maps every notification type to whether it is on
+// for userID. Types are on unless turned off.
+func (cfg *apiConfig) notificationPreferences(ctx context.Context, userID uuid.UUID) (map[string]bool, error) {
+	rows, err := cfg.dbQueries.ListNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	prefs := make(map[string]bool, len(notifications.Types))
+	for _, t := range notifications.Types {
+		prefs[string(t)] = true
+	}
+	for _, row := range rows {
+		if _, ok := prefs[row.Type]; ok {
+			prefs[row.Type] = row.Enabled
+		}
+	}
+	return prefs, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) pinChirpHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid chirp ID", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	// Locking the user row serialises pin requests from the same user, so two
+	// concurrent pins cannot both pass the limit check.
+	dbUser, err := qtx.GetUserByIDForUpdate(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "user not found", err)
+		return
+	}
+	chirp, err := qtx.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 404, "chirp not found", err)
+		return
+	}
+	if chirp.UserID != userID {
+		respondWithError(w, 403, "cannot pin chirp", nil)
+		return
+	}
+	// Pinning a chirp that is already pinned changes nothing, even at the
+	// limit.
+	pinned, err := qtx.IsChirpPinned(r.Context(), chirp.ID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't pin chirp", err)
+		return
+	}
+	if !pinned {
+		count, err := qtx.CountUserPins(r.Context(), userID)
+		if err != nil {
+			respondWithError(w, 500, "couldn't count pinned chirps", err)
+			return
+		}
+		limits, err := userEntitlements(r.Context(), qtx, dbUser)
+		if err != nil {
+			respondWithError(w, 500, "couldn't load entitlements", err)
+			return
+		}
+		if count >= int64(limits.MaxPinnedChirps) {
+			respondWithError(w, 409, fmt.Sprintf("you can pin at most %d chirps", limits.MaxPinnedChirps), nil)
+			return
+		}
+		_, err = qtx.PinChirp(r.Context(), database.PinChirpParams{
+			ChirpID: chirp.ID,
+			UserID:  userID,
+		})
+		if err != nil {
+			respondWithError(w, 500, "couldn't pin chirp", err)
+			return
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "couldn't pin chirp", err)
+		return
+	}
+
+	resp, err := cfg.chirpResponse(r.Context(), chirp, userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load chirp", err)
+		return
+	}
+	resp.Pinned = true
+	respondWithJSON(w, 200, resp)
+}
+
+func (cfg *apiConfig) unpinChirpHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid chirp ID", err)
+		return
+	}
+	// The pin itself is removed without loading the chirp, so a pin whose
+	// chirp has since been hidden can still be taken down by its owner.
+	unpinned, err := cfg.dbQueries.UnpinUserChirp(r.Context(), database.UnpinUserChirpParams{
+		ChirpID: chirpID,
+		UserID:  userID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't unpin chirp", err)
+		return
+	}
+	if unpinned == 0 {
+		chirp, err := cfg.dbQueries.GetChirp(r.Context(), database.GetChirpParams{
+			ID:       chirpID,
+			ViewerID: userID,
+		})
+		if err != nil {
+			respondWithError(w, 404, "chirp not found", err)
+			return
+		}
+		if chirp.UserID != userID {
+			respondWithError(w, 403, "cannot unpin chirp", nil)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pinnedChirpResponses returns a user's pinned chirps, most recently pinned
+// first.
+func (cfg *apiConfig) pinnedChirpResponses(ctx context.Context, userID, viewerID uuid.UUID) ([]ChirpResponse, error) {
+	pinned, err := cfg.dbQueries.GetPinnedChirps(ctx, database.GetPinnedChirpsParams{
+		UserID:   userID,
+		ViewerID: viewerID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cfg.chirpResponses(ctx, pinned, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range resp {
+		resp[i].Pinned = true
+	}
+	return resp, nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	minPollOptions      = 2
+	maxPollOptions      = 4
+	maxPollOptionLength = 25
+	defaultPollDuration = 24 * time.Hour
+	maxPollDuration     = 7 * 24 * time.Hour
+	minPollDuration     = 5 * time.Minute
+)
+
+type pollParameters struct {
+	Options  []string   `json:"options"`
+	ClosesAt *time.Time `json:"closes_at"`
+}
+
+// validatePoll trims and checks poll options and works out when the poll
+// closes. It returns the cleaned options.
+func validatePoll(params *pollParameters, now time.Time) ([]string, time.Time, error) {
+	if len(params.Options) < minPollOptions || len(params.Options) > maxPollOptions {
+		return nil, time.Time{}, fmt.Errorf("polls must have between %d and %d options", minPollOptions, maxPollOptions)
+	}
+	options := make([]string, 0, len(params.Options))
+	seen := make(map[string]struct{}, len(params.Options))
+	for _, option := range params.Options {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			return nil, time.Time{}, errors.New("poll options cannot be empty")
+		}
+		if utf8.RuneCountInString(option) > maxPollOptionLength {
+			return nil, time.Time{}, fmt.Errorf("poll options are limited to %d characters", maxPollOptionLength)
+		}
+		key := strings.ToLower(option)
+		if _, ok := seen[key]; ok {
+			return nil, time.Time{}, errors.New("poll options must be unique")
+		}
+		seen[key] = struct{}{}
+		options = append(options, option)
+	}
+
+	closesAt := now.Add(defaultPollDuration)
+	if params.ClosesAt != nil {
+		closesAt = params.ClosesAt.UTC()
+	}
+	if closesAt.Before(now.Add(minPollDuration)) {
+		return nil, time.Time{}, errors.New("polls must stay open for at least 5 minutes")
+	}
+	if closesAt.After(now.Add(maxPollDuration)) {
+		return nil, time.Time{}, errors.New("polls cannot stay open for more than 7 days")
+	}
+	return options, closesAt, nil
+}
+
+// createPoll attaches a poll to a chirp. It must run in the same transaction
+// as the chirp insert.
+func createPoll(ctx context.Context, qtx *database.Queries, chirpID uuid.UUID, options []string, closesAt time.Time) error {
+	poll, err := qtx.CreatePoll(ctx, database.CreatePollParams{
+		ChirpID:  chirpID,
+		ClosesAt: closesAt,
+	})
+	if err != nil {
+		return err
+	}
+	for i, option := range options {
+		_, err = qtx.CreatePollOption(ctx, database.CreatePollOptionParams{
+			PollID:   poll.ID,
+			Position: int32(i),
+			Label:    option,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) votePollHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		OptionID uuid.UUID `json:"option_id"`
+	}
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid chirp ID", err)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, "couldn't decode parameters", err)
+		return
+	}
+
+	// Trashed chirps keep their poll rows until they are purged, but cannot be
+	// voted on.
+	_, err = cfg.dbQueries.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 404, "poll not found", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	// Locking the poll row serialises votes on it, so the closing time check
+	// and the insert below cannot race with each other.
+	poll, err := qtx.GetPollByChirpForUpdate(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "poll not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "couldn't get poll", err)
+		return
+	}
+	if !time.Now().UTC().Before(poll.ClosesAt) {
+		respondWithError(w, 409, "poll is closed", nil)
+		return
+	}
+	_, err = qtx.GetPollOption(r.Context(), database.GetPollOptionParams{
+		ID:     params.OptionID,
+		PollID: poll.ID,
+	})
+	if err != nil {
+		respondWithError(w, 400, "invalid poll option", err)
+		return
+	}
+	inserted, err := qtx.CreatePollVote(r.Context(), database.CreatePollVoteParams{
+		PollID:   poll.ID,
+		UserID:   userID,
+		OptionID: params.OptionID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't record vote", err)
+		return
+	}
+	if inserted == 0 {
+		respondWithError(w, 409, "already voted in this poll", nil)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "couldn't record vote", err)
+		return
+	}
+
+	chirp, err := cfg.dbQueries.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 404, "chirp not found", err)
+		return
+	}
+	resp, err := cfg.chirpResponse(r.Context(), chirp, userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load chirp", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, resp)
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/events"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxWebsiteLength     = 100
+)
+
+// Handles are matched case-insensitively, so "Alice" and "alice" are the same
+// user. The minimum length also keeps reserved path segments such as "me"
+// from ever being a valid handle.
+var handlePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,15}$`)
+
+func (cfg *apiConfig) getProfileHandler(w http.ResponseWriter, r *http.Request) {
+	handle := r.PathValue("handle")
+	viewerID := cfg.viewerID(r)
+	dbProfile, err := cfg.dbQueries.GetUserProfile(r.Context(), database.GetUserProfileParams{
+		Handle:   handle,
+		ViewerID: viewerID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "user not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "couldn't get profile", err)
+		return
+	}
+
+	resp := profile{
+		ID:             dbProfile.ID,
+		CreatedAt:      dbProfile.CreatedAt,
+		Handle:         dbProfile.Handle,
+		DisplayName:    dbProfile.DisplayName,
+		Bio:            dbProfile.Bio,
+		Website:        dbProfile.Website,
+		IsChirpyRed:    dbProfile.IsChirpyRed,
+		FollowerCount:  dbProfile.FollowerCount,
+		FollowingCount: dbProfile.FollowingCount,
+		ChirpCount:     dbProfile.ChirpCount,
+	}
+	if dbProfile.AvatarKey.Valid {
+		resp.AvatarURL = mediaURL(dbProfile.AvatarKey.String)
+		resp.AvatarThumbURL = mediaURL(dbProfile.AvatarThumbnailKey.String)
+	}
+	resp.PinnedChirps, err = cfg.pinnedChirpResponses(r.Context(), dbProfile.ID, viewerID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load pinned chirps", err)
+		return
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+func (cfg *apiConfig) updateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Handle          *string `json:"handle"`
+		DisplayName     *string `json:"display_name"`
+		Bio             *string `json:"bio"`
+		Website         *string `json:"website"`
+		ExpandSensitive *bool   `json:"expand_sensitive"`
+	}
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, "couldn't decode parameters", err)
+		return
+	}
+
+	dbUser, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "user not found", err)
+		return
+	}
+
+	update := database.UpdateUserProfileParams{
+		ID:              dbUser.ID,
+		Handle:          dbUser.Handle,
+		DisplayName:     dbUser.DisplayName,
+		Bio:             dbUser.Bio,
+		Website:         dbUser.Website,
+		ExpandSensitive: dbUser.ExpandSensitive,
+	}
+	if params.ExpandSensitive != nil {
+		update.ExpandSensitive = *params.ExpandSensitive
+	}
+	if params.Handle != nil {
+		update.Handle = strings.TrimSpace(*params.Handle)
+		if err := validateHandle(update.Handle); err != nil {
+			respondWithError(w, 400, err.Error(), err)
+			return
+		}
+	}
+	if params.DisplayName != nil {
+		update.DisplayName = strings.TrimSpace(*params.DisplayName)
+		if err := validateDisplayName(update.DisplayName); err != nil {
+			respondWithError(w, 400, err.Error(), err)
+			return
+		}
+	}
+	if params.Bio != nil {
+		update.Bio = strings.TrimSpace(*params.Bio)
+		if err := validateBio(update.Bio); err != nil {
+			respondWithError(w, 400, err.Error(), err)
+			return
+		}
+	}
+	if params.Website != nil {
+		update.Website = strings.TrimSpace(*params.Website)
+		if err := validateWebsite(update.Website); err != nil {
+			respondWithError(w, 400, err.Error(), err)
+			return
+		}
+	}
+
+	updated, err := cfg.dbQueries.UpdateUserProfile(r.Context(), update)
+	if isUniqueViolation(err, "users_handle_lower_idx") {
+		respondWithError(w, 409, "handle is already taken", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "couldn't update profile", err)
+		return
+	}
+	isChirpyRed, _, err := chirpyRed(r.Context(), cfg.dbQueries, updated.ID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load subscription", err)
+		return
+	}
+
+	respondWithJSON(w, 200, user{
+		ID:              updated.ID,
+		CreatedAt:       updated.CreatedAt,
+		UpdatedAt:       updated.UpdatedAt,
+		Email:           updated.Email,
+		Handle:          updated.Handle,
+		DisplayName:     updated.DisplayName,
+		Bio:             updated.Bio,
+		Website:         updated.Website,
+		IsChirpyRed:     isChirpyRed,
+		ExpandSensitive: updated.ExpandSensitive,
+	})
+}
+
+func (cfg *apiConfig) followHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	followee, err := cfg.dbQueries.GetUserByHandle(r.Context(), r.PathValue("handle"))
+	if err != nil {
+		respondWithError(w, 404, "user not found", err)
+		return
+	}
+	if followee.ID == userID {
+		respondWithError(w, 400, "cannot follow yourself", nil)
+		return
+	}
+
+	follower, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "user not found", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	followed, err := qtx.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followee.ID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't follow user", err)
+		return
+	}
+	// Following someone already followed changes nothing and sends nothing.
+	if followed > 0 {
+		err = events.Append(r.Context(), qtx, events.UserFollowed, followee.ID, userFollowedEvent{
+			UserID:         followee.ID,
+			FollowerID:     follower.ID,
+			FollowerHandle: follower.Handle,
+		})
+		if err != nil {
+			respondWithError(w, 500, "couldn't follow user", err)
+			return
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "couldn't follow user", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) unfollowHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	followee, err := cfg.dbQueries.GetUserByHandle(r.Context(), r.PathValue("handle"))
+	if err != nil {
+		respondWithError(w, 404, "user not found", err)
+		return
+	}
+
+	_, err = cfg.dbQueries.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followee.ID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't unfollow user", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func validateHandle(handle string) error {
+	if !handlePattern.MatchString(handle) {
+		return errors.New("handle must be 3-15 letters, numbers or underscores")
+	}
+	return nil
+}
+
+func validateDisplayName(name string) error {
+	if utf8.RuneCountInString(name) > maxDisplayNameLength {
+		return errors.New("display name is too long, limit 50 characters")
+	}
+	if strings.ContainsFunc(name, unicode.IsControl) {
+		return errors.New("display name contains invalid characters")
+	}
+	return nil
+}
+
+func validateBio(bio string) error {
+	if utf8.RuneCountInString(bio) > maxBioLength {
+		return errors.New("bio is too long, limit 160 characters")
+	}
+	if strings.ContainsFunc(bio, func(r rune) bool { return unicode.IsControl(r) && r != '\n' }) {
+		return errors.New("bio contains invalid characters")
+	}
+	return nil
+}
+
+func validateWebsite(website string) error {
+	if website == "" {
+		return nil
+	}
+	if len(website) > maxWebsiteLength {
+		return errors.New("website is too long, limit 100 characters")
+	}
+	u, err := url.Parse(website)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("website must be an http or https URL")
+	}
+	return nil
+}
+
+// generateHandle picks a placeholder handle for accounts created without one.
+func generateHandle() (string, error) {
+	b := make([]byte, 5)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return "user_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,572 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxReportDetailsLength  = 1000
+	maxResolutionNoteLength = 1000
+	defaultSuspensionDays   = 7
+	maxSuspensionDays       = 365
+	reportsOpenUniqueIndex  = "reports_open_unique_idx"
+)
+
+const (
+	roleUser      = "user"
+	roleModerator = "moderator"
+	roleAdmin     = "admin"
+)
+
+const (
+	reportStatusOpen     = "open"
+	reportStatusTriaged  = "triaged"
+	reportStatusResolved = "resolved"
+)
+
+const (
+	resolutionDismiss     = "dismiss"
+	resolutionHideChirp   = "hide_chirp"
+	resolutionSuspendUser = "suspend_user"
+)
+
+// Actions recorded in the moderation audit log.
+const (
+	auditActionTriage      = "triage_report"
+	auditActionResolve     = "resolve_report"
+	auditActionHideChirp   = "hide_chirp"
+	auditActionSuspendUser = "suspend_user"
+	auditActionSetUserRole = "set_user_role"
+
+	auditActionApproveHeldChirp = "approve_held_chirp"
+	auditActionRemoveHeldChirp  = "remove_held_chirp"
+
+	auditActionSetChirpVisibility = "set_chirp_visibility"
+	auditActionSetUserVisibility  = "set_user_visibility"
+	auditActionSetContentWarning  = "set_content_warning"
+)
+
+var reportReasons = map[string]struct{}{
+	"spam":          {},
+	"harassment":    {},
+	"hate":          {},
+	"violence":      {},
+	"sexual":        {},
+	"self_harm":     {},
+	"impersonation": {},
+	"other":         {},
+}
+
+// createReportHandler files a report about a chirp or, when no chirp is
+// given, about a user.
+func (cfg *apiConfig) createReportHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ChirpID *uuid.UUID `json:"chirp_id"`
+		UserID  *uuid.UUID `json:"user_id"`
+		Reason  string     `json:"reason"`
+		Details string     `json:"details"`
+	}
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, "couldn't decode parameters", err)
+		return
+	}
+	if _, ok := reportReasons[params.Reason]; !ok {
+		respondWithError(w, 400, "invalid report reason", nil)
+		return
+	}
+	details := strings.TrimSpace(params.Details)
+	if utf8.RuneCountInString(details) > maxReportDetailsLength {
+		respondWithError(w, 400, fmt.Sprintf("details are limited to %d characters", maxReportDetailsLength), nil)
+		return
+	}
+
+	var reportedUserID uuid.UUID
+	chirpID := uuid.NullUUID{}
+	switch {
+	case params.ChirpID != nil:
+		chirp, err := cfg.dbQueries.GetChirp(r.Context(), database.GetChirpParams{
+			ID:       *params.ChirpID,
+			ViewerID: userID,
+		})
+		if err != nil {
+			respondWithError(w, 404, "chirp not found", err)
+			return
+		}
+		reportedUserID = chirp.UserID
+		chirpID = uuid.NullUUID{UUID: chirp.ID, Valid: true}
+	case params.UserID != nil:
+		reported, err := cfg.dbQueries.GetUserByID(r.Context(), *params.UserID)
+		if err != nil {
+			respondWithError(w, 404, "user not found", err)
+			return
+		}
+		reportedUserID = reported.ID
+	default:
+		respondWithError(w, 400, "chirp_id or user_id is required", nil)
+		return
+	}
+	if reportedUserID == userID {
+		respondWithError(w, 400, "cannot report yourself", nil)
+		return
+	}
+
+	report, err := cfg.dbQueries.CreateReport(r.Context(), database.CreateReportParams{
+		ReporterID:     userID,
+		ReportedUserID: reportedUserID,
+		ChirpID:        chirpID,
+		Reason:         params.Reason,
+		Details:        details,
+	})
+	if isUniqueViolation(err, reportsOpenUniqueIndex) {
+		respondWithError(w, 409, "you have already reported this", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "couldn't create report", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, newReportResponse(report))
+}
+
+// requireModerator authenticates the request and checks the user is a
+// moderator or admin. It writes an error response and returns false if not.
+func (cfg *apiConfig) requireModerator(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return database.User{}, false
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return database.User{}, false
+	}
+	dbUser, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 401, "user not found", err)
+		return database.User{}, false
+	}
+	if dbUser.Role != roleModerator && dbUser.Role != roleAdmin {
+		respondWithError(w, 403, "moderator access required", nil)
+		return database.User{}, false
+	}
+	return dbUser, true
+}
+
+func (cfg *apiConfig) listReportsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.requireModerator(w, r); !ok {
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = reportStatusOpen
+	}
+	if status != reportStatusOpen && status != reportStatusTriaged && status != reportStatusResolved {
+		respondWithError(w, 400, "status must be open, triaged or resolved", nil)
+		return
+	}
+	limit, err := parseLimit(r, defaultListLimit, maxListLimit)
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+	reports, err := cfg.dbQueries.ListReports(r.Context(), database.ListReportsParams{
+		Status: status,
+		Limit:  limit,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't list reports", err)
+		return
+	}
+	resp := []reportResponse{}
+	for _, report := range reports {
+		resp = append(resp, newReportResponse(report))
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+// getReportHandler returns a report along with the reported chirp, even if
+// it has since been deleted or hidden.
+func (cfg *apiConfig) getReportHandler(w http.ResponseWriter, r *http.Request) {
+	moderator, ok := cfg.requireModerator(w, r)
+	if !ok {
+		return
+	}
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid report ID", err)
+		return
+	}
+	report, err := cfg.dbQueries.GetReport(r.Context(), reportID)
+	if err != nil {
+		respondWithError(w, 404, "report not found", err)
+		return
+	}
+	resp := newReportResponse(report)
+	if report.ChirpID.Valid {
+		chirp, err := cfg.dbQueries.GetChirpForModeration(r.Context(), report.ChirpID.UUID)
+		if err == nil {
+			chirpResp, err := cfg.chirpResponse(r.Context(), chirp, moderator.ID)
+			if err != nil {
+				respondWithError(w, 500, "couldn't load chirp", err)
+				return
+			}
+			resp.Chirp = &chirpResp
+		}
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+// triageReportHandler assigns a report to the calling moderator.
+func (cfg *apiConfig) triageReportHandler(w http.ResponseWriter, r *http.Request) {
+	moderator, ok := cfg.requireModerator(w, r)
+	if !ok {
+		return
+	}
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid report ID", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	report, err := qtx.GetReportForUpdate(r.Context(), reportID)
+	if err != nil {
+		respondWithError(w, 404, "report not found", err)
+		return
+	}
+	if report.Status == reportStatusResolved {
+		respondWithError(w, 409, "report is already resolved", nil)
+		return
+	}
+	report, err = qtx.TriageReport(r.Context(), database.TriageReportParams{
+		ID:         report.ID,
+		AssigneeID: uuid.NullUUID{UUID: moderator.ID, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't triage report", err)
+		return
+	}
+	err = qtx.CreateAuditLogEntry(r.Context(), database.CreateAuditLogEntryParams{
+		ActorID:      uuid.NullUUID{UUID: moderator.ID, Valid: true},
+		Action:       auditActionTriage,
+		ReportID:     uuid.NullUUID{UUID: report.ID, Valid: true},
+		ChirpID:      report.ChirpID,
+		TargetUserID: uuid.NullUUID{UUID: report.ReportedUserID, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't triage report", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "couldn't triage report", err)
+		return
+	}
+	respondWithJSON(w, 200, newReportResponse(report))
+}
+
+// resolveReportHandler closes a report and applies the chosen action. The
+// report, the action and the audit entries are written in one transaction, so
+// the audit log never disagrees with what happened.
+func (cfg *apiConfig) resolveReportHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Action      string `json:"action"`
+		Note        string `json:"note"`
+		SuspendDays int    `json:"suspend_days"`
+	}
+	moderator, ok := cfg.requireModerator(w, r)
+	if !ok {
+		return
+	}
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid report ID", err)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, "couldn't decode parameters", err)
+		return
+	}
+	note := strings.TrimSpace(params.Note)
+	if utf8.RuneCountInString(note) > maxResolutionNoteLength {
+		respondWithError(w, 400, fmt.Sprintf("notes are limited to %d characters", maxResolutionNoteLength), nil)
+		return
+	}
+	switch params.Action {
+	case resolutionDismiss, resolutionHideChirp:
+	case resolutionSuspendUser:
+		if params.SuspendDays == 0 {
+			params.SuspendDays = defaultSuspensionDays
+		}
+		if params.SuspendDays < 1 || params.SuspendDays > maxSuspensionDays {
+			respondWithError(w, 400, fmt.Sprintf("suspend_days must be between 1 and %d", maxSuspensionDays), nil)
+			return
+		}
+	default:
+		respondWithError(w, 400, "action must be dismiss, hide_chirp or suspend_user", nil)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	report, err := qtx.GetReportForUpdate(r.Context(), reportID)
+	if err != nil {
+		respondWithError(w, 404, "report not found", err)
+		return
+	}
+	if report.Status == reportStatusResolved {
+		respondWithError(w, 409, "report is already resolved", nil)
+		return
+	}
+	actor := uuid.NullUUID{UUID: moderator.ID, Valid: true}
+	target := uuid.NullUUID{UUID: report.ReportedUserID, Valid: true}
+
+	switch params.Action {
+	case resolutionHideChirp:
+		if !report.ChirpID.Valid {
+			respondWithError(w, 400, "report is not about a chirp", nil)
+			return
+		}
+		err = qtx.HideChirp(r.Context(), report.ChirpID.UUID)
+		if err == nil {
+			_, err = qtx.UnpinChirp(r.Context(), report.ChirpID.UUID)
+		}
+		if err == nil {
+			err = qtx.CreateAuditLogEntry(r.Context(), database.CreateAuditLogEntryParams{
+				ActorID:      actor,
+				Action:       auditActionHideChirp,
+				ReportID:     uuid.NullUUID{UUID: report.ID, Valid: true},
+				ChirpID:      report.ChirpID,
+				TargetUserID: target,
+				Details:      note,
+			})
+		}
+		if err != nil {
+			respondWithError(w, 500, "couldn't hide chirp", err)
+			return
+		}
+	case resolutionSuspendUser:
+		reported, err := qtx.GetUserByID(r.Context(), report.ReportedUserID)
+		if err != nil {
+			respondWithError(w, 404, "user not found", err)
+			return
+		}
+		if reported.Role != roleUser {
+			respondWithError(w, 403, "cannot suspend a moderator", nil)
+			return
+		}
+		expiresAt := time.Now().UTC().AddDate(0, 0, params.SuspendDays)
+		_, err = suspendUser(r.Context(), qtx, database.CreateSuspensionParams{
+			UserID:    reported.ID,
+			CreatedBy: actor,
+			Reason:    fmt.Sprintf("report %s (%s): %s", report.ID, report.Reason, note),
+			ExpiresAt: sql.NullTime{Time: expiresAt, Valid: true},
+		})
+		if err == nil {
+			err = qtx.CreateAuditLogEntry(r.Context(), database.CreateAuditLogEntryParams{
+				ActorID:      actor,
+				Action:       auditActionSuspendUser,
+				ReportID:     uuid.NullUUID{UUID: report.ID, Valid: true},
+				ChirpID:      report.ChirpID,
+				TargetUserID: target,
+				Details:      fmt.Sprintf("until %s: %s", expiresAt.Format(time.RFC3339), note),
+			})
+		}
+		if err != nil {
+			respondWithError(w, 500, "couldn't suspend user", err)
+			return
+		}
+	}
+
+	report, err = qtx.ResolveReport(r.Context(), database.ResolveReportParams{
+		ID:             report.ID,
+		Resolution:     params.Action,
+		ResolutionNote: note,
+		ResolvedBy:     moderator.ID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't resolve report", err)
+		return
+	}
+	err = qtx.CreateAuditLogEntry(r.Context(), database.CreateAuditLogEntryParams{
+		ActorID:      actor,
+		Action:       auditActionResolve,
+		ReportID:     uuid.NullUUID{UUID: report.ID, Valid: true},
+		ChirpID:      report.ChirpID,
+		TargetUserID: target,
+		Details:      params.Action,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't resolve report", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "couldn't resolve report", err)
+		return
+	}
+	respondWithJSON(w, 200, newReportResponse(report))
+}
+
+func (cfg *apiConfig) listAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.requireModerator(w, r); !ok {
+		return
+	}
+	limit, err := parseLimit(r, defaultListLimit, maxListLimit)
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+	entries, err := cfg.dbQueries.ListAuditLog(r.Context(), limit)
+	if err != nil {
+		respondWithError(w, 500, "couldn't list audit log", err)
+		return
+	}
+	resp := []auditLogEntryResponse{}
+	for _, entry := range entries {
+		resp = append(resp, auditLogEntryResponse{
+			ID:           entry.ID,
+			CreatedAt:    entry.CreatedAt,
+			ActorID:      nullUUIDPtr(entry.ActorID),
+			Action:       entry.Action,
+			ReportID:     nullUUIDPtr(entry.ReportID),
+			ChirpID:      nullUUIDPtr(entry.ChirpID),
+			TargetUserID: nullUUIDPtr(entry.TargetUserID),
+			Details:      entry.Details,
+		})
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+// setUserRoleHandler lets an admin, authenticated with the admin API key,
+// promote or demote a user.
+func (cfg *apiConfig) setUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Role string `json:"role"`
+	}
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid user ID", err)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, "couldn't decode parameters", err)
+		return
+	}
+	if params.Role != roleUser && params.Role != roleModerator && params.Role != roleAdmin {
+		respondWithError(w, 400, "role must be user, moderator or admin", nil)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	updated, err := qtx.SetUserRole(r.Context(), database.SetUserRoleParams{
+		ID:   userID,
+		Role: params.Role,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't set role", err)
+		return
+	}
+	if updated == 0 {
+		respondWithError(w, 404, "user not found", nil)
+		return
+	}
+	err = qtx.CreateAuditLogEntry(r.Context(), database.CreateAuditLogEntryParams{
+		Action:       auditActionSetUserRole,
+		TargetUserID: uuid.NullUUID{UUID: userID, Valid: true},
+		Details:      params.Role,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't set role", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "couldn't set role", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newReportResponse(report database.Report) reportResponse {
+	resp := reportResponse{
+		ID:             report.ID,
+		CreatedAt:      report.CreatedAt,
+		UpdatedAt:      report.UpdatedAt,
+		ReporterID:     report.ReporterID,
+		ReportedUserID: report.ReportedUserID,
+		ChirpID:        nullUUIDPtr(report.ChirpID),
+		Reason:         report.Reason,
+		Details:        report.Details,
+		Status:         report.Status,
+		AssigneeID:     nullUUIDPtr(report.AssigneeID),
+		Resolution:     report.Resolution.String,
+		ResolutionNote: report.ResolutionNote,
+		ResolvedBy:     nullUUIDPtr(report.ResolvedBy),
+	}
+	if report.ResolvedAt.Valid {
+		resolvedAt := report.ResolvedAt.Time
+		resp.ResolvedAt = &resolvedAt
+	}
+	return resp
+}
+
+func nullUUIDPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/search"
+)
+
+const (
+	maxSearchQueryLength = 500
+	defaultSearchLimit   = 20
+	maxSearchLimit       = 100
+)
+
+// searchChirpsHandler finds chirps matching a search query; see search.Parse
+// for its syntax. Results are ranked by relevance, or newest first with
+// sort=recent, which is the default for queries with no text and the only
+// order that can be paged through. Search is discovery, so limited chirps
+// are left out, as are chirps with keywords the viewer muted.
+func (cfg *apiConfig) searchChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if len(q) > maxSearchQueryLength {
+		respondWithError(w, 400, fmt.Sprintf("q must be at most %d bytes", maxSearchQueryLength), nil)
+		return
+	}
+	query, err := search.Parse(q)
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+	order := r.URL.Query().Get("sort")
+	if order == "" {
+		order = "relevance"
+		if query.Text == "" {
+			order = "recent"
+		}
+	}
+	if order != "relevance" && order != "recent" {
+		respondWithError(w, 400, `sort must be "relevance" or "recent"`, nil)
+		return
+	}
+	byRelevance := order == "relevance"
+	limit, err := parseLimit(r, defaultSearchLimit, maxSearchLimit)
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+	cursor, hasCursor, err := parseCursor(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+	if hasCursor && byRelevance {
+		respondWithError(w, 400, "cursor requires sort=recent", nil)
+		return
+	}
+
+	viewerID := cfg.viewerID(r)
+	rows, err := cfg.dbQueries.SearchChirps(r.Context(), database.SearchChirpsParams{
+		Query:       query.Text,
+		ViewerID:    viewerID,
+		TagPatterns: query.TagPatterns(),
+		// A nil slice would be sent as NULL, which matches nothing.
+		FromHandles: append([]string{}, query.From...),
+		HasSince:    !query.Since.IsZero(),
+		Since:       query.Since,
+		HasUntil:    !query.Until.IsZero(),
+		Until:       query.Until,
+		HasCursor:   hasCursor,
+		CursorTime:  cursor.Time,
+		CursorID:    cursor.ID,
+		ByRelevance: byRelevance,
+		RowLimit:    limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't search chirps", err)
+		return
+	}
+	resp := chirpSearchResponse{Chirps: []chirpSearchResult{}}
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		if !byRelevance {
+			last := rows[len(rows)-1]
+			resp.NextCursor = listCursor{Time: last.CreatedAt, ID: last.ID}.String()
+		}
+	}
+
+	mutes, err := cfg.loadKeywordMutes(r.Context(), viewerID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load muted keywords", err)
+		return
+	}
+	chirps := make([]database.Chirp, 0, len(rows))
+	snippets := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if mutes.hides(row.UserID, row.Body, row.ContentWarning) {
+			continue
+		}
+		chirps = append(chirps, database.Chirp{
+			ID:             row.ID,
+			CreatedAt:      row.CreatedAt,
+			UpdatedAt:      row.UpdatedAt,
+			Body:           row.Body,
+			UserID:         row.UserID,
+			DeletedAt:      row.DeletedAt,
+			HiddenAt:       row.HiddenAt,
+			Visibility:     row.Visibility,
+			ContentWarning: row.ContentWarning,
+			Sensitive:      row.Sensitive,
+			EditedAt:       row.EditedAt,
+			ReplyToID:      row.ReplyToID,
+		})
+		snippets = append(snippets, search.Highlight(row.Snippet))
+	}
+	responses, err := cfg.chirpResponses(r.Context(), chirps, viewerID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load chirps", err)
+		return
+	}
+	for i, chirp := range responses {
+		resp.Chirps = append(resp.Chirps, chirpSearchResult{ChirpResponse: chirp, Snippet: snippets[i]})
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+// searchUsersHandler finds users by handle or display name. An exact handle
+// comes first, then handles starting with the query, then users with words
+// in their handle or display name starting with the words of the query.
+// Limited users are left out, as limited chirps are from chirp search.
+func (cfg *apiConfig) searchUsersHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if len(q) > maxSearchQueryLength {
+		respondWithError(w, 400, fmt.Sprintf("q must be at most %d bytes", maxSearchQueryLength), nil)
+		return
+	}
+	query, err := search.ParseUserQuery(q)
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+	limit, err := parseLimit(r, defaultSearchLimit, maxSearchLimit)
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+
+	rows, err := cfg.dbQueries.SearchUsers(r.Context(), database.SearchUsersParams{
+		HandlePattern: query.HandlePattern,
+		Words:         query.Words,
+		Handle:        query.Handle,
+		ViewerID:      cfg.viewerID(r),
+		RowLimit:      limit,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't search users", err)
+		return
+	}
+	resp := userSearchResponse{Users: make([]userSearchResult, 0, len(rows))}
+	for _, row := range rows {
+		result := userSearchResult{
+			ID:          row.ID,
+			Handle:      row.Handle,
+			DisplayName: row.DisplayName,
+			Bio:         row.Bio,
+			IsChirpyRed: row.IsChirpyRed,
+		}
+		if row.AvatarKey.Valid {
+			result.AvatarURL = mediaURL(row.AvatarKey.String)
+			result.AvatarThumbURL = mediaURL(row.AvatarThumbnailKey.String)
+		}
+		resp.Users = append(resp.Users, result)
+	}
+	respondWithJSON(w, 200, resp)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/events"
+	"github.com/Throne-of-Doom/chirpy/internal/spam"
+	"github.com/google/uuid"
+)
+
+const (
+	spamReviewApprove = "approve"
+	spamReviewRemove  = "remove"
+)
+
+// listSpamScoresHandler lists spam check results with a given decision,
+// newest first. Held chirps waiting for review are listed by default;
+// ?pending=false includes reviewed ones, and ?decision=allow or reject shows
+// the other outcomes for tuning the thresholds.
+func (cfg *apiConfig) listSpamScoresHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := cfg.requireModerator(w, r); !ok {
+		return
+	}
+	decision := r.URL.Query().Get("decision")
+	if decision == "" {
+		decision = string(spam.Hold)
+	}
+	switch spam.Decision(decision) {
+	case spam.Allow, spam.Hold, spam.Reject:
+	default:
+		respondWithError(w, 400, "decision must be allow, hold or reject", nil)
+		return
+	}
+	limit, err := parseLimit(r, defaultListLimit, maxListLimit)
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+	scores, err := cfg.dbQueries.ListSpamScores(r.Context(), database.ListSpamScoresParams{
+		Decision: decision,
+		Pending:  r.URL.Query().Get("pending") != "false",
+		RowLimit: limit,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't list spam scores", err)
+		return
+	}
+	resp := []spamScoreResponse{}
+	for _, score := range scores {
+		resp = append(resp, newSpamScoreResponse(score))
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+// reviewSpamScoreHandler settles a held chirp: approving publishes it and
+// removing leaves it hidden for good. Either way the review is recorded on
+// the score and in the audit log.
+func (cfg *apiConfig) reviewSpamScoreHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Action string `json:"action"`
+	}
+	moderator, ok := cfg.requireModerator(w, r)
+	if !ok {
+		return
+	}
+	scoreID, err := uuid.Parse(r.PathValue("scoreID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid spam score ID", err)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, "couldn't decode parameters", err)
+		return
+	}
+	if params.Action != spamReviewApprove && params.Action != spamReviewRemove {
+		respondWithError(w, 400, "action must be approve or remove", nil)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	score, err := qtx.GetSpamScoreForUpdate(r.Context(), scoreID)
+	if err != nil {
+		respondWithError(w, 404, "spam score not found", err)
+		return
+	}
+	if score.Decision != string(spam.Hold) {
+		respondWithError(w, 409, "only held chirps can be reviewed", nil)
+		return
+	}
+	if score.Review.Valid {
+		respondWithError(w, 409, "chirp has already been reviewed", nil)
+		return
+	}
+	if !score.ChirpID.Valid {
+		respondWithError(w, 409, "chirp no longer exists", nil)
+		return
+	}
+
+	action := auditActionRemoveHeldChirp
+	if params.Action == spamReviewApprove {
+		action = auditActionApproveHeldChirp
+		err = qtx.UnhideChirp(r.Context(), score.ChirpID.UUID)
+		if err != nil {
+			respondWithError(w, 500, "couldn't approve chirp", err)
+			return
+		}
+		chirp, err := qtx.GetChirpForModeration(r.Context(), score.ChirpID.UUID)
+		if err != nil {
+			respondWithError(w, 500, "couldn't approve chirp", err)
+			return
+		}
+		// A held edit of a chirp that was already out is announced as an
+		// edit rather than as a new chirp.
+		eventType := events.ChirpCreated
+		if chirp.EditedAt.Valid {
+			eventType = events.ChirpEdited
+		}
+		err = events.Append(r.Context(), qtx, eventType, chirp.UserID, newChirpEvent(chirp))
+		if err != nil {
+			respondWithError(w, 500, "couldn't approve chirp", err)
+			return
+		}
+	}
+	score, err = qtx.ReviewSpamScore(r.Context(), database.ReviewSpamScoreParams{
+		ID:         score.ID,
+		Review:     sql.NullString{String: params.Action, Valid: true},
+		ReviewedBy: uuid.NullUUID{UUID: moderator.ID, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't review chirp", err)
+		return
+	}
+	err = qtx.CreateAuditLogEntry(r.Context(), database.CreateAuditLogEntryParams{
+		ActorID:      uuid.NullUUID{UUID: moderator.ID, Valid: true},
+		Action:       action,
+		ChirpID:      score.ChirpID,
+		TargetUserID: uuid.NullUUID{UUID: score.UserID, Valid: true},
+		Details:      fmt.Sprintf("spam score %.2f", score.Score),
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't review chirp", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "couldn't review chirp", err)
+		return
+	}
+	respondWithJSON(w, 200, newSpamScoreResponse(score))
+}
+
+func newSpamScoreResponse(score database.SpamScore) spamScoreResponse {
+	resp := spamScoreResponse{
+		ID:         score.ID,
+		CreatedAt:  score.CreatedAt,
+		UserID:     score.UserID,
+		ChirpID:    nullUUIDPtr(score.ChirpID),
+		Body:       score.Body,
+		Score:      score.Score,
+		Decision:   score.Decision,
+		Reasons:    score.Reasons,
+		Review:     score.Review.String,
+		ReviewedBy: nullUUIDPtr(score.ReviewedBy),
+	}
+	if score.ReviewedAt.Valid {
+		reviewedAt := score.ReviewedAt.Time
+		resp.ReviewedAt = &reviewedAt
+	}
+	return resp
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+// streamUserID authenticates a stream request.
+func (cfg *apiConfig) streamUserID(r *http.Request) (uuid.UUID, error) {
+	token, err := streamToken(r)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return auth.ValidateJWT(token, cfg.SECRET)
+}
+
+// streamToken reads the access token of a stream request. Browsers cannot
+// set headers on an EventSource or a WebSocket, so it may also be sent in the
+// access_token query parameter.
+func streamToken(r *http.Request) (string, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		token = r.URL.Query().Get("access_token")
+		if token == "" {
+			return "", err
+		}
+	}
+	return token, nil
+}
+
+// streamChirpsHandler streams new chirps from everyone. Signing in is
+// optional and only hides chirps with keywords the viewer has muted.
+func (cfg *apiConfig) streamChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	viewerID, err := cfg.streamUserID(r)
+	if err != nil {
+		viewerID = uuid.Nil
+	}
+	mutes, err := cfg.loadKeywordMutes(r.Context(), viewerID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load muted keywords", err)
+		return
+	}
+	cfg.serveStream(w, r, []string{streamTopicPublic}, mutes)
+}
+
+// streamTimelineHandler streams new chirps from the caller and the users they
+// follow. Follows made after connecting apply from the next connection.
+func (cfg *apiConfig) streamTimelineHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.streamUserID(r)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	followees, err := cfg.dbQueries.ListFolloweeIDs(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load follows", err)
+		return
+	}
+	mutes, err := cfg.loadKeywordMutes(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load muted keywords", err)
+		return
+	}
+	topics := []string{authorTopic(userID)}
+	for _, followee := range followees {
+		topics = append(topics, authorTopic(followee))
+	}
+	cfg.serveStream(w, r, topics, mutes)
+}
+
+// streamNotificationsHandler streams the caller's notifications as they are
+// created or gain actors.
+func (cfg *apiConfig) streamNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.streamUserID(r)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	// Notifications are not chirps, so no mutes apply.
+	mutes, err := cfg.loadKeywordMutes(r.Context(), uuid.Nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load muted keywords", err)
+		return
+	}
+	cfg.serveStream(w, r, []string{notificationTopic(userID)}, mutes)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/subscription"
+)
+
+const subscriptionHistoryLimit = 50
+
+// getSubscriptionHandler returns the caller's subscription with its recent
+// history.
+func (cfg *apiConfig) getSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	dbSub, err := cfg.dbQueries.GetSubscriptionByUserID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "no subscription", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "couldn't get subscription", err)
+		return
+	}
+	events, err := cfg.dbQueries.ListSubscriptionEvents(r.Context(), database.ListSubscriptionEventsParams{
+		SubscriptionID: dbSub.ID,
+		Limit:          subscriptionHistoryLimit,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't get subscription history", err)
+		return
+	}
+
+	resp := subscriptionResponse{
+		Plan:               dbSub.Plan,
+		Status:             dbSub.Status,
+		IsChirpyRed:        subscription.Entitled(subscriptionState(dbSub), time.Now().UTC()),
+		CurrentPeriodStart: dbSub.CurrentPeriodStart,
+		CurrentPeriodEnd:   timePtr(dbSub.CurrentPeriodEnd),
+		GraceUntil:         timePtr(dbSub.GraceUntil),
+		CancelledAt:        timePtr(dbSub.CancelledAt),
+		History:            []subscriptionEventResponse{},
+	}
+	for _, event := range events {
+		resp.History = append(resp.History, subscriptionEventResponse{
+			CreatedAt:        event.CreatedAt,
+			Event:            event.Event,
+			Status:           event.Status,
+			CurrentPeriodEnd: timePtr(event.CurrentPeriodEnd),
+		})
+	}
+	respondWithJSON(w, 200, resp)
+}
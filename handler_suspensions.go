@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxSuspensionReasonLength = 500
+	auditActionBanUser        = "ban_user"
+	auditActionLiftSuspension = "lift_suspension"
+)
+
+// suspendUser records a suspension and revokes the user's refresh tokens, so
+// they are signed out once their current access token expires. It should run
+// in a transaction with the matching audit entry.
+func suspendUser(ctx context.Context, qtx *database.Queries, params database.CreateSuspensionParams) (database.UserSuspension, error) {
+	suspension, err := qtx.CreateSuspension(ctx, params)
+	if err != nil {
+		return database.UserSuspension{}, err
+	}
+	err = qtx.RevokeUserRefreshTokens(ctx, params.UserID)
+	if err != nil {
+		return database.UserSuspension{}, err
+	}
+	return suspension, nil
+}
+
+// checkNotSuspended writes a 403 response and returns false if the user has
+// an active suspension or ban.
+func (cfg *apiConfig) checkNotSuspended(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	suspension, err := cfg.dbQueries.GetActiveSuspension(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return true
+	}
+	if err != nil {
+		respondWithError(w, 500, "couldn't check account status", err)
+		return false
+	}
+	respondWithError(w, 403, suspensionMessage(suspension), nil)
+	return false
+}
+
+func suspensionMessage(suspension database.UserSuspension) string {
+	if !suspension.ExpiresAt.Valid {
+		return fmt.Sprintf("account is banned: %s", suspension.Reason)
+	}
+	return fmt.Sprintf("account is suspended until %s: %s", suspension.ExpiresAt.Time.Format(time.RFC3339), suspension.Reason)
+}
+
+// createSuspensionHandler suspends a user for a number of days, or bans them
+// permanently, and hides their chirps while it lasts.
+func (cfg *apiConfig) createSuspensionHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Reason    string `json:"reason"`
+		Days      int    `json:"days"`
+		Permanent bool   `json:"permanent"`
+	}
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid user ID", err)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, "couldn't decode parameters", err)
+		return
+	}
+	reason := strings.TrimSpace(params.Reason)
+	if reason == "" || utf8.RuneCountInString(reason) > maxSuspensionReasonLength {
+		respondWithError(w, 400, fmt.Sprintf("reason is required and limited to %d characters", maxSuspensionReasonLength), nil)
+		return
+	}
+	expiresAt := sql.NullTime{}
+	action := auditActionBanUser
+	if !params.Permanent {
+		if params.Days < 1 || params.Days > maxSuspensionDays {
+			respondWithError(w, 400, fmt.Sprintf("days must be between 1 and %d, or set permanent", maxSuspensionDays), nil)
+			return
+		}
+		expiresAt = sql.NullTime{Time: time.Now().UTC().AddDate(0, 0, params.Days), Valid: true}
+		action = auditActionSuspendUser
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	_, err = qtx.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "user not found", err)
+		return
+	}
+	suspension, err := suspendUser(r.Context(), qtx, database.CreateSuspensionParams{
+		UserID:    userID,
+		Reason:    reason,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't suspend user", err)
+		return
+	}
+	err = qtx.CreateAuditLogEntry(r.Context(), database.CreateAuditLogEntryParams{
+		Action:       action,
+		TargetUserID: uuid.NullUUID{UUID: userID, Valid: true},
+		Details:      reason,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't suspend user", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "couldn't suspend user", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, newSuspensionResponse(suspension))
+}
+
+func (cfg *apiConfig) listSuspensionsHandler(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid user ID", err)
+		return
+	}
+	suspensions, err := cfg.dbQueries.ListUserSuspensions(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't list suspensions", err)
+		return
+	}
+	resp := []suspensionResponse{}
+	for _, suspension := range suspensions {
+		resp = append(resp, newSuspensionResponse(suspension))
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+// liftSuspensionsHandler ends every active suspension and ban on a user.
+// Revoked refresh tokens stay revoked, so the user has to log in again.
+func (cfg *apiConfig) liftSuspensionsHandler(w http.ResponseWriter, r *http.Request) {
+	if !cfg.requireAdmin(w, r) {
+		return
+	}
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid user ID", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	lifted, err := qtx.LiftSuspensions(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't lift suspension", err)
+		return
+	}
+	if lifted == 0 {
+		respondWithError(w, 404, "user has no active suspension", nil)
+		return
+	}
+	err = qtx.CreateAuditLogEntry(r.Context(), database.CreateAuditLogEntryParams{
+		Action:       auditActionLiftSuspension,
+		TargetUserID: uuid.NullUUID{UUID: userID, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't lift suspension", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "couldn't lift suspension", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newSuspensionResponse(suspension database.UserSuspension) suspensionResponse {
+	resp := suspensionResponse{
+		ID:        suspension.ID,
+		CreatedAt: suspension.CreatedAt,
+		UserID:    suspension.UserID,
+		CreatedBy: nullUUIDPtr(suspension.CreatedBy),
+		Reason:    suspension.Reason,
+		Permanent: !suspension.ExpiresAt.Valid,
+	}
+	if suspension.ExpiresAt.Valid {
+		expiresAt := suspension.ExpiresAt.Time
+		resp.ExpiresAt = &expiresAt
+	}
+	if suspension.LiftedAt.Valid {
+		liftedAt := suspension.LiftedAt.Time
+		resp.LiftedAt = &liftedAt
+	}
+	return resp
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultTrashRetention = 30 * 24 * time.Hour
+	trashPurgeInterval    = time.Hour
+)
+
+func (cfg *apiConfig) getTrashHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	chirps, err := cfg.dbQueries.GetTrashedChirps(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't list trash", err)
+		return
+	}
+	resp, err := cfg.chirpResponses(r.Context(), chirps, userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load chirps", err)
+		return
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+func (cfg *apiConfig) restoreChirpHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid chirp ID", err)
+		return
+	}
+	chirp, err := cfg.dbQueries.GetTrashedChirp(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, 404, "chirp not found in trash", err)
+		return
+	}
+	if chirp.UserID != userID {
+		respondWithError(w, 403, "cannot restore chirp", nil)
+		return
+	}
+	restored, err := cfg.dbQueries.RestoreChirp(r.Context(), chirp.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "chirp not found in trash", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "couldn't restore chirp", err)
+		return
+	}
+	resp, err := cfg.chirpResponse(r.Context(), restored, userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load chirp", err)
+		return
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+// runTrashPurge permanently deletes chirps that have been in the trash for
+// longer than retention, checking every interval until ctx is cancelled.
+func (cfg *apiConfig) runTrashPurge(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		purged, err := cfg.purgeTrash(ctx, time.Now().UTC().Add(-retention))
+		if err != nil {
+			log.Printf("couldn't purge trashed chirps: %v", err)
+			continue
+		}
+		if purged > 0 {
+			log.Printf("purged %d trashed chirps", purged)
+		}
+	}
+}
+
+// purgeTrash deletes chirps trashed before cutoff and returns how many it
+// deleted. Their media rows go with them through ON DELETE CASCADE, and the
+// same statement returns the media's blob keys, so only the blobs of chirps
+// that were actually purged are removed, once the rows are gone.
+func (cfg *apiConfig) purgeTrash(ctx context.Context, cutoff time.Time) (int64, error) {
+	rows, err := cfg.dbQueries.PurgeTrashedChirps(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	purged := make(map[uuid.UUID]struct{}, len(rows))
+	for _, row := range rows {
+		purged[row.ID] = struct{}{}
+		if row.StorageKey.Valid {
+			cfg.deleteBlobs(ctx, row.StorageKey.String, row.ThumbnailKey.String)
+		}
+	}
+	return int64(len(purged)), nil
+}
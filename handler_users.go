@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/Throne-of-Doom/chirpy/internal/auth"
 	"github.com/Throne-of-Doom/chirpy/internal/database"
@@ -10,14 +11,29 @@ import (
 )
 
 func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		CredentialsRequest
+		Handle string `json:"handle"`
+	}
 
 	decoder := json.NewDecoder(r.Body)
-	params := CredentialsRequest{}
+	params := parameters{}
 	err := decoder.Decode(&params)
 	if err != nil {
 		respondWithError(w, 500, "couldn't decode parameters", err)
 		return
 	}
+	handle := strings.TrimSpace(params.Handle)
+	if handle == "" {
+		handle, err = generateHandle()
+		if err != nil {
+			respondWithError(w, 500, "couldn't generate handle", err)
+			return
+		}
+	} else if err := validateHandle(handle); err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
 	hashedPW, err := auth.HashPassword(params.Password)
 	if err != nil {
 		respondWithError(w, 500, "an error has ocurred", err)
@@ -27,18 +43,27 @@ func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request)
 	dbUser, err := cfg.dbQueries.CreateUser(r.Context(), database.CreateUserParams{
 		Email:          params.Email,
 		HashedPassword: hashedPW,
+		Handle:         handle,
 	})
+	if isUniqueViolation(err, "users_handle_lower_idx") {
+		respondWithError(w, 409, "handle is already taken", err)
+		return
+	}
 	if err != nil {
 		respondWithError(w, 500, "couldn't call database", err)
 		return
 	}
 
 	resp := user{
-		ID:          dbUser.ID,
-		CreatedAt:   dbUser.CreatedAt,
-		UpdatedAt:   dbUser.UpdatedAt,
-		Email:       dbUser.Email,
-		IsChirpyRed: dbUser.IsChirpyRed,
+		ID:              dbUser.ID,
+		CreatedAt:       dbUser.CreatedAt,
+		UpdatedAt:       dbUser.UpdatedAt,
+		Email:           dbUser.Email,
+		Handle:          dbUser.Handle,
+		DisplayName:     dbUser.DisplayName,
+		Bio:             dbUser.Bio,
+		Website:         dbUser.Website,
+		ExpandSensitive: dbUser.ExpandSensitive,
 	}
 	respondWithJSON(w, 201, resp)
 }
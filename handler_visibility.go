@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Visibility states, from least to most restrictive. See
+// sql/schema/017_visibility.sql for how each one is applied.
+const (
+	visibilityPublic     = "public"
+	visibilityWarned     = "warned"
+	visibilityLimited    = "limited"
+	visibilityAuthorOnly = "author_only"
+)
+
+type visibilityParameters struct {
+	Visibility string `json:"visibility"`
+	Note       string `json:"note"`
+}
+
+// decodeVisibilityParameters reads and checks a request to change a
+// visibility state. It writes an error response and returns false if the
+// request is invalid.
+func decodeVisibilityParameters(w http.ResponseWriter, r *http.Request) (visibilityParameters, bool) {
+	decoder := json.NewDecoder(r.Body)
+	params := visibilityParameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, "couldn't decode parameters", err)
+		return params, false
+	}
+	switch params.Visibility {
+	case visibilityPublic, visibilityWarned, visibilityLimited, visibilityAuthorOnly:
+	default:
+		respondWithError(w, 400, "visibility must be public, warned, limited or author_only", nil)
+		return params, false
+	}
+	params.Note = strings.TrimSpace(params.Note)
+	if utf8.RuneCountInString(params.Note) > maxResolutionNoteLength {
+		respondWithError(w, 400, fmt.Sprintf("notes are limited to %d characters", maxResolutionNoteLength), nil)
+		return params, false
+	}
+	return params, true
+}
+
+// setChirpVisibilityHandler changes the visibility of a single chirp.
+func (cfg *apiConfig) setChirpVisibilityHandler(w http.ResponseWriter, r *http.Request) {
+	moderator, ok := cfg.requireModerator(w, r)
+	if !ok {
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid chirp ID", err)
+		return
+	}
+	params, ok := decodeVisibilityParameters(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	chirp, err := qtx.GetChirpForModeration(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, 404, "chirp not found", err)
+		return
+	}
+	_, err = qtx.SetChirpVisibility(r.Context(), database.SetChirpVisibilityParams{
+		ID:         chirp.ID,
+		Visibility: params.Visibility,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't set visibility", err)
+		return
+	}
+	err = qtx.CreateAuditLogEntry(r.Context(), database.CreateAuditLogEntryParams{
+		ActorID:      uuid.NullUUID{UUID: moderator.ID, Valid: true},
+		Action:       auditActionSetChirpVisibility,
+		ChirpID:      uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		TargetUserID: uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+		Details:      visibilityAuditDetails(params),
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't set visibility", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "couldn't set visibility", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setUserVisibilityHandler changes the visibility of every chirp a user has
+// posted or will post, on top of each chirp's own state.
+func (cfg *apiConfig) setUserVisibilityHandler(w http.ResponseWriter, r *http.Request) {
+	moderator, ok := cfg.requireModerator(w, r)
+	if !ok {
+		return
+	}
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid user ID", err)
+		return
+	}
+	params, ok := decodeVisibilityParameters(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	updated, err := qtx.SetUserVisibility(r.Context(), database.SetUserVisibilityParams{
+		ID:         userID,
+		Visibility: params.Visibility,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't set visibility", err)
+		return
+	}
+	if updated == 0 {
+		respondWithError(w, 404, "user not found", nil)
+		return
+	}
+	err = qtx.CreateAuditLogEntry(r.Context(), database.CreateAuditLogEntryParams{
+		ActorID:      uuid.NullUUID{UUID: moderator.ID, Valid: true},
+		Action:       auditActionSetUserVisibility,
+		TargetUserID: uuid.NullUUID{UUID: userID, Valid: true},
+		Details:      visibilityAuditDetails(params),
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't set visibility", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "couldn't set visibility", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func visibilityAuditDetails(params visibilityParameters) string {
+	if params.Note == "" {
+		return params.Visibility
+	}
+	return params.Visibility + ": " + params.Note
+}
+
+// setChirpContentWarningHandler lets a moderator put a content warning or the
+// sensitive flag on someone else's chirp, or take them off.
+func (cfg *apiConfig) setChirpContentWarningHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ContentWarning string `json:"content_warning"`
+		Sensitive      bool   `json:"sensitive"`
+		Note           string `json:"note"`
+	}
+	moderator, ok := cfg.requireModerator(w, r)
+	if !ok {
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid chirp ID", err)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, "couldn't decode parameters", err)
+		return
+	}
+	warning, err := validateContentWarning(params.ContentWarning)
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+	note := strings.TrimSpace(params.Note)
+	if utf8.RuneCountInString(note) > maxResolutionNoteLength {
+		respondWithError(w, 400, fmt.Sprintf("notes are limited to %d characters", maxResolutionNoteLength), nil)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	chirp, err := qtx.GetChirpForModeration(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, 404, "chirp not found", err)
+		return
+	}
+	err = qtx.SetChirpContentWarning(r.Context(), database.SetChirpContentWarningParams{
+		ID:             chirp.ID,
+		ContentWarning: warning,
+		Sensitive:      params.Sensitive,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't set content warning", err)
+		return
+	}
+	details := fmt.Sprintf("content warning %q, sensitive %t", warning, params.Sensitive)
+	if note != "" {
+		details += ": " + note
+	}
+	err = qtx.CreateAuditLogEntry(r.Context(), database.CreateAuditLogEntryParams{
+		ActorID:      uuid.NullUUID{UUID: moderator.ID, Valid: true},
+		Action:       auditActionSetContentWarning,
+		ChirpID:      uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		TargetUserID: uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+		Details:      details,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't set content warning", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "couldn't set content warning", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
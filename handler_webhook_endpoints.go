@@ -0,0 +1,379 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+const (
+	maxWebhookEndpoints         = 10
+	maxWebhookDescriptionLength = 200
+	maxWebhookURLLength         = 2048
+)
+
+// validateWebhookURL checks that an endpoint URL is absolute, uses HTTPS and
+// is not an IP address outside the public internet. The sender checks every
+// address it connects to as well, which also covers hostnames. Plain HTTP and
+// private addresses are allowed on the dev platform so that local receivers
+// work.
+func validateWebhookURL(raw string, dev bool) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) > maxWebhookURLLength {
+		return "", fmt.Errorf("url is limited to %d characters", maxWebhookURLLength)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "", errors.New("url must be an absolute URL")
+	}
+	if u.Scheme != "https" && !(dev && u.Scheme == "http") {
+		return "", errors.New("url must use https")
+	}
+	if ip, err := netip.ParseAddr(u.Hostname()); err == nil && !dev && !webhooks.PublicAddr(ip) {
+		return "", errors.New("url must not point to a private address")
+	}
+	if u.User != nil {
+		return "", errors.New("url must not contain credentials")
+	}
+	return u.String(), nil
+}
+
+// createWebhookEndpointHandler registers an endpoint for some of the caller's
+// events. The signing secret is generated here and only returned once.
+func (cfg *apiConfig) createWebhookEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		URL         string   `json:"url"`
+		Events      []string `json:"events"`
+		Description string   `json:"description"`
+	}
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, "couldn't decode parameters", err)
+		return
+	}
+	endpointURL, err := validateWebhookURL(params.URL, cfg.PLATFORM == "dev")
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+	if len(params.Events) == 0 {
+		respondWithError(w, 400, "events must list at least one event type", nil)
+		return
+	}
+	events := []string{}
+	seen := map[string]bool{}
+	for _, event := range params.Events {
+		if !webhooks.ValidEventType(event) {
+			respondWithError(w, 400, fmt.Sprintf("unknown event type %q; must be one of %s", event, strings.Join(webhooks.EventTypes, ", ")), nil)
+			return
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	description := strings.TrimSpace(params.Description)
+	if utf8.RuneCountInString(description) > maxWebhookDescriptionLength {
+		respondWithError(w, 400, fmt.Sprintf("descriptions are limited to %d characters", maxWebhookDescriptionLength), nil)
+		return
+	}
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		respondWithError(w, 500, "couldn't generate secret", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	// Lock the user row so concurrent requests cannot both pass the limit.
+	_, err = qtx.GetUserByIDForUpdate(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "user not found", err)
+		return
+	}
+	count, err := qtx.CountWebhookEndpoints(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't count webhook endpoints", err)
+		return
+	}
+	if count >= maxWebhookEndpoints {
+		respondWithError(w, 409, fmt.Sprintf("you can register up to %d webhook endpoints", maxWebhookEndpoints), nil)
+		return
+	}
+	endpoint, err := qtx.CreateWebhookEndpoint(r.Context(), database.CreateWebhookEndpointParams{
+		UserID:      userID,
+		Url:         endpointURL,
+		Secret:      secret,
+		EventTypes:  events,
+		Description: description,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't create webhook endpoint", err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, "couldn't create webhook endpoint", err)
+		return
+	}
+
+	resp := newWebhookEndpointResponse(endpoint)
+	resp.Secret = endpoint.Secret
+	respondWithJSON(w, http.StatusCreated, resp)
+}
+
+func (cfg *apiConfig) listWebhookEndpointsHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	endpoints, err := cfg.dbQueries.ListWebhookEndpoints(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't list webhook endpoints", err)
+		return
+	}
+	resp := []webhookEndpointResponse{}
+	for _, endpoint := range endpoints {
+		resp = append(resp, newWebhookEndpointResponse(endpoint))
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+// deleteWebhookEndpointHandler removes an endpoint along with its pending
+// deliveries and delivery log.
+func (cfg *apiConfig) deleteWebhookEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	endpointID, err := uuid.Parse(r.PathValue("endpointID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid endpoint ID", err)
+		return
+	}
+	deleted, err := cfg.dbQueries.DeleteWebhookEndpoint(r.Context(), database.DeleteWebhookEndpointParams{
+		ID:     endpointID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't delete webhook endpoint", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "webhook endpoint not found", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listWebhookDeliveriesHandler is the delivery log for an endpoint, newest
+// first, optionally filtered with ?status=pending|delivered|dead.
+func (cfg *apiConfig) listWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := cfg.ownWebhookEndpoint(w, r)
+	if !ok {
+		return
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", webhookStatusPending, webhookStatusDelivered, webhookStatusDead:
+	default:
+		respondWithError(w, 400, "status must be pending, delivered or dead", nil)
+		return
+	}
+	limit, err := parseLimit(r, defaultListLimit, maxListLimit)
+	if err != nil {
+		respondWithError(w, 400, err.Error(), err)
+		return
+	}
+	deliveries, err := cfg.dbQueries.ListWebhookDeliveries(r.Context(), database.ListWebhookDeliveriesParams{
+		EndpointID: endpoint.ID,
+		Status:     status,
+		RowLimit:   limit,
+	})
+	if err != nil {
+		respondWithError(w, 500, "couldn't list deliveries", err)
+		return
+	}
+	resp := []webhookDeliveryResponse{}
+	for _, delivery := range deliveries {
+		resp = append(resp, newWebhookDeliveryResponse(delivery))
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+// getWebhookDeliveryHandler returns one delivery with its payload and every
+// attempt made at it.
+func (cfg *apiConfig) getWebhookDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := cfg.ownWebhookEndpoint(w, r)
+	if !ok {
+		return
+	}
+	deliveryID, err := uuid.Parse(r.PathValue("deliveryID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid delivery ID", err)
+		return
+	}
+	delivery, err := cfg.dbQueries.GetWebhookDelivery(r.Context(), database.GetWebhookDeliveryParams{
+		ID:         deliveryID,
+		EndpointID: endpoint.ID,
+	})
+	if err != nil {
+		respondWithError(w, 404, "delivery not found", err)
+		return
+	}
+	attempts, err := cfg.dbQueries.ListWebhookDeliveryAttempts(r.Context(), delivery.ID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't list delivery attempts", err)
+		return
+	}
+	resp := newWebhookDeliveryResponse(delivery)
+	resp.Payload = delivery.Payload
+	resp.AttemptLog = []webhookDeliveryAttemptResponse{}
+	for _, attempt := range attempts {
+		entry := webhookDeliveryAttemptResponse{
+			AttemptedAt: attempt.AttemptedAt,
+			Error:       attempt.Error,
+			DurationMs:  attempt.DurationMs,
+		}
+		if attempt.StatusCode.Valid {
+			statusCode := attempt.StatusCode.Int32
+			entry.StatusCode = &statusCode
+		}
+		resp.AttemptLog = append(resp.AttemptLog, entry)
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+// redeliverWebhookHandler queues a delivery to be sent again straight away
+// with a fresh set of attempts, whatever its status. The event ID stays the
+// same so receivers can tell it is a repeat.
+func (cfg *apiConfig) redeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := cfg.ownWebhookEndpoint(w, r)
+	if !ok {
+		return
+	}
+	deliveryID, err := uuid.Parse(r.PathValue("deliveryID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid delivery ID", err)
+		return
+	}
+	delivery, err := cfg.dbQueries.RedeliverWebhookDelivery(r.Context(), database.RedeliverWebhookDeliveryParams{
+		ID:         deliveryID,
+		EndpointID: endpoint.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "delivery not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "couldn't queue redelivery", err)
+		return
+	}
+	respondWithJSON(w, http.StatusAccepted, newWebhookDeliveryResponse(delivery))
+}
+
+// ownWebhookEndpoint authenticates the request and loads the endpoint in its
+// path, which must belong to the caller. It writes an error response and
+// returns false otherwise.
+func (cfg *apiConfig) ownWebhookEndpoint(w http.ResponseWriter, r *http.Request) (database.WebhookEndpoint, bool) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return database.WebhookEndpoint{}, false
+	}
+	userID, err := auth.ValidateJWT(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return database.WebhookEndpoint{}, false
+	}
+	endpointID, err := uuid.Parse(r.PathValue("endpointID"))
+	if err != nil {
+		respondWithError(w, 400, "invalid endpoint ID", err)
+		return database.WebhookEndpoint{}, false
+	}
+	endpoint, err := cfg.dbQueries.GetWebhookEndpoint(r.Context(), database.GetWebhookEndpointParams{
+		ID:     endpointID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, 404, "webhook endpoint not found", err)
+		return database.WebhookEndpoint{}, false
+	}
+	return endpoint, true
+}
+
+func newWebhookEndpointResponse(endpoint database.WebhookEndpoint) webhookEndpointResponse {
+	return webhookEndpointResponse{
+		ID:          endpoint.ID,
+		CreatedAt:   endpoint.CreatedAt,
+		URL:         endpoint.Url,
+		Events:      endpoint.EventTypes,
+		Description: endpoint.Description,
+	}
+}
+
+func newWebhookDeliveryResponse(delivery database.WebhookDelivery) webhookDeliveryResponse {
+	resp := webhookDeliveryResponse{
+		ID:        delivery.ID,
+		CreatedAt: delivery.CreatedAt,
+		EventID:   delivery.EventID,
+		EventType: delivery.EventType,
+		Status:    delivery.Status,
+		Attempts:  delivery.Attempts,
+		LastError: delivery.LastError,
+	}
+	if delivery.Status == webhookStatusPending {
+		nextAttemptAt := delivery.NextAttemptAt
+		resp.NextAttemptAt = &nextAttemptAt
+	}
+	if delivery.DeliveredAt.Valid {
+		deliveredAt := delivery.DeliveredAt.Time
+		resp.DeliveredAt = &deliveredAt
+	}
+	if delivery.LastStatusCode.Valid {
+		statusCode := delivery.LastStatusCode.Int32
+		resp.LastStatusCode = &statusCode
+	}
+	return resp
+}
@@ -3,47 +3,168 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/events"
+	"github.com/Throne-of-Doom/chirpy/internal/subscription"
 	"github.com/google/uuid"
+	"io"
 	"net/http"
+	"time"
 )
 
+const (
+	webhookSourcePolka   = "polka"
+	webhookTolerance     = 5 * time.Minute
+	maxWebhookBodyLength = 64 << 10
+)
+
+// Data is the payload of a Polka event. Plan and the period bounds are
+// optional; upgrades without a period_end never lapse on their own.
 type Data struct {
-	UserID uuid.UUID `json:"user_id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	Plan        string     `json:"plan"`
+	PeriodStart *time.Time `json:"period_start"`
+	PeriodEnd   *time.Time `json:"period_end"`
 }
 
+// eventPolka is a Polka webhook delivery. Retries of a delivery carry the
+// same ID.
 type eventPolka struct {
+	ID    string `json:"id"`
 	Event string `json:"event"`
 	Data  Data   `json:"data"`
 }
 
+// upgradeChirpyHandler receives Polka webhooks. When POLKA_WEBHOOK_SECRETS is
+// set, deliveries must be signed with one of the secrets and carry an event
+// ID; otherwise the older POLKA_KEY API key is accepted. Events with an ID are
+// recorded in webhook_events in the same transaction that applies them, so a
+// retried delivery is acknowledged without being applied again. Subscription
+// events update the user's subscription and its history; other events are
+// acknowledged and ignored.
 func (cfg *apiConfig) upgradeChirpyHandler(w http.ResponseWriter, r *http.Request) {
-	APIKey, err := auth.GetAPIKey(r.Header)
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodyLength))
 	if err != nil {
-		respondWithError(w, 401, "invalid authorization header", err)
+		respondWithError(w, 400, "unable to read body", err)
 		return
 	}
-	if APIKey != cfg.POLKA_KEY {
-		respondWithError(w, http.StatusUnauthorized, "unauthorized", nil)
-		return
-
+	if len(cfg.polkaSecrets) > 0 {
+		err = auth.VerifyWebhook(r.Header, body, cfg.polkaSecrets, time.Now(), webhookTolerance)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "invalid signature", err)
+			return
+		}
+	} else {
+		APIKey, err := auth.GetAPIKey(r.Header)
+		if err != nil {
+			respondWithError(w, 401, "invalid authorization header", err)
+			return
+		}
+		if !auth.KeysEqual(APIKey, cfg.POLKA_KEY) {
+			respondWithError(w, http.StatusUnauthorized, "unauthorized", nil)
+			return
+		}
 	}
-	decoder := json.NewDecoder(r.Body)
 	var params eventPolka
-	err = decoder.Decode(&params)
+	err = json.Unmarshal(body, &params)
 	if err != nil {
 		respondWithError(w, 400, "unable to decode JSON", err)
 		return
 	}
-	if params.Event != "user.upgraded" {
-		w.WriteHeader(204)
+	// Signed deliveries can be replayed within the timestamp tolerance, so
+	// without an ID to deduplicate on they are refused.
+	if len(cfg.polkaSecrets) > 0 && params.ID == "" {
+		respondWithError(w, 400, "missing event id", nil)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	if params.ID != "" {
+		recorded, err := qtx.RecordWebhookEvent(r.Context(), database.RecordWebhookEventParams{
+			Source:  webhookSourcePolka,
+			EventID: params.ID,
+			Event:   params.Event,
+		})
+		if err != nil {
+			respondWithError(w, 500, "error recording event", err)
+			return
+		}
+		if recorded == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	event := subscription.Event(params.Event)
+	if !subscription.Known(event) {
+		err = tx.Commit()
+		if err != nil {
+			respondWithError(w, 500, "error recording event", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Lock the user row so concurrent deliveries for the same user apply one
+	// after the other, even before the user has a subscription row to lock.
+	dbUser, err := qtx.GetUserByIDForUpdate(r.Context(), params.Data.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "user not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "error updating user", err)
+		return
+	}
+	dbSub, err := qtx.GetSubscriptionByUserIDForUpdate(r.Context(), params.Data.UserID)
+	exists := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 500, "error updating subscription", err)
 		return
 	}
-	err = cfg.dbQueries.UpgradeUser(r.Context(), params.Data.UserID)
-	if err == sql.ErrNoRows {
-		respondWithError(w, 404, "unable to upgrade user", err)
+	change := subscription.Change{Plan: params.Data.Plan}
+	if params.Data.PeriodStart != nil {
+		change.PeriodStart = params.Data.PeriodStart.UTC()
+	}
+	if params.Data.PeriodEnd != nil {
+		change.PeriodEnd = params.Data.PeriodEnd.UTC()
+	}
+	now := time.Now().UTC()
+	wasEntitled := exists && subscription.Entitled(subscriptionState(dbSub), now)
+	state, err := subscription.Apply(subscriptionState(dbSub), exists, event, change, now, cfg.subscriptionGrace)
+	// Without a subscription there is nothing to downgrade, cancel or mark
+	// past due, so the event is only recorded.
+	if err != nil && !errors.Is(err, subscription.ErrNoSubscription) {
+		respondWithError(w, 400, "invalid subscription event", err)
 		return
 	}
+	if err == nil {
+		_, err = saveSubscription(r.Context(), qtx, params.Data.UserID, state, event, params.ID)
+		if err != nil {
+			respondWithError(w, 500, "error updating subscription", err)
+			return
+		}
+		if !wasEntitled && subscription.Entitled(state, now) {
+			err = events.Append(r.Context(), qtx, events.UserUpgraded, dbUser.ID, userUpgradedEvent{
+				UserID: dbUser.ID,
+				Plan:   state.Plan,
+			})
+			if err != nil {
+				respondWithError(w, 500, "error updating subscription", err)
+				return
+			}
+		}
+	}
+	err = tx.Commit()
 	if err != nil {
 		respondWithError(w, 500, "error updating user", err)
 		return
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/ratelimit"
+	"github.com/Throne-of-Doom/chirpy/internal/websocket"
+	"github.com/google/uuid"
+)
+
+// websocketHandler upgrades to a WebSocket connection for clients that want
+// events, replies and chirp posting on one connection. Like the streams, it
+// takes the access token from the Authorization header or the access_token
+// query parameter, since browsers cannot set headers on a WebSocket.
+func (cfg *apiConfig) websocketHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := streamToken(r)
+	if err != nil {
+		respondWithError(w, 401, "invalid or missing token", err)
+		return
+	}
+	userID, expiresAt, err := auth.ValidateJWTWithExpiry(token, cfg.SECRET)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired token", err)
+		return
+	}
+	if !cfg.checkNotSuspended(w, r, userID) {
+		return
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 404, "user not found", err)
+		return
+	}
+	mutes, err := cfg.loadKeywordMutes(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "couldn't load muted keywords", err)
+		return
+	}
+
+	// Counting the connection before it is hijacked, while the server still
+	// tracks it, means shutdown cannot start waiting for sockets before it
+	// is counted.
+	cfg.sockets.Add(1)
+	defer cfg.sockets.Done()
+	conn, err := websocket.Accept(w, r)
+	if errors.Is(err, websocket.ErrBadHandshake) {
+		respondWithError(w, 400, "expected a websocket upgrade", err)
+		return
+	}
+	if err != nil {
+		log.Printf("couldn't accept websocket: %v", err)
+		return
+	}
+
+	session := &socketSession{
+		cfg:     cfg,
+		conn:    conn,
+		r:       r,
+		user:    user,
+		mutes:   mutes,
+		out:     make(chan socketFrame, socketSendBuffer),
+		closing: make(chan socketClose, 1),
+		reauth:  make(chan time.Time, 1),
+		done:    make(chan struct{}),
+		token:   token,
+		topics:  map[string][]string{},
+		bucket:  ratelimit.NewBucket(socketMessageLimit, time.Now().UTC()),
+		typedAt: map[uuid.UUID]time.Time{},
+	}
+	session.run(expiresAt)
+}
@@ -45,23 +45,30 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	userID, _, err := ValidateJWTWithExpiry(tokenString, tokenSecret)
+	return userID, err
+}
+
+// ValidateJWTWithExpiry is ValidateJWT for callers that hold on to a token,
+// and also returns when it expires.
+func ValidateJWTWithExpiry(tokenString, tokenSecret string) (uuid.UUID, time.Time, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(tokenSecret), nil
 	})
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, time.Time{}, err
 	}
 
 	claims, ok := token.Claims.(*jwt.RegisteredClaims)
-	if !ok || !token.Valid {
-		return uuid.Nil, fmt.Errorf("invalid token")
+	if !ok || !token.Valid || claims.ExpiresAt == nil {
+		return uuid.Nil, time.Time{}, fmt.Errorf("invalid token")
 	}
 	userID, err := uuid.Parse(claims.Subject)
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, time.Time{}, err
 	}
 
-	return userID, nil
+	return userID, claims.ExpiresAt.Time, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -31,6 +31,25 @@ func TestMakeAndValidateJWT(t *testing.T) {
 	}
 }
 
+func TestValidateJWTWithExpiry(t *testing.T) {
+	userID := uuid.New()
+	token, err := MakeJWT(userID, "super-secret", time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+
+	gotID, expiresAt, err := ValidateJWTWithExpiry(token, "super-secret")
+	if err != nil {
+		t.Fatalf("ValidateJWTWithExpiry returned error: %v", err)
+	}
+	if gotID != userID {
+		t.Errorf("got userID %v, want %v", gotID, userID)
+	}
+	if until := time.Until(expiresAt); until <= 0 || until > time.Hour {
+		t.Errorf("token expires in %v, want within the hour", until)
+	}
+}
+
 func TestExpiredJWT(t *testing.T) {
 	userID := uuid.New()
 	tokenSecret := "super-secret"
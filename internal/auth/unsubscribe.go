@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// unsubscribeMACLength is how many bytes of the HMAC are kept in a token.
+// 128 bits is plenty against guessing and keeps links short.
+const unsubscribeMACLength = 16
+
+var ErrUnsubscribeTokenInvalid = errors.New("invalid unsubscribe token")
+
+// MakeUnsubscribeToken returns a token for the one-click unsubscribe links
+// in emails to userID. It holds the user ID and an HMAC of it, so it works
+// without signing in and without being stored, and it does not expire, since
+// old emails should keep working.
+func MakeUnsubscribeToken(userID uuid.UUID, secret string) string {
+	token := append(userID[:], unsubscribeMAC(userID, secret)...)
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+// ValidateUnsubscribeToken returns the user an unsubscribe token was made
+// for.
+func ValidateUnsubscribeToken(token, secret string) (uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != len(uuid.UUID{})+unsubscribeMACLength {
+		return uuid.Nil, ErrUnsubscribeTokenInvalid
+	}
+	userID, err := uuid.FromBytes(raw[:len(uuid.UUID{})])
+	if err != nil {
+		return uuid.Nil, ErrUnsubscribeTokenInvalid
+	}
+	if !hmac.Equal(raw[len(uuid.UUID{}):], unsubscribeMAC(userID, secret)) {
+		return uuid.Nil, ErrUnsubscribeTokenInvalid
+	}
+	return userID, nil
+}
+
+// unsubscribeMAC is keyed for this purpose only, so the same secret can sign
+// access tokens without one kind of token standing in for the other.
+func unsubscribeMAC(userID uuid.UUID, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("chirpy-unsubscribe:"))
+	mac.Write(userID[:])
+	return mac.Sum(nil)[:unsubscribeMACLength]
+}
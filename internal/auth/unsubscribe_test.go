@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestUnsubscribeToken(t *testing.T) {
+	userID := uuid.New()
+	token := MakeUnsubscribeToken(userID, "super-secret")
+
+	got, err := ValidateUnsubscribeToken(token, "super-secret")
+	if err != nil {
+		t.Fatalf("ValidateUnsubscribeToken returned error: %v", err)
+	}
+	if got != userID {
+		t.Errorf("got userID %v, want %v", got, userID)
+	}
+
+	if _, err := ValidateUnsubscribeToken(token, "other-secret"); !errors.Is(err, ErrUnsubscribeTokenInvalid) {
+		t.Errorf("token checked with another secret: err = %v", err)
+	}
+	// Swapping in another user's ID must not keep the token valid.
+	other := MakeUnsubscribeToken(uuid.New(), "super-secret")
+	forged := other[:22] + token[22:]
+	if _, err := ValidateUnsubscribeToken(forged, "super-secret"); !errors.Is(err, ErrUnsubscribeTokenInvalid) {
+		t.Errorf("forged token: err = %v", err)
+	}
+	if _, err := ValidateUnsubscribeToken("not-a-token", "super-secret"); !errors.Is(err, ErrUnsubscribeTokenInvalid) {
+		t.Errorf("garbage token: err = %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func signedHeaders(signature string, timestamp int64) http.Header {
+	headers := http.Header{}
+	headers.Set(SignatureHeader, signature)
+	headers.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	return headers
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	body := []byte(`{"id":"evt_1","event":"user.upgraded"}`)
+	now := time.Unix(1700000000, 0)
+	tolerance := 5 * time.Minute
+	secrets := []string{"new-secret", "old-secret"}
+
+	tests := []struct {
+		name    string
+		headers http.Header
+		body    []byte
+		want    error
+	}{
+		{"current secret", signedHeaders("v1="+SignWebhook("new-secret", now.Unix(), body), now.Unix()), body, nil},
+		{"rotated out secret still listed", signedHeaders("v1="+SignWebhook("old-secret", now.Unix(), body), now.Unix()), body, nil},
+		{"one of several signatures", signedHeaders("v1=00, v1="+SignWebhook("new-secret", now.Unix(), body), now.Unix()), body, nil},
+		{"unknown secret", signedHeaders("v1="+SignWebhook("other", now.Unix(), body), now.Unix()), body, ErrSignatureInvalid},
+		{"tampered body", signedHeaders("v1="+SignWebhook("new-secret", now.Unix(), body), now.Unix()), []byte(`{}`), ErrSignatureInvalid},
+		{"missing version prefix", signedHeaders(SignWebhook("new-secret", now.Unix(), body), now.Unix()), body, ErrSignatureInvalid},
+		{"too old", signedHeaders("v1="+SignWebhook("new-secret", now.Unix()-600, body), now.Unix()-600), body, ErrSignatureExpired},
+		{"too far ahead", signedHeaders("v1="+SignWebhook("new-secret", now.Unix()+600, body), now.Unix()+600), body, ErrSignatureExpired},
+		{"missing headers", http.Header{}, body, ErrSignatureMissing},
+	}
+	for _, tt := range tests {
+		err := VerifyWebhook(tt.headers, tt.body, secrets, now, tolerance)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: VerifyWebhook() = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestVerifyWebhookRejectsReplayedTimestamp(t *testing.T) {
+	body := []byte(`{}`)
+	old := time.Unix(1700000000, 0)
+	signature := "v1=" + SignWebhook("secret", old.Unix(), body)
+	now := old.Add(time.Hour)
+
+	err := VerifyWebhook(signedHeaders(signature, now.Unix()), body, []string{"secret"}, now, 5*time.Minute)
+	if !errors.Is(err, ErrSignatureInvalid) {
+		t.Fatalf("signature for an old timestamp with a new one should be invalid, got %v", err)
+	}
+}
+
+func TestKeysEqual(t *testing.T) {
+	if !KeysEqual("abc", "abc") {
+		t.Error("equal keys should match")
+	}
+	if KeysEqual("abd", "abc") || KeysEqual("ab", "abc") {
+		t.Error("different keys should not match")
+	}
+	if KeysEqual("", "") {
+		t.Error("an empty key should never match")
+	}
+}
@@ -7,29 +7,38 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, hidden_at, content_warning, sensitive, reply_to_id)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, deleted_at, hidden_at, visibility, content_warning, sensitive, edited_at, reply_to_id
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	HiddenAt       sql.NullTime
+	ContentWarning string
+	Sensitive      bool
+	ReplyToID      uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID, arg.HiddenAt, arg.ContentWarning, arg.Sensitive, arg.ReplyToID)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,6 +46,51 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.DeletedAt,
+		&i.HiddenAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.EditedAt,
+		&i.ReplyToID,
+	)
+	return i, err
+}
+
+const editChirp = `-- name: EditChirp :one
+UPDATE chirps
+SET
+    body = $1::text,
+    updated_at = NOW(),
+    edited_at = NOW(),
+    hidden_at = CASE WHEN $2::boolean THEN COALESCE(hidden_at, NOW()) ELSE hidden_at END
+WHERE id = $3::uuid AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, deleted_at, hidden_at, visibility, content_warning, sensitive, edited_at, reply_to_id
+`
+
+type EditChirpParams struct {
+	Body string
+	Hold bool
+	ID   uuid.UUID
+}
+
+// hold hides the chirp for review, as the spam check does for new chirps.
+func (q *Queries) EditChirp(ctx context.Context, arg EditChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, editChirp, arg.Body, arg.Hold, arg.ID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.DeletedAt,
+		&i.HiddenAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.EditedAt,
+		&i.ReplyToID,
 	)
 	return i, err
 }
@@ -12,11 +12,54 @@ import (
 )
 
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE id = $1
+SELECT id, created_at, updated_at, body, user_id, deleted_at, hidden_at, visibility, content_warning, sensitive, edited_at, reply_to_id FROM chirps
+WHERE id = $1 AND deleted_at IS NULL AND hidden_at IS NULL AND NOT is_user_suspended(user_id)
+    AND chirp_visible(visibility, user_id, $2::uuid, FALSE)
 `
 
-func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, getChirp, id)
+type GetChirpParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.UUID
+}
+
+func (q *Queries) GetChirp(ctx context.Context, arg GetChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirp, arg.ID, arg.ViewerID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.DeletedAt,
+		&i.HiddenAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.EditedAt,
+		&i.ReplyToID,
+	)
+	return i, err
+}
+
+const getChirpEffectiveVisibility = `-- name: GetChirpEffectiveVisibility :one
+SELECT effective_visibility(visibility, user_id)::text AS visibility FROM chirps
+WHERE id = $1 AND deleted_at IS NULL AND hidden_at IS NULL AND NOT is_user_suspended(user_id)
+`
+
+func (q *Queries) GetChirpEffectiveVisibility(ctx context.Context, id uuid.UUID) (string, error) {
+	row := q.db.QueryRowContext(ctx, getChirpEffectiveVisibility, id)
+	var visibility string
+	err := row.Scan(&visibility)
+	return visibility, err
+}
+
+const getChirpForUpdate = `-- name: GetChirpForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, deleted_at, hidden_at, visibility, content_warning, sensitive, edited_at, reply_to_id FROM chirps WHERE id = $1 AND deleted_at IS NULL FOR UPDATE
+`
+
+func (q *Queries) GetChirpForUpdate(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpForUpdate, id)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -24,6 +67,13 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.DeletedAt,
+		&i.HiddenAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+		&i.EditedAt,
+		&i.ReplyToID,
 	)
 	return i, err
 }
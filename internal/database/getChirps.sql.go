@@ -7,14 +7,107 @@ package database
 
 import (
 	"context"
+	"time"
+
+	"github.com/google/uuid"
 )
 
-const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps ORDER BY created_at ASC
+const getChirpsAscending = `-- name: GetChirpsAscending :many
+SELECT id, created_at, updated_at, body, user_id, deleted_at, hidden_at, visibility, content_warning, sensitive, edited_at, reply_to_id FROM chirps
+WHERE deleted_at IS NULL AND hidden_at IS NULL AND NOT is_user_suspended(user_id)
+    AND chirp_visible(visibility, user_id, $1::uuid, $2::boolean)
+    AND (NOT $3::boolean OR user_id = $4::uuid)
+    AND (NOT $5::boolean OR created_at >= $6::timestamp)
+    AND (NOT $7::boolean OR created_at < $8::timestamp)
+    AND (NOT $9::boolean OR (created_at, id) > ($10::timestamp, $11::uuid))
+ORDER BY created_at, id
+LIMIT $12::int
+`
+
+type GetChirpsAscendingParams struct {
+	ViewerID   uuid.UUID
+	Discovery  bool
+	HasAuthor  bool
+	AuthorID   uuid.UUID
+	HasSince   bool
+	Since      time.Time
+	HasUntil   bool
+	Until      time.Time
+	HasCursor  bool
+	CursorTime time.Time
+	CursorID   uuid.UUID
+	RowLimit   int32
+}
+
+// A page of chirps, oldest first, starting after the cursor. The ORDER BY is
+// fixed so that even a generic plan can walk the (created_at, id) indexes
+// instead of sorting.
+func (q *Queries) GetChirpsAscending(ctx context.Context, arg GetChirpsAscendingParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsAscending, arg.ViewerID, arg.Discovery, arg.HasAuthor, arg.AuthorID, arg.HasSince, arg.Since, arg.HasUntil, arg.Until, arg.HasCursor, arg.CursorTime, arg.CursorID, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.DeletedAt,
+			&i.HiddenAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.EditedAt,
+			&i.ReplyToID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsDescending = `-- name: GetChirpsDescending :many
+SELECT id, created_at, updated_at, body, user_id, deleted_at, hidden_at, visibility, content_warning, sensitive, edited_at, reply_to_id FROM chirps
+WHERE deleted_at IS NULL AND hidden_at IS NULL AND NOT is_user_suspended(user_id)
+    AND chirp_visible(visibility, user_id, $1::uuid, $2::boolean)
+    AND (NOT $3::boolean OR user_id = $4::uuid)
+    AND (NOT $5::boolean OR created_at >= $6::timestamp)
+    AND (NOT $7::boolean OR created_at < $8::timestamp)
+    AND (NOT $9::boolean OR (created_at, id) < ($10::timestamp, $11::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $12::int
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+type GetChirpsDescendingParams struct {
+	ViewerID   uuid.UUID
+	Discovery  bool
+	HasAuthor  bool
+	AuthorID   uuid.UUID
+	HasSince   bool
+	Since      time.Time
+	HasUntil   bool
+	Until      time.Time
+	HasCursor  bool
+	CursorTime time.Time
+	CursorID   uuid.UUID
+	RowLimit   int32
+}
+
+// Like GetChirpsAscending, newest first.
+func (q *Queries) GetChirpsDescending(ctx context.Context, arg GetChirpsDescendingParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsDescending, arg.ViewerID, arg.Discovery, arg.HasAuthor, arg.AuthorID, arg.HasSince, arg.Since, arg.HasUntil, arg.Until, arg.HasCursor, arg.CursorTime, arg.CursorID, arg.RowLimit)
 	if err != nil {
 		return nil, err
 	}
@@ -28,6 +121,13 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.DeletedAt,
+			&i.HiddenAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
+			&i.EditedAt,
+			&i.ReplyToID,
 		); err != nil {
 			return nil, err
 		}
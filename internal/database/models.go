@@ -6,17 +6,191 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type Chirp struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Body           string
+	UserID         uuid.UUID
+	DeletedAt      sql.NullTime
+	HiddenAt       sql.NullTime
+	Visibility     string
+	ContentWarning string
+	Sensitive      bool
+	EditedAt       sql.NullTime
+	ReplyToID      uuid.NullUUID
+}
+
+type ChirpFlag struct {
+	ChirpID   uuid.UUID
+	RuleID    string
+	Term      string
+	CreatedAt time.Time
+}
+
+type ChirpLike struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpSearch struct {
+	ChirpID      uuid.UUID
+	SearchVector interface{}
+}
+
+type DigestPreference struct {
+	UserID     uuid.UUID
+	Frequency  string
+	LastSentAt sql.NullTime
+	UpdatedAt  time.Time
+}
+
+type Draft struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	UserID          uuid.UUID
+	Body            string
+	PublishAt       sql.NullTime
+	ContentWarning  string
+	Sensitive       bool
+	PublishAttempts int32
+	LastError       string
+	NextAttemptAt   sql.NullTime
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type MediaObject struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UserID       uuid.UUID
+	ChirpID      uuid.NullUUID
+	ContentType  string
+	SizeBytes    int64
+	Width        int32
+	Height       int32
+	StorageKey   string
+	ThumbnailKey string
+}
+
+type ModerationAuditLog struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	ActorID      uuid.NullUUID
+	Action       string
+	ReportID     uuid.NullUUID
+	ChirpID      uuid.NullUUID
+	TargetUserID uuid.NullUUID
+	Details      string
+}
+
+type ModerationRule struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Body      string
+	Term      string
+	Action    string
+}
+
+type MutedKeyword struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	Keyword    string
+	Normalized string
+	ExpiresAt  sql.NullTime
+}
+
+type Notification struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Type      string
+	GroupKey  string
+	ChirpID   uuid.NullUUID
+	ReadAt    sql.NullTime
+}
+
+type NotificationActor struct {
+	NotificationID uuid.UUID
+	ActorID        uuid.UUID
+	CreatedAt      time.Time
+}
+
+type NotificationEvent struct {
+	EventID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type NotificationPreference struct {
+	UserID    uuid.UUID
+	Type      string
+	Enabled   bool
+	UpdatedAt time.Time
+}
+
+type OutboxCheckpoint struct {
+	Consumer  string
+	Position  int64
+	UpdatedAt time.Time
+}
+
+type OutboxEvent struct {
+	Seq         int64
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	EventType   string
+	UserID      uuid.UUID
+	Payload     json.RawMessage
+	Position    sql.NullInt64
+	PublishedAt sql.NullTime
+}
+
+type PinnedChirp struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type Poll struct {
+	ID        uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+	ClosesAt  time.Time
+}
+
+type PollOption struct {
+	ID       uuid.UUID
+	PollID   uuid.UUID
+	Position int32
+	Label    string
+}
+
+type PollVote struct {
+	PollID    uuid.UUID
 	UserID    uuid.UUID
+	OptionID  uuid.UUID
+	CreatedAt time.Time
+}
+
+type RateLimitBucket struct {
+	Key       string
+	Tokens    float64
+	UpdatedAt time.Time
 }
 
 type RefreshToken struct {
@@ -28,11 +202,126 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime
 }
 
+type Report struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	ReporterID     uuid.UUID
+	ReportedUserID uuid.UUID
+	ChirpID        uuid.NullUUID
+	Reason         string
+	Details        string
+	Status         string
+	AssigneeID     uuid.NullUUID
+	Resolution     sql.NullString
+	ResolutionNote string
+	ResolvedBy     uuid.NullUUID
+	ResolvedAt     sql.NullTime
+}
+
+type SpamScore struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	ChirpID    uuid.NullUUID
+	Body       string
+	Score      float64
+	Decision   string
+	Reasons    json.RawMessage
+	Review     sql.NullString
+	ReviewedBy uuid.NullUUID
+	ReviewedAt sql.NullTime
+}
+
+type Subscription struct {
+	ID                 uuid.UUID
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	UserID             uuid.UUID
+	Plan               string
+	Status             string
+	CurrentPeriodStart time.Time
+	CurrentPeriodEnd   sql.NullTime
+	GraceUntil         sql.NullTime
+	CancelledAt        sql.NullTime
+}
+
+type SubscriptionEvent struct {
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	SubscriptionID   uuid.UUID
+	Event            string
+	Status           string
+	CurrentPeriodEnd sql.NullTime
+	WebhookEventID   sql.NullString
+}
+
 type User struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Email           string
+	HashedPassword  string
+	Handle          string
+	DisplayName     string
+	Bio             string
+	Website         string
+	AvatarID        uuid.NullUUID
+	Role            string
+	Visibility      string
+	ExpandSensitive bool
+	LastActiveAt    sql.NullTime
+}
+
+type UserSuspension struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	CreatedBy uuid.NullUUID
+	Reason    string
+	ExpiresAt sql.NullTime
+	LiftedAt  sql.NullTime
+}
+
+type WebhookDelivery struct {
 	ID             uuid.UUID
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	EndpointID     uuid.UUID
+	EventID        uuid.UUID
+	EventType      string
+	Payload        json.RawMessage
+	Status         string
+	Attempts       int32
+	NextAttemptAt  time.Time
+	DeliveredAt    sql.NullTime
+	LastStatusCode sql.NullInt32
+	LastError      string
+}
+
+type WebhookDeliveryAttempt struct {
+	ID          uuid.UUID
+	DeliveryID  uuid.UUID
+	AttemptedAt time.Time
+	StatusCode  sql.NullInt32
+	Error       string
+	DurationMs  int32
+}
+
+type WebhookEndpoint struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Url         string
+	Secret      string
+	EventTypes  []string
+	Description string
+}
+
+type WebhookEvent struct {
+	Source     string
+	EventID    string
+	Event      string
+	ReceivedAt time.Time
 }
@@ -45,7 +45,7 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 }
 
 const getUserFromRefreshToken = `-- name: GetUserFromRefreshToken :one
-SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red FROM users JOIN refresh_tokens ON users.id = refresh_tokens.user_id
+SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.handle, users.display_name, users.bio, users.website, users.avatar_id, users.role, users.visibility, users.expand_sensitive, users.last_active_at FROM users JOIN refresh_tokens ON users.id = refresh_tokens.user_id
 WHERE refresh_tokens.token = $1 AND revoked_at IS NULL AND expires_at > NOW()
 `
 
@@ -58,7 +58,15 @@ func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (Us
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
-		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Website,
+		&i.AvatarID,
+		&i.Role,
+		&i.Visibility,
+		&i.ExpandSensitive,
+		&i.LastActiveAt,
 	)
 	return i, err
 }
@@ -12,24 +12,26 @@ import (
 )
 
 const createUser = `-- name: CreateUser :one
-Insert INTO users (id, created_at, updated_at, email, hashed_password)
+Insert INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, handle, display_name, bio, website, avatar_id, role, visibility, expand_sensitive, last_active_at
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         string
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Handle)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -37,13 +39,21 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
-		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Website,
+		&i.AvatarID,
+		&i.Role,
+		&i.Visibility,
+		&i.ExpandSensitive,
+		&i.LastActiveAt,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users
+SELECT id, created_at, updated_at, email, hashed_password, handle, display_name, bio, website, avatar_id, role, visibility, expand_sensitive, last_active_at FROM users
 WHERE email = $1
 `
 
@@ -56,11 +66,83 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
-		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Website,
+		&i.AvatarID,
+		&i.Role,
+		&i.Visibility,
+		&i.ExpandSensitive,
+		&i.LastActiveAt,
 	)
 	return i, err
 }
 
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, handle, display_name, bio, website, avatar_id, role, visibility, expand_sensitive, last_active_at FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Website,
+		&i.AvatarID,
+		&i.Role,
+		&i.Visibility,
+		&i.ExpandSensitive,
+		&i.LastActiveAt,
+	)
+	return i, err
+}
+
+const getUserByIDForUpdate = `-- name: GetUserByIDForUpdate :one
+SELECT id, created_at, updated_at, email, hashed_password, handle, display_name, bio, website, avatar_id, role, visibility, expand_sensitive, last_active_at FROM users
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetUserByIDForUpdate(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByIDForUpdate, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Website,
+		&i.AvatarID,
+		&i.Role,
+		&i.Visibility,
+		&i.ExpandSensitive,
+		&i.LastActiveAt,
+	)
+	return i, err
+}
+
+const touchUserActivity = `-- name: TouchUserActivity :exec
+UPDATE users SET last_active_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) TouchUserActivity(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, touchUserActivity, id)
+	return err
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users 
 SET 
@@ -68,7 +150,7 @@ SET
     hashed_password = $3,
     updated_at = NOW()
     WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, handle, display_name, bio, website, avatar_id, role, visibility, expand_sensitive, last_active_at
 `
 
 type UpdateUserParams struct {
@@ -86,7 +168,15 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
-		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.Website,
+		&i.AvatarID,
+		&i.Role,
+		&i.Visibility,
+		&i.ExpandSensitive,
+		&i.LastActiveAt,
 	)
 	return i, err
 }
@@ -0,0 +1,108 @@
+// Package digest renders the email digests sent to users who have not been
+// on Chirpy for a while, summing up their unread notifications and popular
+// chirps from the people they follow.
+package digest
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Frequency is how often a user gets a digest.
+type Frequency string
+
+const (
+	Off    Frequency = "off"
+	Daily  Frequency = "daily"
+	Weekly Frequency = "weekly"
+)
+
+// Default is the frequency of users who have not chosen one.
+const Default = Weekly
+
+// ValidFrequency reports whether f is a frequency users can choose.
+func ValidFrequency(f Frequency) bool {
+	return f == Off || f == Daily || f == Weekly
+}
+
+// Period is how long after one digest the next is due. It is zero for Off.
+func (f Frequency) Period() time.Duration {
+	switch f {
+	case Daily:
+		return 24 * time.Hour
+	case Weekly:
+		return 7 * 24 * time.Hour
+	}
+	return 0
+}
+
+// Chirp is a chirp shown in a digest.
+type Chirp struct {
+	Handle string
+	Body   string
+	Likes  int64
+}
+
+// Digest is everything that goes into one email. Notifications are their
+// summaries, newest first; UnreadCount may be more than are listed.
+type Digest struct {
+	Handle         string
+	Frequency      Frequency
+	Notifications  []string
+	UnreadCount    int64
+	Chirps         []Chirp
+	AppURL         string
+	UnsubscribeURL string
+}
+
+// Empty reports whether there is nothing worth sending.
+func (d Digest) Empty() bool {
+	return len(d.Notifications) == 0 && len(d.Chirps) == 0
+}
+
+// Subject is the email's subject line.
+func (d Digest) Subject() string {
+	if d.UnreadCount > 0 {
+		noun := "notifications"
+		if d.UnreadCount == 1 {
+			noun = "notification"
+		}
+		return fmt.Sprintf("You have %d unread %s on Chirpy", d.UnreadCount, noun)
+	}
+	return fmt.Sprintf("Your %s Chirpy digest", d.Frequency)
+}
+
+// PeriodLabel describes the time a digest covers.
+func (d Digest) PeriodLabel() string {
+	if d.Frequency == Daily {
+		return "today"
+	}
+	return "this week"
+}
+
+//go:embed templates
+var templateFS embed.FS
+
+var (
+	textTemplate = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/digest.txt"))
+	htmlTemplate = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/digest.html"))
+)
+
+// Render returns the plain-text and HTML bodies of d.
+func Render(d Digest) (text, html string, err error) {
+	var textBuf, htmlBuf bytes.Buffer
+	err = textTemplate.Execute(&textBuf, d)
+	if err != nil {
+		return "", "", err
+	}
+	err = htmlTemplate.Execute(&htmlBuf, d)
+	if err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(textBuf.String()) + "\n", htmlBuf.String(), nil
+}
@@ -0,0 +1,75 @@
+package digest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	d := Digest{
+		Handle:        "ann",
+		Frequency:     Daily,
+		Notifications: []string{"@bob mentioned you", "@cat and 2 others liked your chirp"},
+		UnreadCount:   5,
+		Chirps: []Chirp{
+			{Handle: "bob", Body: "<script>alert(1)</script>", Likes: 1},
+		},
+		AppURL:         "https://chirpy.example/app/",
+		UnsubscribeURL: "https://chirpy.example/api/digest/unsubscribe?token=abc",
+	}
+	text, html, err := Render(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"Hi @ann,",
+		"on Chirpy today.",
+		"You have 5 unread notifications:",
+		"  - @cat and 2 others liked your chirp",
+		"  @bob: <script>alert(1)</script>",
+		"(1 like)",
+		"Unsubscribe: https://chirpy.example/api/digest/unsubscribe?token=abc",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text body is missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(html, "<script>") {
+		t.Error("chirp bodies must be escaped in the HTML body")
+	}
+	for _, want := range []string{
+		"&lt;script&gt;",
+		`<a href="https://chirpy.example/api/digest/unsubscribe?token=abc">Unsubscribe</a>`,
+		"<li>@bob mentioned you</li>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML body is missing %q:\n%s", want, html)
+		}
+	}
+}
+
+func TestSubject(t *testing.T) {
+	tests := []struct {
+		d    Digest
+		want string
+	}{
+		{Digest{Frequency: Weekly, UnreadCount: 1}, "You have 1 unread notification on Chirpy"},
+		{Digest{Frequency: Weekly, UnreadCount: 3}, "You have 3 unread notifications on Chirpy"},
+		{Digest{Frequency: Daily}, "Your daily Chirpy digest"},
+	}
+	for _, tt := range tests {
+		if got := tt.d.Subject(); got != tt.want {
+			t.Errorf("Subject() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestFrequency(t *testing.T) {
+	if !ValidFrequency(Default) || ValidFrequency("hourly") {
+		t.Error("ValidFrequency accepts the wrong values")
+	}
+	if Off.Period() != 0 || Weekly.Period() != 7*Daily.Period() {
+		t.Error("Period returns the wrong durations")
+	}
+}
@@ -0,0 +1,121 @@
+// Package entitlements maps subscription plans to what their users may do:
+// how long their chirps can be, how much media they can attach, how many
+// chirps they can pin, how long they can edit a chirp for and how fast they
+// can post.
+package entitlements
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Throne-of-Doom/chirpy/internal/ratelimit"
+)
+
+// Plans. Free is everyone without an active subscription.
+const (
+	PlanFree = "free"
+	PlanRed  = "chirpy_red"
+)
+
+// Rate limit classes, the keys of Entitlements.RateLimits.
+const (
+	RateLimitSignup = "signup"
+	RateLimitLogin  = "login"
+	RateLimitPost   = "post"
+)
+
+// Features a plan may include.
+const (
+	FeatureEditChirps   = "edit_chirps"
+	FeatureLongChirps   = "long_chirps"
+	FeatureLargeUploads = "large_uploads"
+	FeatureExtraMedia   = "extra_media"
+	FeatureExtraPins    = "extra_pins"
+	FeatureHigherLimits = "higher_rate_limits"
+)
+
+// Entitlements are the limits that apply to one plan. A zero EditWindow means
+// chirps cannot be edited.
+type Entitlements struct {
+	Plan             string
+	MaxChirpLength   int
+	MaxMediaPerChirp int
+	MaxUploadBytes   int64
+	MaxPinnedChirps  int
+	EditWindow       time.Duration
+	RateLimits       map[string]ratelimit.Limit
+	Features         []string
+}
+
+// Free is the plan for users without a subscription.
+var Free = Entitlements{
+	Plan:             PlanFree,
+	MaxChirpLength:   140,
+	MaxMediaPerChirp: 4,
+	MaxUploadBytes:   2 << 20,
+	MaxPinnedChirps:  3,
+	RateLimits: map[string]ratelimit.Limit{
+		RateLimitSignup: {Burst: 5, Period: time.Hour},
+		RateLimitLogin:  {Burst: 10, Period: time.Minute},
+		RateLimitPost:   {Burst: 10, Period: time.Minute},
+	},
+	Features: []string{},
+}
+
+// Red is the Chirpy Red plan.
+var Red = Entitlements{
+	Plan:             PlanRed,
+	MaxChirpLength:   280,
+	MaxMediaPerChirp: 8,
+	MaxUploadBytes:   10 << 20,
+	MaxPinnedChirps:  10,
+	EditWindow:       time.Hour,
+	RateLimits: map[string]ratelimit.Limit{
+		RateLimitSignup: {Burst: 5, Period: time.Hour},
+		RateLimitLogin:  {Burst: 10, Period: time.Minute},
+		RateLimitPost:   {Burst: 30, Period: time.Minute},
+	},
+	Features: []string{
+		FeatureEditChirps,
+		FeatureLongChirps,
+		FeatureLargeUploads,
+		FeatureExtraMedia,
+		FeatureExtraPins,
+		FeatureHigherLimits,
+	},
+}
+
+var plans = map[string]Entitlements{
+	PlanFree: Free,
+	PlanRed:  Red,
+}
+
+// Resolve returns the entitlements of a user. subscribed is whether they
+// currently have Chirpy Red and plan is their subscription's plan. Paid plans
+// without their own entry get Red's entitlements, so a new plan name from the
+// billing provider never leaves a subscriber with nothing.
+func Resolve(subscribed bool, plan string) Entitlements {
+	if !subscribed {
+		return Free
+	}
+	if e, ok := plans[plan]; ok && plan != PlanFree {
+		return e
+	}
+	return Red
+}
+
+// CheckChirpLength returns an error if body is longer than the plan allows.
+// Length is counted in characters, not bytes.
+func (e Entitlements) CheckChirpLength(body string) error {
+	if utf8.RuneCountInString(body) > e.MaxChirpLength {
+		return fmt.Errorf("Chirp is too long, Limit %d Characters", e.MaxChirpLength)
+	}
+	return nil
+}
+
+// CanEdit reports whether a chirp posted at createdAt can still be edited at
+// now.
+func (e Entitlements) CanEdit(createdAt, now time.Time) bool {
+	return e.EditWindow > 0 && now.Before(createdAt.Add(e.EditWindow))
+}
@@ -0,0 +1,69 @@
+package entitlements
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		subscribed bool
+		plan       string
+		want       string
+	}{
+		{false, "", PlanFree},
+		{false, PlanRed, PlanFree},
+		{true, PlanRed, PlanRed},
+		{true, "chirpy_red_yearly", PlanRed},
+		{true, PlanFree, PlanRed},
+	}
+	for _, tt := range tests {
+		if got := Resolve(tt.subscribed, tt.plan).Plan; got != tt.want {
+			t.Errorf("Resolve(%v, %q) = %s, want %s", tt.subscribed, tt.plan, got, tt.want)
+		}
+	}
+}
+
+func TestCheckChirpLength(t *testing.T) {
+	body := strings.Repeat("a", 200)
+	if err := Free.CheckChirpLength(body); err == nil {
+		t.Error("Free should reject a 200 character chirp")
+	}
+	if err := Red.CheckChirpLength(body); err != nil {
+		t.Errorf("Red.CheckChirpLength() = %v", err)
+	}
+	if err := Free.CheckChirpLength(strings.Repeat("a", 140)); err != nil {
+		t.Errorf("Free should allow exactly 140 characters: %v", err)
+	}
+	// 140 characters, but 420 bytes.
+	if err := Free.CheckChirpLength(strings.Repeat("日", 140)); err != nil {
+		t.Errorf("Free should count characters, not bytes: %v", err)
+	}
+	if err := Free.CheckChirpLength(strings.Repeat("é", 141)); err == nil {
+		t.Error("Free should reject 141 non-ASCII characters")
+	}
+}
+
+func TestCanEdit(t *testing.T) {
+	posted := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	if Free.CanEdit(posted, posted) {
+		t.Error("Free should not be able to edit")
+	}
+	if !Red.CanEdit(posted, posted.Add(Red.EditWindow-time.Second)) {
+		t.Error("Red should be able to edit within the window")
+	}
+	if Red.CanEdit(posted, posted.Add(Red.EditWindow)) {
+		t.Error("Red should not be able to edit once the window has passed")
+	}
+}
+
+func TestPlansHaveEveryRateLimit(t *testing.T) {
+	for name, e := range plans {
+		for _, class := range []string{RateLimitSignup, RateLimitLogin, RateLimitPost} {
+			if _, ok := e.RateLimits[class]; !ok {
+				t.Errorf("plan %s has no %s rate limit", name, class)
+			}
+		}
+	}
+}
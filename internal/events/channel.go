@@ -0,0 +1,49 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// ChannelBus is an in-process Bus. It only reaches consumers in the same
+// process, which is enough while every instance runs its own consumers
+// against the shared store.
+type ChannelBus struct {
+	mu     sync.Mutex
+	buffer int
+	subs   map[string]chan Event
+}
+
+// NewChannelBus returns a bus that holds up to buffer unread events for each
+// subscriber.
+func NewChannelBus(buffer int) *ChannelBus {
+	return &ChannelBus{buffer: buffer, subs: map[string]chan Event{}}
+}
+
+// Publish offers ev to every subscriber without waiting. A subscriber whose
+// buffer is full misses the event and picks it up from the store when it
+// notices the gap.
+func (b *ChannelBus) Publish(ctx context.Context, ev Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+	return nil
+}
+
+// Subscribe returns the channel events are published to for name. Calling
+// it again with the same name returns the same channel.
+func (b *ChannelBus) Subscribe(name string) <-chan Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch, ok := b.subs[name]
+	if !ok {
+		ch = make(chan Event, b.buffer)
+		b.subs[name] = ch
+	}
+	return ch
+}
@@ -0,0 +1,174 @@
+// Package events carries domain events from the handlers that cause them to
+// the consumers that react to them.
+//
+// Handlers append events to an outbox in the same transaction as the change
+// they describe, so an event exists if and only if its change committed. A
+// relay gives published events increasing positions and hands them to a Bus.
+// Consumers remember the position of the last event they handled and catch up
+// from the Store after a restart or whenever they fall behind the bus, so
+// every event reaches every consumer at least once. Consumers must tolerate
+// seeing an event more than once.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types.
+const (
+	ChirpCreated = "chirp.created"
+	ChirpDeleted = "chirp.deleted"
+	ChirpEdited  = "chirp.edited"
+	ChirpLiked   = "chirp.liked"
+	UserFollowed = "user.followed"
+	UserUpgraded = "user.upgraded"
+	// NotificationUpdated follows a notification being created or gaining
+	// an actor.
+	NotificationUpdated = "notification.updated"
+)
+
+// Event is a published domain event. Position orders events: a consumer that
+// has handled position N has been offered every event before it. ID is the
+// same every time an event is delivered, so consumers can use it to ignore
+// duplicates. UserID is the user the event concerns, such as the author of a
+// chirp or the user who was followed.
+type Event struct {
+	Position  int64
+	ID        uuid.UUID
+	Type      string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	Payload   json.RawMessage
+}
+
+// Bus passes published events to subscribers as they are published. A bus
+// may drop events; consumers recover them from the Store.
+type Bus interface {
+	Publish(ctx context.Context, ev Event) error
+	Subscribe(name string) <-chan Event
+}
+
+// Store holds published events and consumer checkpoints.
+type Store interface {
+	// Checkpoint is the position of the last event consumer handled, or 0.
+	Checkpoint(ctx context.Context, consumer string) (int64, error)
+	// SaveCheckpoint records that consumer has handled position. It never
+	// moves a checkpoint backwards.
+	SaveCheckpoint(ctx context.Context, consumer string, position int64) error
+	// EventsAfter returns up to limit events after position, in order.
+	EventsAfter(ctx context.Context, position int64, limit int) ([]Event, error)
+}
+
+// Consumer handles events. Handle is called once for each event, in order,
+// unless it fails, in which case the event is offered again later. An event
+// may also be offered again if the process stops before the checkpoint after
+// it is saved, so Handle must be idempotent.
+type Consumer struct {
+	Name   string
+	Handle func(ctx context.Context, ev Event) error
+}
+
+const catchUpBatchSize = 100
+
+// Run feeds events to c until ctx is cancelled. It takes events from bus as
+// they are published and reads any it missed from store, both at start-up
+// and every pollInterval. After a failure, live events are ignored until the
+// next poll retries from the checkpoint.
+func Run(ctx context.Context, bus Bus, store Store, c Consumer, pollInterval time.Duration) {
+	live := bus.Subscribe(c.Name)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	r := &runner{store: store, consumer: c}
+	r.catchUp(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.catchUp(ctx)
+		case ev := <-live:
+			r.deliver(ctx, ev)
+		}
+	}
+}
+
+// runner is the state of one consumer. Subscribing before the checkpoint is
+// loaded means no event can slip between the two: anything published in
+// between is either read from the store or waiting on the channel.
+type runner struct {
+	store    Store
+	consumer Consumer
+	position int64
+	loaded   bool
+	behind   bool
+}
+
+// catchUp handles every stored event after the checkpoint.
+func (r *runner) catchUp(ctx context.Context) {
+	if !r.loaded {
+		position, err := r.store.Checkpoint(ctx, r.consumer.Name)
+		if err != nil {
+			log.Printf("consumer %s: couldn't load checkpoint: %v", r.consumer.Name, err)
+			return
+		}
+		r.position = position
+		r.loaded = true
+	}
+	for {
+		batch, err := r.store.EventsAfter(ctx, r.position, catchUpBatchSize)
+		if err != nil {
+			log.Printf("consumer %s: couldn't read events: %v", r.consumer.Name, err)
+			r.behind = true
+			return
+		}
+		for _, ev := range batch {
+			err = r.handle(ctx, ev)
+			if err != nil {
+				log.Printf("consumer %s: couldn't handle event %s: %v", r.consumer.Name, ev.ID, err)
+				r.behind = true
+				return
+			}
+		}
+		if len(batch) < catchUpBatchSize {
+			r.behind = false
+			return
+		}
+	}
+}
+
+// deliver handles an event from the bus. Events already handled are skipped.
+// An event past the next position means some were missed, perhaps dropped
+// by the bus, so they are read from the store instead; positions can also
+// skip numbers, in which case the store simply has nothing in between.
+func (r *runner) deliver(ctx context.Context, ev Event) {
+	if !r.loaded || r.behind || ev.Position <= r.position {
+		return
+	}
+	if ev.Position > r.position+1 {
+		r.catchUp(ctx)
+		return
+	}
+	err := r.handle(ctx, ev)
+	if err != nil {
+		log.Printf("consumer %s: couldn't handle event %s: %v", r.consumer.Name, ev.ID, err)
+		r.behind = true
+	}
+}
+
+func (r *runner) handle(ctx context.Context, ev Event) error {
+	err := r.consumer.Handle(ctx, ev)
+	if err != nil {
+		return err
+	}
+	err = r.store.SaveCheckpoint(ctx, r.consumer.Name, ev.Position)
+	if err != nil {
+		return err
+	}
+	r.position = ev.Position
+	return nil
+}
@@ -0,0 +1,153 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type memoryStore struct {
+	events      []Event
+	checkpoints map[string]int64
+}
+
+func newMemoryStore(n int) *memoryStore {
+	s := &memoryStore{checkpoints: map[string]int64{}}
+	for i := 1; i <= n; i++ {
+		s.events = append(s.events, Event{Position: int64(i), ID: uuid.New(), Type: ChirpCreated})
+	}
+	return s
+}
+
+func (s *memoryStore) Checkpoint(ctx context.Context, consumer string) (int64, error) {
+	return s.checkpoints[consumer], nil
+}
+
+func (s *memoryStore) SaveCheckpoint(ctx context.Context, consumer string, position int64) error {
+	s.checkpoints[consumer] = max(s.checkpoints[consumer], position)
+	return nil
+}
+
+func (s *memoryStore) EventsAfter(ctx context.Context, position int64, limit int) ([]Event, error) {
+	var out []Event
+	for _, ev := range s.events {
+		if ev.Position > position && len(out) < limit {
+			out = append(out, ev)
+		}
+	}
+	return out, nil
+}
+
+// recorder is a consumer that records the positions it handles and fails on
+// the positions in failOn.
+type recorder struct {
+	seen   []int64
+	failOn map[int64]bool
+}
+
+func (rec *recorder) handle(ctx context.Context, ev Event) error {
+	if rec.failOn[ev.Position] {
+		return errors.New("handler failed")
+	}
+	rec.seen = append(rec.seen, ev.Position)
+	return nil
+}
+
+func newRunner(store Store, rec *recorder) *runner {
+	return &runner{store: store, consumer: Consumer{Name: "test", Handle: rec.handle}}
+}
+
+func equalPositions(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCatchUpResumesFromCheckpoint(t *testing.T) {
+	store := newMemoryStore(catchUpBatchSize + 5)
+	store.checkpoints["test"] = 3
+	rec := &recorder{}
+	r := newRunner(store, rec)
+
+	r.catchUp(context.Background())
+	if len(rec.seen) != catchUpBatchSize+2 || rec.seen[0] != 4 {
+		t.Fatalf("handled %d events starting at %d, want %d starting at 4", len(rec.seen), rec.seen[0], catchUpBatchSize+2)
+	}
+	if got := store.checkpoints["test"]; got != catchUpBatchSize+5 {
+		t.Errorf("checkpoint = %d, want %d", got, catchUpBatchSize+5)
+	}
+}
+
+func TestDeliverSkipsHandledAndFillsGaps(t *testing.T) {
+	store := newMemoryStore(2)
+	rec := &recorder{}
+	r := newRunner(store, rec)
+	r.catchUp(context.Background())
+
+	// A duplicate of an event already handled.
+	r.deliver(context.Background(), store.events[1])
+	// Events 3 and 4 were dropped by the bus; 5 arrives.
+	for i := 3; i <= 5; i++ {
+		store.events = append(store.events, Event{Position: int64(i), ID: uuid.New()})
+	}
+	r.deliver(context.Background(), store.events[4])
+
+	want := []int64{1, 2, 3, 4, 5}
+	if !equalPositions(rec.seen, want) {
+		t.Errorf("handled %v, want %v", rec.seen, want)
+	}
+}
+
+func TestFailureDoesNotAdvanceCheckpoint(t *testing.T) {
+	store := newMemoryStore(3)
+	rec := &recorder{failOn: map[int64]bool{2: true}}
+	r := newRunner(store, rec)
+
+	r.catchUp(context.Background())
+	if got := store.checkpoints["test"]; got != 1 {
+		t.Fatalf("checkpoint = %d, want 1", got)
+	}
+	// Live events wait for the next catch-up while the consumer is behind.
+	store.events = append(store.events, Event{Position: 4, ID: uuid.New()})
+	r.deliver(context.Background(), store.events[3])
+	if !equalPositions(rec.seen, []int64{1}) {
+		t.Fatalf("handled %v while behind, want [1]", rec.seen)
+	}
+
+	rec.failOn = nil
+	r.catchUp(context.Background())
+	want := []int64{1, 2, 3, 4}
+	if !equalPositions(rec.seen, want) {
+		t.Errorf("handled %v after retry, want %v", rec.seen, want)
+	}
+}
+
+func TestChannelBusDropsForFullSubscribers(t *testing.T) {
+	bus := NewChannelBus(1)
+	ch := bus.Subscribe("test")
+	if bus.Subscribe("test") != ch {
+		t.Fatal("Subscribe with the same name returned a different channel")
+	}
+	for i := 1; i <= 2; i++ {
+		err := bus.Publish(context.Background(), Event{Position: int64(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ev := <-ch; ev.Position != 1 {
+		t.Errorf("received position %d, want 1", ev.Position)
+	}
+	select {
+	case ev := <-ch:
+		t.Errorf("received position %d, want it dropped", ev.Position)
+	default:
+	}
+}
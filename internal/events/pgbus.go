@@ -0,0 +1,139 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// notifyChannel is the Postgres channel events are announced on.
+	notifyChannel = "outbox_events"
+	// maxNotifyPayload keeps payloads under Postgres's 8000 byte limit.
+	// Larger events are announced by position and read back from the store.
+	maxNotifyPayload = 7900
+
+	listenerMinReconnect = 10 * time.Second
+	listenerMaxReconnect = time.Minute
+	listenerPingInterval = 90 * time.Second
+)
+
+// PostgresBus carries events between instances with LISTEN/NOTIFY, so every
+// instance's subscribers see every event, whichever instance relayed it.
+// Notifications sent while an instance is reconnecting are lost; its
+// consumers notice the gap and catch up from the store.
+type PostgresBus struct {
+	store    *PostgresStore
+	listener *pq.Listener
+	local    *ChannelBus
+}
+
+// NewPostgresBus listens for events on a connection of its own to dbURL.
+// Call Run to start passing them to subscribers.
+func NewPostgresBus(dbURL string, store *PostgresStore, buffer int) (*PostgresBus, error) {
+	listener := pq.NewListener(dbURL, listenerMinReconnect, listenerMaxReconnect, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("event bus listener: %v", err)
+		}
+	})
+	err := listener.Listen(notifyChannel)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return &PostgresBus{store: store, listener: listener, local: NewChannelBus(buffer)}, nil
+}
+
+// Publish announces ev to every instance, this one included.
+func (b *PostgresBus) Publish(ctx context.Context, ev Event) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxNotifyPayload {
+		payload = []byte(strconv.FormatInt(ev.Position, 10))
+	}
+	return b.store.queries.NotifyOutboxEvent(ctx, string(payload))
+}
+
+func (b *PostgresBus) Subscribe(name string) <-chan Event {
+	return b.local.Subscribe(name)
+}
+
+// Run passes announced events to this instance's subscribers until ctx is
+// cancelled, then closes the listener.
+func (b *PostgresBus) Run(ctx context.Context) {
+	defer b.listener.Close()
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			go b.listener.Ping()
+		case n := <-b.listener.Notify:
+			// n is nil after a reconnect, when anything sent in between
+			// has been missed.
+			if n == nil {
+				continue
+			}
+			ev, err := b.decode(ctx, n.Extra)
+			if err != nil {
+				log.Printf("event bus: couldn't decode %q: %v", n.Extra, err)
+				continue
+			}
+			b.local.Publish(ctx, ev)
+		}
+	}
+}
+
+func (b *PostgresBus) decode(ctx context.Context, payload string) (Event, error) {
+	position, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		var ev Event
+		err = json.Unmarshal([]byte(payload), &ev)
+		return ev, err
+	}
+	row, err := b.store.queries.GetOutboxEventByPosition(ctx, position)
+	if err != nil {
+		return Event{}, err
+	}
+	return newEvent(row), nil
+}
+
+// Tail is a Store whose consumers start from the latest event rather than a
+// saved checkpoint and keep their checkpoints in memory. It suits consumers
+// that pass events on to whoever is listening at the time, which have no use
+// for events from before they started.
+type Tail struct {
+	*PostgresStore
+	mu          sync.Mutex
+	checkpoints map[string]int64
+}
+
+func (s *PostgresStore) Tail() *Tail {
+	return &Tail{PostgresStore: s, checkpoints: map[string]int64{}}
+}
+
+func (t *Tail) Checkpoint(ctx context.Context, consumer string) (int64, error) {
+	t.mu.Lock()
+	position, ok := t.checkpoints[consumer]
+	t.mu.Unlock()
+	if ok {
+		return position, nil
+	}
+	return t.Latest(ctx)
+}
+
+func (t *Tail) SaveCheckpoint(ctx context.Context, consumer string, position int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.checkpoints[consumer] = max(t.checkpoints[consumer], position)
+	return nil
+}
@@ -0,0 +1,141 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// relayLockKey is the advisory lock held while events are given positions,
+// so that only one relay at a time assigns them.
+const relayLockKey = 0x636869727079 // "chirpy"
+
+// Append records an event in the outbox. It must run in the transaction that
+// makes the change the event describes.
+func Append(ctx context.Context, qtx *database.Queries, eventType string, userID uuid.UUID, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return qtx.CreateOutboxEvent(ctx, database.CreateOutboxEventParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		EventType: eventType,
+		UserID:    userID,
+		Payload:   payload,
+	})
+}
+
+// PostgresStore keeps events in the outbox_events table and checkpoints in
+// outbox_checkpoints.
+type PostgresStore struct {
+	db      *sql.DB
+	queries *database.Queries
+}
+
+func NewPostgresStore(db *sql.DB, queries *database.Queries) *PostgresStore {
+	return &PostgresStore{db: db, queries: queries}
+}
+
+func (s *PostgresStore) Checkpoint(ctx context.Context, consumer string) (int64, error) {
+	position, err := s.queries.GetOutboxCheckpoint(ctx, consumer)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return position, err
+}
+
+func (s *PostgresStore) SaveCheckpoint(ctx context.Context, consumer string, position int64) error {
+	return s.queries.SaveOutboxCheckpoint(ctx, database.SaveOutboxCheckpointParams{
+		Consumer: consumer,
+		Position: position,
+	})
+}
+
+func (s *PostgresStore) EventsAfter(ctx context.Context, position int64, limit int) ([]Event, error) {
+	rows, err := s.queries.ListOutboxEventsAfter(ctx, database.ListOutboxEventsAfterParams{
+		After:    position,
+		RowLimit: int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	events := make([]Event, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, newEvent(row))
+	}
+	return events, nil
+}
+
+// Relay publishes up to limit unpublished events and returns how many it
+// published. Positions are assigned under an advisory lock and only become
+// visible when the transaction commits, so a consumer never sees a position
+// before an earlier one. If another relay holds the lock Relay does nothing.
+// Events are passed to bus after the commit; if that fails consumers still
+// find them in the store.
+func (s *PostgresStore) Relay(ctx context.Context, bus Bus, limit int) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	qtx := s.queries.WithTx(tx)
+
+	locked, err := qtx.TryLockOutboxRelay(ctx, relayLockKey)
+	if err != nil {
+		return 0, err
+	}
+	if !locked {
+		return 0, nil
+	}
+	rows, err := qtx.PublishOutboxEvents(ctx, int32(limit))
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	events := make([]Event, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, newEvent(row))
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Position < events[j].Position })
+	for _, ev := range events {
+		err = bus.Publish(ctx, ev)
+		if err != nil {
+			return len(events), err
+		}
+	}
+	return len(events), nil
+}
+
+// Latest is the position of the most recently published event, or 0.
+func (s *PostgresStore) Latest(ctx context.Context) (int64, error) {
+	return s.queries.GetLatestOutboxPosition(ctx)
+}
+
+// Prune deletes events published before cutoff. Callers should keep events
+// for longer than any consumer could reasonably be stopped for, since a
+// consumer whose checkpoint is older than cutoff will never see them.
+func (s *PostgresStore) Prune(ctx context.Context, cutoff time.Time) (int64, error) {
+	return s.queries.DeletePublishedOutboxEvents(ctx, cutoff)
+}
+
+func newEvent(row database.OutboxEvent) Event {
+	return Event{
+		Position:  row.Position.Int64,
+		ID:        row.ID,
+		Type:      row.EventType,
+		UserID:    row.UserID,
+		CreatedAt: row.CreatedAt,
+		Payload:   row.Payload,
+	}
+}
@@ -0,0 +1,99 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuild(t *testing.T) {
+	data, err := Build("Chirpy <digest@chirpy.example>", Message{
+		To:      "ann@example.com",
+		Subject: "Your daily digest ✨",
+		Text:    "Hello, Ann",
+		HTML:    "<p>Hello, Ann</p>",
+		Headers: map[string]string{"list-unsubscribe": "<https://chirpy.example/u>"},
+	}, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Your daily digest ✨" {
+		t.Errorf("Subject = %q (%v)", subject, err)
+	}
+	if got := msg.Header.Get("List-Unsubscribe"); got != "<https://chirpy.example/u>" {
+		t.Errorf("List-Unsubscribe = %q", got)
+	}
+	if got := msg.Header.Get("Message-Id"); !strings.HasSuffix(got, "@chirpy.example>") {
+		t.Errorf("Message-ID = %q", got)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q (%v)", mediaType, err)
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", "Hello, Ann"},
+		{"text/html; charset=utf-8", "<p>Hello, Ann</p>"},
+	} {
+		part, err := parts.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(part)
+		if got := part.Header.Get("Content-Type"); got != want.contentType {
+			t.Errorf("part Content-Type = %q, want %q", got, want.contentType)
+		}
+		if string(body) != want.body {
+			t.Errorf("part body = %q, want %q", body, want.body)
+		}
+	}
+}
+
+func TestBuildRejectsBadAddresses(t *testing.T) {
+	_, err := Build("digest@chirpy.example", Message{To: "not an address"}, time.Now())
+	if err == nil {
+		t.Error("expected an error for an invalid recipient")
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewFileMailer(filepath.Join(dir, "mail"), "digest@chirpy.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		err = m.Send(context.Background(), Message{To: "ann@example.com", Subject: "Hi", Text: "Hi", HTML: "<p>Hi</p>"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "mail", "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2", len(files))
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "To: <ann@example.com>") {
+		t.Errorf("message does not look like an email:\n%s", data)
+	}
+}
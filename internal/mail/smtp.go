@@ -0,0 +1,96 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer sends messages through an SMTP server, upgrading to TLS with
+// STARTTLS when the server offers it.
+type SMTPMailer struct {
+	addr     string
+	from     string
+	username string
+	password string
+}
+
+// NewSMTPMailer returns a mailer that sends through the server at addr
+// ("host:port") as from. username may be empty for servers that do not need
+// authentication, such as a local relay.
+func NewSMTPMailer(addr, from, username, password string) *SMTPMailer {
+	return &SMTPMailer{addr: addr, from: from, username: username, password: password}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	data, err := Build(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	fromAddr, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return err
+	}
+	toAddr, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+	host, _, err := net.SplitHostPort(m.addr)
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	// net/smtp does not take a context, so the deadline is set on the
+	// connection instead.
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		err = c.StartTLS(&tls.Config{ServerName: host})
+		if err != nil {
+			return err
+		}
+	}
+	if m.username != "" {
+		err = c.Auth(smtp.PlainAuth("", m.username, m.password, host))
+		if err != nil {
+			return err
+		}
+	}
+	err = c.Mail(fromAddr.Address)
+	if err != nil {
+		return err
+	}
+	err = c.Rcpt(toAddr.Address)
+	if err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	return c.Quit()
+}
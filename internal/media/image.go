@@ -0,0 +1,332 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	maxPixels    = 40_000_000
+	maxDimension = 10_000
+	jpegQuality  = 90
+	// Every frame of a GIF is decoded and kept in memory at a byte a pixel,
+	// so animations are limited in frames and in pixels across all frames.
+	maxGIFFrames = 500
+	maxGIFPixels = 100_000_000
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported media type")
+	ErrTooLarge        = errors.New("image dimensions are too large")
+)
+
+// Image is an uploaded image after it has been re-encoded. Re-encoding drops
+// EXIF and any other metadata the original file carried.
+type Image struct {
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+	Data        []byte
+
+	ThumbnailContentType string
+	ThumbnailExt         string
+	Thumbnail            []byte
+}
+
+// Process sniffs the content type of data, re-encodes it without metadata and
+// builds a thumbnail that fits within thumbSize x thumbSize.
+func Process(data []byte, thumbSize int) (*Image, error) {
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return nil, ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	if cfg.Width > maxDimension || cfg.Height > maxDimension || cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	var (
+		first   image.Image
+		encoded bytes.Buffer
+	)
+	switch contentType {
+	case "image/gif":
+		err := checkGIFFrames(data)
+		if err != nil {
+			return nil, err
+		}
+		// Keep animations, but drop comment and application extensions.
+		anim, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrUnsupportedType
+		}
+		first = anim.Image[0]
+		err = gif.EncodeAll(&encoded, anim)
+		if err != nil {
+			return nil, err
+		}
+	case "image/jpeg":
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrUnsupportedType
+		}
+		// The orientation tag is about to be stripped along with the rest of
+		// the EXIF data, so apply it to the pixels first.
+		first = orient(img, exifOrientation(data))
+		err = jpeg.Encode(&encoded, first, &jpeg.Options{Quality: jpegQuality})
+		if err != nil {
+			return nil, err
+		}
+	case "image/png":
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrUnsupportedType
+		}
+		first = img
+		err = png.Encode(&encoded, img)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	out := &Image{
+		ContentType: contentType,
+		Ext:         extensions[contentType],
+		Width:       first.Bounds().Dx(),
+		Height:      first.Bounds().Dy(),
+		Data:        encoded.Bytes(),
+	}
+
+	var thumb bytes.Buffer
+	small := resize(first, thumbSize)
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&thumb, small, &jpeg.Options{Quality: jpegQuality})
+		out.ThumbnailContentType = "image/jpeg"
+	} else {
+		err = png.Encode(&thumb, small)
+		out.ThumbnailContentType = "image/png"
+	}
+	if err != nil {
+		return nil, err
+	}
+	out.ThumbnailExt = extensions[out.ThumbnailContentType]
+	out.Thumbnail = thumb.Bytes()
+	return out, nil
+}
+
+// checkGIFFrames walks the blocks of a GIF without decoding any pixels and
+// returns ErrTooLarge if it has more than maxGIFFrames frames or more than
+// maxGIFPixels pixels across them.
+func checkGIFFrames(data []byte) error {
+	// The header and logical screen descriptor, then the global color table.
+	if len(data) < 13 {
+		return ErrUnsupportedType
+	}
+	i := 13
+	if data[10]&0x80 != 0 {
+		i += 3 << (data[10]&7 + 1)
+	}
+	frames, pixels := 0, 0
+	for i < len(data) {
+		switch data[i] {
+		case 0x21: // Extension: a label, then data sub-blocks.
+			i += 2
+		case 0x2C: // Image descriptor, local color table, LZW code size.
+			if i+10 > len(data) {
+				return ErrUnsupportedType
+			}
+			w := int(binary.LittleEndian.Uint16(data[i+5:]))
+			h := int(binary.LittleEndian.Uint16(data[i+7:]))
+			frames++
+			pixels += w * h
+			if frames > maxGIFFrames || pixels > maxGIFPixels {
+				return ErrTooLarge
+			}
+			packed := data[i+9]
+			i += 10
+			if packed&0x80 != 0 {
+				i += 3 << (packed&7 + 1)
+			}
+			i++
+		case 0x3B: // Trailer.
+			return nil
+		default:
+			return ErrUnsupportedType
+		}
+		// Skip the data sub-blocks up to the empty one that ends them.
+		for {
+			if i >= len(data) {
+				return ErrUnsupportedType
+			}
+			size := int(data[i])
+			i += 1 + size
+			if size == 0 {
+				break
+			}
+		}
+	}
+	return ErrUnsupportedType
+}
+
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// ContentTypeForExt maps a stored file's extension back to its content type.
+func ContentTypeForExt(ext string) string {
+	for contentType, e := range extensions {
+		if e == ext {
+			return contentType
+		}
+	}
+	return "application/octet-stream"
+}
+
+// resize scales img down with a box filter so that neither side exceeds size.
+// Images that already fit are returned unchanged.
+func resize(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	dw, dh := size, h*size/w
+	if h > w {
+		dw, dh = w*size/h, size
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+
+	dst := image.NewRGBA64(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := b.Min.Y+y*h/dh, b.Min.Y+(y+1)*h/dh
+		for x := 0; x < dw; x++ {
+			x0, x1 := b.Min.X+x*w/dw, b.Min.X+(x+1)*w/dw
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < max(y1, y0+1); sy++ {
+				for sx := x0; sx < max(x1, x0+1); sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(bl / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
+
+// orient rotates and flips img according to an EXIF orientation value (1-8).
+func orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA64(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// exifOrientation returns the orientation tag from a JPEG's EXIF segment, or
+// 1 (upright) if there is none.
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := int(order.Uint32(tiff[4:]))
+	if offset+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for n := 0; n < count; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 1
+}
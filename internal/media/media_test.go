@@ -0,0 +1,199 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"io"
+	"testing"
+)
+
+func makeJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("jpeg.Encode returned error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// withOrientation splices an EXIF APP1 segment carrying the given orientation
+// right after the JPEG's SOI marker.
+func withOrientation(data []byte, orientation uint16) []byte {
+	var tiff bytes.Buffer
+	tiff.WriteString("II")
+	binary.Write(&tiff, binary.LittleEndian, uint16(42))
+	binary.Write(&tiff, binary.LittleEndian, uint32(8))
+	binary.Write(&tiff, binary.LittleEndian, uint16(1))
+	binary.Write(&tiff, binary.LittleEndian, uint16(0x0112))
+	binary.Write(&tiff, binary.LittleEndian, uint16(3))
+	binary.Write(&tiff, binary.LittleEndian, uint32(1))
+	binary.Write(&tiff, binary.LittleEndian, orientation)
+	binary.Write(&tiff, binary.LittleEndian, uint16(0))
+	binary.Write(&tiff, binary.LittleEndian, uint32(0))
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	var out bytes.Buffer
+	out.Write(data[:2])
+	out.Write([]byte{0xFF, 0xE1})
+	binary.Write(&out, binary.BigEndian, uint16(len(payload)+2))
+	out.Write(payload)
+	out.Write(data[2:])
+	return out.Bytes()
+}
+
+func TestProcessStripsEXIFAndAppliesOrientation(t *testing.T) {
+	data := withOrientation(makeJPEG(t, 40, 20), 6)
+
+	img, err := Process(data, 16)
+	if err != nil {
+		t.Fatalf("Process returned error: %v", err)
+	}
+	if bytes.Contains(img.Data, []byte("Exif")) {
+		t.Errorf("expected EXIF to be stripped from the image")
+	}
+	if img.Width != 20 || img.Height != 40 {
+		t.Errorf("expected rotated size 20x40, got %dx%d", img.Width, img.Height)
+	}
+	if img.ContentType != "image/jpeg" || img.Ext != ".jpg" {
+		t.Errorf("unexpected content type %q / ext %q", img.ContentType, img.Ext)
+	}
+}
+
+func TestProcessBuildsThumbnail(t *testing.T) {
+	img, err := Process(makeJPEG(t, 400, 100), 64)
+	if err != nil {
+		t.Fatalf("Process returned error: %v", err)
+	}
+	thumb, _, err := image.DecodeConfig(bytes.NewReader(img.Thumbnail))
+	if err != nil {
+		t.Fatalf("could not decode thumbnail: %v", err)
+	}
+	if thumb.Width != 64 || thumb.Height != 16 {
+		t.Errorf("expected 64x16 thumbnail, got %dx%d", thumb.Width, thumb.Height)
+	}
+}
+
+func TestProcessRejectsNonImages(t *testing.T) {
+	_, err := Process([]byte("<html><body>not an image</body></html>"), 64)
+	if err != ErrUnsupportedType {
+		t.Fatalf("expected ErrUnsupportedType, got %v", err)
+	}
+}
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore returned error: %v", err)
+	}
+	ctx := context.Background()
+
+	err = store.Put(ctx, "avatars/a.jpg", bytes.NewReader([]byte("hello")))
+	if err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+	rc, err := store.Open(ctx, "avatars/a.jpg")
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(got) != "hello" {
+		t.Errorf("expected %q, got %q", "hello", got)
+	}
+
+	if err := store.Delete(ctx, "avatars/a.jpg"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, err := store.Open(ctx, "avatars/a.jpg"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestLocalStoreRejectsTraversal(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStore returned error: %v", err)
+	}
+	err = store.Put(context.Background(), "../escape.txt", bytes.NewReader(nil))
+	if err == nil {
+		t.Fatalf("expected an error for a key outside the store")
+	}
+}
+
+func TestProcessKeepsSmallAnimations(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	anim := &gif.GIF{}
+	for i := 0; i < 3; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 20, 10), palette)
+		frame.SetColorIndex(i, i, 1)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatalf("gif.EncodeAll returned error: %v", err)
+	}
+	img, err := Process(buf.Bytes(), 64)
+	if err != nil {
+		t.Fatalf("Process returned error: %v", err)
+	}
+	out, err := gif.DecodeAll(bytes.NewReader(img.Data))
+	if err != nil {
+		t.Fatalf("could not decode result: %v", err)
+	}
+	if len(out.Image) != 3 {
+		t.Errorf("expected 3 frames, got %d", len(out.Image))
+	}
+}
+
+// manyFrameGIF builds a GIF of frames w x h pixels whose image data is a
+// single empty block, so it stays a few bytes a frame however large the
+// frames claim to be.
+func manyFrameGIF(frames, w, h int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("GIF89a")
+	binary.Write(&buf, binary.LittleEndian, uint16(w))
+	binary.Write(&buf, binary.LittleEndian, uint16(h))
+	buf.Write([]byte{0x80, 0, 0})             // A two-color global color table.
+	buf.Write([]byte{0, 0, 0, 255, 255, 255}) // Black and white.
+	for i := 0; i < frames; i++ {
+		buf.WriteByte(0x2C)
+		binary.Write(&buf, binary.LittleEndian, [2]uint16{0, 0})
+		binary.Write(&buf, binary.LittleEndian, uint16(w))
+		binary.Write(&buf, binary.LittleEndian, uint16(h))
+		buf.Write([]byte{0, 2, 1, 0x44, 0}) // No local table; LZW size 2; one data byte.
+	}
+	buf.WriteByte(0x3B)
+	return buf.Bytes()
+}
+
+func TestProcessRejectsHugeAnimations(t *testing.T) {
+	tests := []struct {
+		name         string
+		frames, w, h int
+	}{
+		{"too many pixels", 10, 6000, 6000},
+		{"too many frames", maxGIFFrames + 1, 10, 10},
+	}
+	for _, tt := range tests {
+		data := manyFrameGIF(tt.frames, tt.w, tt.h)
+		if len(data) > 16<<10 {
+			t.Fatalf("%s: test GIF is %d bytes, want a small one", tt.name, len(data))
+		}
+		_, err := Process(data, 64)
+		if err != ErrTooLarge {
+			t.Errorf("%s: expected ErrTooLarge, got %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package moderation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// LoadFile reads rules from a JSON file holding an array of rules. Rules
+// without an ID are given one based on their position in the file.
+func LoadFile(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	err = json.Unmarshal(data, &rules)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for i := range rules {
+		err := rules[i].Validate()
+		if err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %w", path, i, err)
+		}
+		if rules[i].ID == "" {
+			rules[i].ID = fmt.Sprintf("file:%d", i)
+		}
+	}
+	return rules, nil
+}
@@ -0,0 +1,190 @@
+// Package moderation checks user-written text against a list of rules and
+// decides whether it should be masked, rejected or flagged for review.
+package moderation
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Throne-of-Doom/chirpy/internal/textutil"
+)
+
+// Action is what happens to text that matches a rule.
+type Action string
+
+const (
+	// ActionMask replaces the matched words with a mask.
+	ActionMask Action = "mask"
+	// ActionReject refuses the text outright.
+	ActionReject Action = "reject"
+	// ActionFlag lets the text through but marks it for review.
+	ActionFlag Action = "flag"
+)
+
+// Mask is what masked words are replaced with.
+const Mask = "****"
+
+var (
+	ErrInvalidAction = errors.New("action must be mask, reject or flag")
+	ErrEmptyTerm     = errors.New("term must contain at least one word")
+)
+
+// Rule matches a term of one or more words. Matching is done on normalized
+// words, so case, accents, fullwidth characters and surrounding punctuation do
+// not matter, but a term only matches whole words.
+type Rule struct {
+	ID     string `json:"id"`
+	Term   string `json:"term"`
+	Action Action `json:"action"`
+}
+
+// Validate reports whether the rule can be used in a filter.
+func (r Rule) Validate() error {
+	switch r.Action {
+	case ActionMask, ActionReject, ActionFlag:
+	default:
+		return ErrInvalidAction
+	}
+	if len(textutil.Words(r.Term)) == 0 {
+		return ErrEmptyTerm
+	}
+	return nil
+}
+
+// Match records that a rule fired.
+type Match struct {
+	RuleID string `json:"rule_id"`
+	Term   string `json:"term"`
+	Action Action `json:"action"`
+}
+
+// Result is the outcome of checking a piece of text.
+type Result struct {
+	// Text is the input with every masked term replaced by Mask.
+	Text string
+	// Matches lists each rule that fired once, in the order they first
+	// matched.
+	Matches []Match
+}
+
+// Rejected reports whether any reject rule fired.
+func (r Result) Rejected() bool {
+	return r.has(ActionReject)
+}
+
+// Flagged reports whether any flag rule fired.
+func (r Result) Flagged() bool {
+	return r.has(ActionFlag)
+}
+
+func (r Result) has(action Action) bool {
+	for _, match := range r.Matches {
+		if match.Action == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter checks text against a set of rules. The rules can be replaced at
+// any time with SetRules; checks already in progress finish with the rules
+// they started with.
+type Filter struct {
+	rules atomic.Pointer[ruleSet]
+}
+
+type compiledRule struct {
+	Rule
+	words []string
+}
+
+// ruleSet indexes rules by their first word.
+type ruleSet struct {
+	rules   []Rule
+	byFirst map[string][]compiledRule
+}
+
+// NewFilter returns a filter using rules. Invalid rules are skipped.
+func NewFilter(rules []Rule) *Filter {
+	f := &Filter{}
+	f.SetRules(rules)
+	return f
+}
+
+// SetRules replaces the filter's rules. Invalid rules are skipped.
+func (f *Filter) SetRules(rules []Rule) {
+	set := &ruleSet{byFirst: make(map[string][]compiledRule)}
+	for _, rule := range rules {
+		if rule.Validate() != nil {
+			continue
+		}
+		words := textutil.Words(rule.Term)
+		set.rules = append(set.rules, rule)
+		set.byFirst[words[0]] = append(set.byFirst[words[0]], compiledRule{Rule: rule, words: words})
+	}
+	f.rules.Store(set)
+}
+
+// Rules returns the rules the filter is using.
+func (f *Filter) Rules() []Rule {
+	return append([]Rule(nil), f.rules.Load().rules...)
+}
+
+// Check matches text against the filter's rules.
+func (f *Filter) Check(text string) Result {
+	set := f.rules.Load()
+	tokens := textutil.Tokenize(text)
+
+	result := Result{}
+	fired := make(map[string]bool)
+	// masked holds the byte ranges to replace, in order and without overlap.
+	var masked [][2]int
+	for i := range tokens {
+		for _, rule := range set.byFirst[tokens[i].Text] {
+			if !matchesAt(tokens, i, rule.words) {
+				continue
+			}
+			if !fired[rule.ID] {
+				fired[rule.ID] = true
+				result.Matches = append(result.Matches, Match{
+					RuleID: rule.ID,
+					Term:   rule.Term,
+					Action: rule.Action,
+				})
+			}
+			if rule.Action != ActionMask {
+				continue
+			}
+			start, end := tokens[i].Start, tokens[i+len(rule.words)-1].End
+			if n := len(masked); n > 0 && start <= masked[n-1][1] {
+				masked[n-1][1] = max(masked[n-1][1], end)
+			} else {
+				masked = append(masked, [2]int{start, end})
+			}
+		}
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, span := range masked {
+		b.WriteString(text[last:span[0]])
+		b.WriteString(Mask)
+		last = span[1]
+	}
+	b.WriteString(text[last:])
+	result.Text = b.String()
+	return result
+}
+
+func matchesAt(tokens []textutil.Token, i int, words []string) bool {
+	if i+len(words) > len(tokens) {
+		return false
+	}
+	for j, word := range words {
+		if tokens[i+j].Text != word {
+			return false
+		}
+	}
+	return true
+}
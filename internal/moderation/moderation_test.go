@@ -0,0 +1,101 @@
+package moderation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckMasksPunctuatedWords(t *testing.T) {
+	f := NewFilter([]Rule{
+		{ID: "1", Term: "kerfuffle", Action: ActionMask},
+		{ID: "2", Term: "fornax", Action: ActionMask},
+	})
+	result := f.Check("What a Kerfuffle! Fornax, again.")
+	if want := "What a ****! ****, again."; result.Text != want {
+		t.Errorf("Text = %q, want %q", result.Text, want)
+	}
+	if len(result.Matches) != 2 {
+		t.Fatalf("got %d matches, want 2", len(result.Matches))
+	}
+	if result.Rejected() || result.Flagged() {
+		t.Error("mask rules should not reject or flag")
+	}
+}
+
+func TestCheckMatchesNormalizedText(t *testing.T) {
+	f := NewFilter([]Rule{{ID: "1", Term: "sharbert", Action: ActionMask}})
+	result := f.Check("\uff53\uff48\uff41\uff52\uff42\uff45\uff52\uff54 and shárbert")
+	if want := "**** and ****"; result.Text != want {
+		t.Errorf("Text = %q, want %q", result.Text, want)
+	}
+}
+
+func TestCheckOnlyMatchesWholeWords(t *testing.T) {
+	f := NewFilter([]Rule{{ID: "1", Term: "ass", Action: ActionMask}})
+	result := f.Check("a classic assessment")
+	if result.Text != "a classic assessment" || len(result.Matches) != 0 {
+		t.Errorf("unexpected match: %+v", result)
+	}
+}
+
+func TestCheckMultiWordTerm(t *testing.T) {
+	f := NewFilter([]Rule{{ID: "1", Term: "bad word", Action: ActionMask}})
+	result := f.Check("a Bad-word here, bad. word there")
+	if want := "a **** here, **** there"; result.Text != want {
+		t.Errorf("Text = %q, want %q", result.Text, want)
+	}
+}
+
+func TestCheckActions(t *testing.T) {
+	f := NewFilter([]Rule{
+		{ID: "reject", Term: "spam", Action: ActionReject},
+		{ID: "flag", Term: "suspicious", Action: ActionFlag},
+	})
+	result := f.Check("suspicious SPAM, spam")
+	if !result.Rejected() || !result.Flagged() {
+		t.Errorf("expected reject and flag, got %+v", result.Matches)
+	}
+	if len(result.Matches) != 2 || result.Matches[0].RuleID != "flag" || result.Matches[1].RuleID != "reject" {
+		t.Errorf("each rule should be reported once in order, got %+v", result.Matches)
+	}
+	if result.Text != "suspicious SPAM, spam" {
+		t.Errorf("reject and flag rules should not change the text, got %q", result.Text)
+	}
+}
+
+func TestSetRulesSkipsInvalidRules(t *testing.T) {
+	f := NewFilter(nil)
+	f.SetRules([]Rule{
+		{ID: "1", Term: "ok", Action: "delete"},
+		{ID: "2", Term: "!!!", Action: ActionMask},
+		{ID: "3", Term: "fine", Action: ActionMask},
+	})
+	rules := f.Rules()
+	if len(rules) != 1 || rules[0].ID != "3" {
+		t.Errorf("Rules() = %+v, want only rule 3", rules)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	err := os.WriteFile(path, []byte(`[{"term": "kerfuffle", "action": "mask"}, {"id": "x", "term": "spam", "action": "reject"}]`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].ID != "file:0" || rules[1].ID != "x" {
+		t.Errorf("LoadFile() = %+v", rules)
+	}
+
+	err = os.WriteFile(path, []byte(`[{"term": "kerfuffle", "action": "ban"}]`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Error("expected an error for an invalid action")
+	}
+}
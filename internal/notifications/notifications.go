@@ -0,0 +1,117 @@
+// Package notifications decides how events are presented to the users they
+// concern: which kind of notification each is, which notifications fold
+// together, and how a notification reads.
+package notifications
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Type is a kind of notification. Users can turn each type off.
+type Type string
+
+const (
+	// Mention is sent to users named with @handle in a chirp.
+	Mention Type = "mention"
+	// Reply is sent to the author of a chirp that was replied to.
+	Reply Type = "reply"
+	// Like is sent to the author of a chirp that was liked.
+	Like Type = "like"
+	// Follow is sent to a user who was followed.
+	Follow Type = "follow"
+	// Upgrade is sent to a user who became a Chirpy Red subscriber.
+	Upgrade Type = "upgrade"
+)
+
+// Types lists every type, in the order they are documented.
+var Types = []Type{Mention, Reply, Like, Follow, Upgrade}
+
+// MaxMentions is how many users one chirp can notify by mentioning them.
+const MaxMentions = 10
+
+// mentionPattern matches @handle where the @ does not follow a word
+// character, so email addresses are not mentions. Handles are 3 to 15
+// letters, numbers or underscores.
+var mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_])@([A-Za-z0-9_]{3,15})\b`)
+
+// ValidType reports whether t is a known type.
+func ValidType(t Type) bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupKey identifies the notifications an event folds into while they are
+// unread. Likes and replies group by chirp and follows group together; each
+// mention and upgrade stands alone.
+func GroupKey(t Type, chirpID, eventID uuid.UUID) string {
+	switch t {
+	case Like, Reply, Mention:
+		return string(t) + ":" + chirpID.String()
+	case Follow:
+		return string(t)
+	}
+	return string(t) + ":" + eventID.String()
+}
+
+// Summary is the text of a notification of type t from count actors, of
+// whom handles are the most recent.
+func Summary(t Type, handles []string, count int) string {
+	who := actors(handles, count)
+	switch t {
+	case Mention:
+		return who + " mentioned you"
+	case Reply:
+		return who + " replied to your chirp"
+	case Like:
+		return who + " liked your chirp"
+	case Follow:
+		return who + " followed you"
+	case Upgrade:
+		return "Your Chirpy Red subscription is active"
+	}
+	return string(t)
+}
+
+func actors(handles []string, count int) string {
+	switch {
+	case count == 0 || len(handles) == 0:
+		if count > 1 {
+			return fmt.Sprintf("%d people", count)
+		}
+		return "Someone"
+	case count == 1:
+		return "@" + handles[0]
+	case count == 2 && len(handles) >= 2:
+		return "@" + handles[0] + " and @" + handles[1]
+	case count == 2:
+		return "@" + handles[0] + " and 1 other"
+	}
+	return fmt.Sprintf("@%s and %d others", handles[0], count-1)
+}
+
+// Mentions returns the handles mentioned in body, lowercased, in the order
+// they first appear and at most MaxMentions of them.
+func Mentions(body string) []string {
+	var handles []string
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		handle := strings.ToLower(match[1])
+		if seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		handles = append(handles, handle)
+		if len(handles) == MaxMentions {
+			break
+		}
+	}
+	return handles
+}
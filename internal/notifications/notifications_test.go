@@ -0,0 +1,73 @@
+package notifications
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{"hello @Alice and @bob_2", []string{"alice", "bob_2"}},
+		{"@alice @ALICE again", []string{"alice"}},
+		{"mail me at someone@example.com", nil},
+		{"@al is too short, @this_is_far_too_long too long", nil},
+		{"(@carol), @dave!", []string{"carol", "dave"}},
+	}
+	for _, tt := range tests {
+		if got := Mentions(tt.body); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Mentions(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestMentionsLimit(t *testing.T) {
+	body := ""
+	for i := 0; i < MaxMentions+5; i++ {
+		body += " @user_" + string(rune('a'+i))
+	}
+	if got := len(Mentions(body)); got != MaxMentions {
+		t.Errorf("len(Mentions()) = %d, want %d", got, MaxMentions)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		t       Type
+		handles []string
+		count   int
+		want    string
+	}{
+		{Like, []string{"alice"}, 1, "@alice liked your chirp"},
+		{Like, []string{"bob", "alice"}, 2, "@bob and @alice liked your chirp"},
+		{Like, []string{"carol", "bob", "alice"}, 5, "@carol and 4 others liked your chirp"},
+		{Follow, nil, 5, "5 people followed you"},
+		{Reply, nil, 1, "Someone replied to your chirp"},
+		{Upgrade, nil, 0, "Your Chirpy Red subscription is active"},
+	}
+	for _, tt := range tests {
+		if got := Summary(tt.t, tt.handles, tt.count); got != tt.want {
+			t.Errorf("Summary(%s, %v, %d) = %q, want %q", tt.t, tt.handles, tt.count, got, tt.want)
+		}
+	}
+}
+
+func TestGroupKey(t *testing.T) {
+	chirp, event, other := uuid.New(), uuid.New(), uuid.New()
+	if GroupKey(Like, chirp, event) != GroupKey(Like, chirp, other) {
+		t.Error("likes of the same chirp should share a group")
+	}
+	if GroupKey(Like, chirp, event) == GroupKey(Reply, chirp, event) {
+		t.Error("likes and replies should not share a group")
+	}
+	if GroupKey(Follow, chirp, event) != GroupKey(Follow, other, other) {
+		t.Error("follows should share a group")
+	}
+	if GroupKey(Upgrade, uuid.Nil, event) == GroupKey(Upgrade, uuid.Nil, other) {
+		t.Error("upgrades should not share a group")
+	}
+}
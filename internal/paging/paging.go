@@ -0,0 +1,137 @@
+// Package paging fills pages of a list ordered by time and then ID, going
+// forwards from a cursor or back from one, and works out the cursors of the
+// neighbouring pages. Items the viewer should not see are skipped and more
+// are fetched in their place, so pages are only short at the end of the list.
+package paging
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cursor is a place in the list. A page starts just after it, or ends just
+// before it when going back.
+type Cursor struct {
+	Time time.Time
+	ID   uuid.UUID
+}
+
+// step returns the cursor right after c in ascending order, or right before
+// it with back. Only the ID changes; Postgres compares UUIDs byte by byte, so
+// stepping it as a 128-bit number leaves nothing in between.
+func (c Cursor) step(back bool) Cursor {
+	id := c.ID
+	for i := len(id) - 1; i >= 0; i-- {
+		if back {
+			id[i]--
+			if id[i] != 0xff {
+				break
+			}
+		} else {
+			id[i]++
+			if id[i] != 0 {
+				break
+			}
+		}
+	}
+	return Cursor{Time: c.Time, ID: id}
+}
+
+// Fetch returns up to n items after the cursor, or from the start when
+// hasAfter is false, in ascending or descending order.
+type Fetch[T any] func(after Cursor, hasAfter, ascending bool, n int) ([]T, error)
+
+// Request describes the page to fill. The list is shown in ascending order
+// unless Descending is set. With Before, the page holds the items before
+// Cursor rather than after it. Size is how many items the page holds.
+// Items are fetched Batch at a time, at most MaxBatches times; Batch must be
+// more than Size for the page to tell whether there is more.
+type Request struct {
+	Descending bool
+	Cursor     Cursor
+	HasCursor  bool
+	Before     bool
+	Size       int
+	Batch      int
+	MaxBatches int
+}
+
+// Page is a filled page in the order it is shown. Next and Prev are the
+// cursors of the neighbouring pages: Next is used as the cursor of the next
+// page and Prev as the Before cursor of the previous one.
+type Page[T any] struct {
+	Items   []T
+	Next    Cursor
+	HasNext bool
+	Prev    Cursor
+	HasPrev bool
+}
+
+// Fill fills the page req describes from fetch. key returns an item's place
+// in the list, and skip reports whether the viewer should not see it.
+func Fill[T any](req Request, key func(T) Cursor, skip func(T) bool, fetch Fetch[T]) (Page[T], error) {
+	// Going back scans the list in reverse from the cursor, nearest first.
+	ascending := req.Descending == req.Before
+	after, hasAfter := req.Cursor, req.HasCursor
+	var first, blocked Cursor
+	seen := false
+	items := []T{}
+	more, exhausted := false, false
+	for batch := 0; batch < req.MaxBatches && !more && !exhausted; batch++ {
+		rows, err := fetch(after, hasAfter, ascending, req.Batch)
+		if err != nil {
+			return Page[T]{}, err
+		}
+		exhausted = len(rows) < req.Batch
+		for _, row := range rows {
+			k := key(row)
+			if !skip(row) {
+				if len(items) == req.Size {
+					more, blocked = true, k
+					break
+				}
+				items = append(items, row)
+			}
+			if !seen {
+				first, seen = k, true
+			}
+			after, hasAfter = k, true
+		}
+	}
+	// Running out of batches leaves the rest for the next page.
+	more = more || !exhausted
+
+	// The near side of the page is where the scan started. Only a page with
+	// a cursor has anything there; the page on that side ends at the first
+	// item looked at, or at the cursor itself if there was none.
+	var page Page[T]
+	var near, far Cursor
+	hasNear := req.HasCursor
+	if hasNear {
+		near = first
+		if !seen {
+			near = req.Cursor.step(!ascending)
+		}
+	}
+	// The far side is where the scan stopped. If it stopped at the very
+	// first item, because the page had no room, the next page starts with it.
+	hasFar := more
+	if hasFar {
+		far = after
+		if !hasAfter {
+			far = blocked.step(ascending)
+		}
+	}
+	if req.Before {
+		slices.Reverse(items)
+		page.Next, page.HasNext = near, hasNear
+		page.Prev, page.HasPrev = far, hasFar
+	} else {
+		page.Next, page.HasNext = far, hasFar
+		page.Prev, page.HasPrev = near, hasNear
+	}
+	page.Items = items
+	return page, nil
+}
@@ -0,0 +1,204 @@
+package paging
+
+import (
+	"bytes"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type item struct {
+	n    int
+	key  Cursor
+	skip bool
+}
+
+// list is an in-memory list of n items. Pairs of items share a time, so the
+// IDs decide their order.
+func list(n int, skipped ...int) []item {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	items := make([]item, n)
+	for i := range items {
+		var id uuid.UUID
+		id[15] = byte(i + 1)
+		items[i] = item{
+			n:    i,
+			key:  Cursor{Time: base.Add(time.Duration(i/2) * time.Minute), ID: id},
+			skip: slices.Contains(skipped, i),
+		}
+	}
+	return items
+}
+
+func compare(a, b Cursor) int {
+	if c := a.Time.Compare(b.Time); c != 0 {
+		return c
+	}
+	return bytes.Compare(a.ID[:], b.ID[:])
+}
+
+func fetcher(items []item) Fetch[item] {
+	return func(after Cursor, hasAfter, ascending bool, n int) ([]item, error) {
+		var rows []item
+		for _, it := range items {
+			c := compare(it.key, after)
+			if !hasAfter || (ascending && c > 0) || (!ascending && c < 0) {
+				rows = append(rows, it)
+			}
+		}
+		if !ascending {
+			slices.Reverse(rows)
+		}
+		if len(rows) > n {
+			rows = rows[:n]
+		}
+		return rows, nil
+	}
+}
+
+func fill(t *testing.T, items []item, req Request) Page[item] {
+	t.Helper()
+	if req.Batch == 0 {
+		req.Batch = req.Size + 1
+	}
+	if req.MaxBatches == 0 {
+		req.MaxBatches = 5
+	}
+	page, err := Fill(req, func(it item) Cursor { return it.key }, func(it item) bool { return it.skip }, fetcher(items))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+func numbers(page Page[item]) []int {
+	ns := []int{}
+	for _, it := range page.Items {
+		ns = append(ns, it.n)
+	}
+	return ns
+}
+
+func TestFillForwardThenBack(t *testing.T) {
+	for _, descending := range []bool{false, true} {
+		items := list(10)
+		var pages []Page[item]
+		page := fill(t, items, Request{Descending: descending, Size: 3})
+		if page.HasPrev {
+			t.Errorf("descending=%v: first page has a prev link", descending)
+		}
+		pages = append(pages, page)
+		for page.HasNext {
+			page = fill(t, items, Request{Descending: descending, Cursor: page.Next, HasCursor: true, Size: 3})
+			pages = append(pages, page)
+		}
+		var all []int
+		for _, p := range pages {
+			all = append(all, numbers(p)...)
+		}
+		want := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+		if descending {
+			slices.Reverse(want)
+		}
+		if !reflect.DeepEqual(all, want) {
+			t.Fatalf("descending=%v: paging forward got %v, want %v", descending, all, want)
+		}
+
+		for i := len(pages) - 1; i > 0; i-- {
+			if !pages[i].HasPrev {
+				t.Fatalf("descending=%v: page %d has no prev link", descending, i)
+			}
+			back := fill(t, items, Request{Descending: descending, Cursor: pages[i].Prev, HasCursor: true, Before: true, Size: 3})
+			if got, want := numbers(back), numbers(pages[i-1]); !reflect.DeepEqual(got, want) {
+				t.Errorf("descending=%v: going back from page %d got %v, want %v", descending, i, got, want)
+			}
+		}
+	}
+}
+
+func TestFillBackThenForward(t *testing.T) {
+	items := list(10)
+	page := fill(t, items, Request{Cursor: items[9].key, HasCursor: true, Before: true, Size: 3})
+	if got, want := numbers(page), []int{6, 7, 8}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("page before 9 = %v, want %v", got, want)
+	}
+	if !page.HasNext || !page.HasPrev {
+		t.Fatalf("page before 9 has next %v and prev %v, want both", page.HasNext, page.HasPrev)
+	}
+	next := fill(t, items, Request{Cursor: page.Next, HasCursor: true, Size: 3})
+	if got, want := numbers(next), []int{9}; !reflect.DeepEqual(got, want) {
+		t.Errorf("next page = %v, want %v", got, want)
+	}
+	prev := fill(t, items, Request{Cursor: page.Prev, HasCursor: true, Before: true, Size: 3})
+	if got, want := numbers(prev), []int{3, 4, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("prev page = %v, want %v", got, want)
+	}
+	again := fill(t, items, Request{Cursor: prev.Next, HasCursor: true, Size: 3})
+	if got, want := numbers(again), []int{6, 7, 8}; !reflect.DeepEqual(got, want) {
+		t.Errorf("forward again = %v, want %v", got, want)
+	}
+}
+
+func TestFillPinnedFirstPage(t *testing.T) {
+	// Pinned items are skipped in the list and take room on the first page.
+	for _, descending := range []bool{false, true} {
+		items := list(6, 1, 3)
+		for _, size := range []int{0, 1} {
+			first := fill(t, items, Request{Descending: descending, Size: size, Batch: 4})
+			if len(first.Items) != size || !first.HasNext || first.HasPrev {
+				t.Fatalf("descending=%v size=%d: first page %v, next %v, prev %v", descending, size, numbers(first), first.HasNext, first.HasPrev)
+			}
+			rest := fill(t, items, Request{Descending: descending, Cursor: first.Next, HasCursor: true, Size: 4})
+			got := append(numbers(first), numbers(rest)...)
+			want := []int{0, 2, 4, 5}
+			if descending {
+				want = []int{5, 4, 2, 0}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("descending=%v size=%d: got %v, want %v", descending, size, got, want)
+			}
+		}
+	}
+}
+
+func TestFillSkipsMuted(t *testing.T) {
+	items := list(10, 1, 2, 3)
+	page := fill(t, items, Request{Size: 2})
+	if got, want := numbers(page), []int{0, 4}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("first page = %v, want %v", got, want)
+	}
+	next := fill(t, items, Request{Cursor: page.Next, HasCursor: true, Size: 2})
+	if got, want := numbers(next), []int{5, 6}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("second page = %v, want %v", got, want)
+	}
+	back := fill(t, items, Request{Cursor: next.Prev, HasCursor: true, Before: true, Size: 2})
+	if got, want := numbers(back), []int{0, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("back from second page = %v, want %v", got, want)
+	}
+
+	// Out of batches, the page is short but says there is more.
+	short := fill(t, items, Request{Size: 2, MaxBatches: 1})
+	if got, want := numbers(short), []int{0}; !reflect.DeepEqual(got, want) || !short.HasNext {
+		t.Fatalf("short page = %v with next %v, want %v with next", got, short.HasNext, want)
+	}
+	rest := fill(t, items, Request{Cursor: short.Next, HasCursor: true, Size: 2})
+	if got, want := numbers(rest), []int{4, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("page after short page = %v, want %v", got, want)
+	}
+}
+
+func TestFillEmptyPageLinks(t *testing.T) {
+	items := list(4)
+	// Nothing after the last item: going back still includes it.
+	page := fill(t, items, Request{Cursor: items[3].key, HasCursor: true, Size: 2})
+	if len(page.Items) != 0 || page.HasNext || !page.HasPrev {
+		t.Fatalf("page after the end = %v, next %v, prev %v", numbers(page), page.HasNext, page.HasPrev)
+	}
+	back := fill(t, items, Request{Cursor: page.Prev, HasCursor: true, Before: true, Size: 2})
+	if got, want := numbers(back), []int{2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("back from the end = %v, want %v", got, want)
+	}
+}
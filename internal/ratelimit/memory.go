@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many calls to Take happen between sweeps of idle
+// buckets.
+const sweepEvery = 1024
+
+// MemoryStore keeps buckets in memory. It is only suitable for a single
+// instance, since each process has its own buckets.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]memoryBucket
+	calls   int
+}
+
+type memoryBucket struct {
+	Bucket
+	// full is when the bucket will be full again, after which it is the same
+	// as a missing bucket and can be dropped.
+	full time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]memoryBucket)}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.calls%sweepEvery == 0 {
+		for k, b := range s.buckets {
+			if !now.Before(b.full) {
+				delete(s.buckets, k)
+			}
+		}
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b.Bucket = NewBucket(limit, now)
+	}
+	bucket, result := b.Take(limit, now)
+	s.buckets[key] = memoryBucket{Bucket: bucket, full: now.Add(result.ResetAfter)}
+	return result, nil
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so that every
+// instance shares them. Each Take locks the bucket's row for the length of a
+// short transaction.
+type PostgresStore struct {
+	db      *sql.DB
+	queries *database.Queries
+}
+
+func NewPostgresStore(db *sql.DB, queries *database.Queries) *PostgresStore {
+	return &PostgresStore{db: db, queries: queries}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback()
+	qtx := s.queries.WithTx(tx)
+
+	full := NewBucket(limit, now)
+	err = qtx.CreateRateLimitBucket(ctx, database.CreateRateLimitBucketParams{
+		Key:       key,
+		Tokens:    full.Tokens,
+		UpdatedAt: full.UpdatedAt,
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	row, err := qtx.GetRateLimitBucketForUpdate(ctx, key)
+	if err != nil {
+		return Result{}, err
+	}
+	bucket, result := Bucket{Tokens: row.Tokens, UpdatedAt: row.UpdatedAt}.Take(limit, now)
+	err = qtx.UpdateRateLimitBucket(ctx, database.UpdateRateLimitBucketParams{
+		Key:       key,
+		Tokens:    bucket.Tokens,
+		UpdatedAt: bucket.UpdatedAt,
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	return result, tx.Commit()
+}
+
+// DeleteStale removes buckets that have not been used since before cutoff.
+// Callers should pass a cutoff older than the longest limit period, so that
+// only buckets that would be full anyway are removed.
+func (s *PostgresStore) DeleteStale(ctx context.Context, cutoff time.Time) (int64, error) {
+	return s.queries.DeleteStaleRateLimitBuckets(ctx, cutoff)
+}
@@ -0,0 +1,77 @@
+// Package ratelimit implements token bucket rate limiting with pluggable
+// storage for the buckets.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit describes a token bucket. A full bucket holds Burst tokens and it
+// refills at Burst tokens per Period, so a client can make Burst requests at
+// once and then Burst requests per Period after that.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+func (l Limit) perToken() time.Duration {
+	return l.Period / time.Duration(l.Burst)
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until a token is available. It is zero when the
+	// request was allowed.
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again.
+	ResetAfter time.Duration
+}
+
+// Store keeps buckets and takes tokens from them. Implementations must make
+// Take atomic for a given key.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// Bucket is the stored state of a token bucket.
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// NewBucket returns a full bucket for limit.
+func NewBucket(limit Limit, now time.Time) Bucket {
+	return Bucket{Tokens: float64(limit.Burst), UpdatedAt: now}
+}
+
+// Take refills the bucket for the time since it was last updated and tries
+// to take one token from it. It returns the new state of the bucket, which
+// stores should save whether or not the request was allowed.
+func (b Bucket) Take(limit Limit, now time.Time) (Bucket, Result) {
+	elapsed := now.Sub(b.UpdatedAt)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	capacity := float64(limit.Burst)
+	tokens := math.Min(capacity, b.Tokens+elapsed.Seconds()/limit.perToken().Seconds())
+
+	result := Result{Limit: limit.Burst}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = durationForTokens(1-tokens, limit)
+	}
+	result.Remaining = int(math.Floor(tokens))
+	result.ResetAfter = durationForTokens(capacity-tokens, limit)
+	return Bucket{Tokens: tokens, UpdatedAt: now}, result
+}
+
+func durationForTokens(tokens float64, limit Limit) time.Duration {
+	return time.Duration(math.Ceil(tokens * float64(limit.perToken())))
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBucketAllowsBurstThenRejects(t *testing.T) {
+	limit := Limit{Burst: 3, Period: 3 * time.Second}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBucket(limit, now)
+
+	for i := 2; i >= 0; i-- {
+		var result Result
+		b, result = b.Take(limit, now)
+		if !result.Allowed {
+			t.Fatalf("request %d should be allowed", 3-i)
+		}
+		if result.Remaining != i {
+			t.Errorf("Remaining = %d, want %d", result.Remaining, i)
+		}
+	}
+
+	b, result := b.Take(limit, now)
+	if result.Allowed {
+		t.Fatal("request over the burst should be rejected")
+	}
+	if result.RetryAfter != time.Second {
+		t.Errorf("RetryAfter = %v, want 1s", result.RetryAfter)
+	}
+	if result.ResetAfter != 3*time.Second {
+		t.Errorf("ResetAfter = %v, want 3s", result.ResetAfter)
+	}
+
+	_, result = b.Take(limit, now.Add(time.Second))
+	if !result.Allowed {
+		t.Error("a token should have refilled after a second")
+	}
+}
+
+func TestBucketRefillIsCapped(t *testing.T) {
+	limit := Limit{Burst: 2, Period: time.Minute}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBucket(limit, now)
+	b, _ = b.Take(limit, now)
+
+	_, result := b.Take(limit, now.Add(time.Hour))
+	if result.Remaining != 1 {
+		t.Errorf("Remaining = %d, want 1", result.Remaining)
+	}
+}
+
+func TestMemoryStoreKeysAreIndependent(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Burst: 1, Period: time.Minute}
+	now := time.Now()
+	ctx := context.Background()
+
+	result, err := store.Take(ctx, "a", limit, now)
+	if err != nil || !result.Allowed {
+		t.Fatalf("first request for a: %+v, %v", result, err)
+	}
+	result, _ = store.Take(ctx, "a", limit, now)
+	if result.Allowed {
+		t.Error("second request for a should be rejected")
+	}
+	result, _ = store.Take(ctx, "b", limit, now)
+	if !result.Allowed {
+		t.Error("first request for b should be allowed")
+	}
+}
@@ -0,0 +1,231 @@
+// Package search parses what users type into the search box. Chirp queries
+// are free text with a few operators mixed in; the text is left for
+// Postgres's websearch_to_tsquery, which already understands "quoted
+// phrases", -excluded words and OR.
+package search
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// The markers Postgres's ts_headline puts around matched words in snippets.
+// They are private-use characters, which do not turn up in chirps, so they
+// survive HTML escaping and are then swapped for tags by Highlight.
+const (
+	StartSel = "\ue000"
+	StopSel  = "\ue001"
+)
+
+var (
+	ErrEmpty        = errors.New("search query is empty")
+	handlePattern   = regexp.MustCompile(`^[A-Za-z0-9_]{3,15}$`)
+	tagPattern      = regexp.MustCompile(`^[A-Za-z0-9_]{1,50}$`)
+	dateOnlyPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+// Query is a parsed chirp search. Text is what remains once the operators
+// are taken out, and hashtags are kept in it too so the full-text index can
+// narrow the search before the exact tag check. From and Tags are lowercase
+// and match any of the handles but all of the tags. Since and Until are zero
+// when not given; Until is exclusive.
+type Query struct {
+	Text  string
+	From  []string
+	Tags  []string
+	Since time.Time
+	Until time.Time
+}
+
+// Parse reads a chirp search query. Besides free text it understands:
+//
+//	from:handle   chirps by handle (the @ is optional)
+//	#tag          chirps containing the hashtag
+//	since:date    chirps posted on or after date
+//	until:date    chirps posted before the end of date
+//
+// Dates are YYYY-MM-DD in UTC, or RFC 3339 timestamps. Operator names are
+// not case-sensitive.
+func Parse(q string) (Query, error) {
+	var query Query
+	var text []string
+	for _, term := range splitTerms(q) {
+		if strings.HasPrefix(term, `"`) || strings.HasPrefix(term, `-"`) {
+			text = append(text, term)
+			continue
+		}
+		name, value, found := strings.Cut(term, ":")
+		if found {
+			switch strings.ToLower(name) {
+			case "from":
+				handle := strings.TrimPrefix(value, "@")
+				if !handlePattern.MatchString(handle) {
+					return Query{}, fmt.Errorf("invalid handle in %q", term)
+				}
+				query.From = append(query.From, strings.ToLower(handle))
+				continue
+			case "since":
+				t, err := parseTime(value, false)
+				if err != nil {
+					return Query{}, fmt.Errorf("invalid date in %q", term)
+				}
+				query.Since = t
+				continue
+			case "until":
+				t, err := parseTime(value, true)
+				if err != nil {
+					return Query{}, fmt.Errorf("invalid date in %q", term)
+				}
+				query.Until = t
+				continue
+			}
+		}
+		if tag, ok := strings.CutPrefix(term, "#"); ok && tagPattern.MatchString(tag) {
+			query.Tags = append(query.Tags, strings.ToLower(tag))
+			text = append(text, tag)
+			continue
+		}
+		text = append(text, term)
+	}
+	query.Text = strings.Join(text, " ")
+
+	if query.Text == "" && len(query.From) == 0 && query.Since.IsZero() && query.Until.IsZero() {
+		return Query{}, ErrEmpty
+	}
+	if !query.Since.IsZero() && !query.Until.IsZero() && !query.Since.Before(query.Until) {
+		return Query{}, errors.New("since must be before until")
+	}
+	return query, nil
+}
+
+// TagPatterns returns a Postgres regular expression for each tag, matching
+// the hashtag as a whole word, to be matched case-insensitively.
+func (q Query) TagPatterns() []string {
+	patterns := make([]string, 0, len(q.Tags))
+	for _, tag := range q.Tags {
+		patterns = append(patterns, `(^|[^A-Za-z0-9_])#`+tag+`($|[^A-Za-z0-9_])`)
+	}
+	return patterns
+}
+
+// splitTerms splits q on white space, keeping quoted phrases, with their
+// quotes and any leading minus, as single terms. An unclosed quote runs to
+// the end of q.
+func splitTerms(q string) []string {
+	var terms []string
+	var cur strings.Builder
+	quoted := false
+	flush := func() {
+		// Empty phrases are dropped along with their quotes.
+		if s := cur.String(); s == `""` || s == `-""` {
+			cur.Reset()
+		}
+		if cur.Len() > 0 {
+			terms = append(terms, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range q {
+		switch {
+		case r == '"' && quoted:
+			cur.WriteRune(r)
+			quoted = false
+			flush()
+		case r == '"' && (cur.Len() == 0 || cur.String() == "-"):
+			cur.WriteRune(r)
+			quoted = true
+		case r == '"':
+			// A quote inside a word means nothing to websearch_to_tsquery.
+		case unicode.IsSpace(r) && !quoted:
+			flush()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if quoted {
+		cur.WriteRune('"')
+	}
+	flush()
+	return terms
+}
+
+// parseTime reads a date or timestamp. A plain date stands for the start of
+// the day, or with endOfDay, the start of the next.
+func parseTime(s string, endOfDay bool) (time.Time, error) {
+	if dateOnlyPattern.MatchString(s) {
+		t, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+// Highlight HTML-escapes a snippet from ts_headline and wraps the matched
+// words in <mark> tags.
+func Highlight(snippet string) string {
+	var b strings.Builder
+	open := false
+	for _, r := range html.EscapeString(snippet) {
+		switch {
+		case string(r) == StartSel && !open:
+			b.WriteString("<mark>")
+			open = true
+		case string(r) == StopSel && open:
+			b.WriteString("</mark>")
+			open = false
+		case string(r) == StartSel || string(r) == StopSel:
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if open {
+		b.WriteString("</mark>")
+	}
+	return b.String()
+}
+
+// UserQuery is a parsed user search. Handle is the query as a lowercase
+// handle, or empty if it cannot be one, and HandlePattern is a LIKE pattern
+// for handles starting with it. Words is a to_tsquery expression matching
+// handles and display names with words starting with each word of the query.
+type UserQuery struct {
+	Handle        string
+	HandlePattern string
+	Words         string
+}
+
+// ParseUserQuery reads a user search query.
+func ParseUserQuery(q string) (UserQuery, error) {
+	var query UserQuery
+	q = strings.TrimSpace(q)
+	handle := strings.ToLower(strings.TrimPrefix(q, "@"))
+	if handle != "" && strings.Trim(handle, "abcdefghijklmnopqrstuvwxyz0123456789_") == "" && len(handle) <= 15 {
+		query.Handle = handle
+		query.HandlePattern = strings.ReplaceAll(handle, "_", `\_`) + "%"
+	}
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	query.Words = strings.Join(words, " & ")
+	if query.Handle == "" && query.Words == "" {
+		return UserQuery{}, ErrEmpty
+	}
+	return query, nil
+}
@@ -0,0 +1,116 @@
+package search
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		q    string
+		want Query
+	}{
+		{"go generics", Query{Text: "go generics"}},
+		{`"hello world"  -spam`, Query{Text: `"hello world" -spam`}},
+		{`say "unclosed phrase`, Query{Text: `say "unclosed phrase"`}},
+		{"from:@Alice from:bob_2 coffee", Query{Text: "coffee", From: []string{"alice", "bob_2"}}},
+		{"#GoLang release", Query{Text: "GoLang release", Tags: []string{"golang"}}},
+		{"# #not-a-tag", Query{Text: "# #not-a-tag"}},
+		{"https://example.com", Query{Text: "https://example.com"}},
+		{
+			"SINCE:2026-01-02 until:2026-01-31",
+			Query{
+				Since: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+				Until: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			"since:2026-01-02T10:00:00+02:00 news",
+			Query{Text: "news", Since: time.Date(2026, 1, 2, 8, 0, 0, 0, time.UTC)},
+		},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.q)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.q, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, q := range []string{
+		"from:",
+		"from:a",
+		"since:yesterday",
+		"until:2026-13-01",
+		"since:2026-02-01 until:2026-01-01",
+	} {
+		if _, err := Parse(q); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", q)
+		}
+	}
+	if _, err := Parse(`  "" `); !errors.Is(err, ErrEmpty) {
+		t.Errorf("Parse of an empty query = %v, want ErrEmpty", err)
+	}
+}
+
+func TestTagPatterns(t *testing.T) {
+	q, err := Parse("#go #chirpy_dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`(^|[^A-Za-z0-9_])#go($|[^A-Za-z0-9_])`,
+		`(^|[^A-Za-z0-9_])#chirpy_dev($|[^A-Za-z0-9_])`,
+	}
+	if got := q.TagPatterns(); !reflect.DeepEqual(got, want) {
+		t.Errorf("TagPatterns() = %v, want %v", got, want)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		snippet string
+		want    string
+	}{
+		{"plain text", "plain text"},
+		{"I <3 " + StartSel + "Go" + StopSel + " & tea", "I &lt;3 <mark>Go</mark> &amp; tea"},
+		{StartSel + "unclosed", "<mark>unclosed</mark>"},
+		{"stray" + StopSel + " marker", "stray marker"},
+	}
+	for _, tt := range tests {
+		if got := Highlight(tt.snippet); got != tt.want {
+			t.Errorf("Highlight(%q) = %q, want %q", tt.snippet, got, tt.want)
+		}
+	}
+}
+
+func TestParseUserQuery(t *testing.T) {
+	tests := []struct {
+		q    string
+		want UserQuery
+	}{
+		{"@Ann_S", UserQuery{Handle: "ann_s", HandlePattern: `ann\_s%`, Words: "ann:* & s:*"}},
+		{"Ann Smith", UserQuery{Words: "ann:* & smith:*"}},
+		{"José", UserQuery{Words: "josé:*"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseUserQuery(tt.q)
+		if err != nil {
+			t.Errorf("ParseUserQuery(%q) error: %v", tt.q, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseUserQuery(%q) = %+v, want %+v", tt.q, got, tt.want)
+		}
+	}
+	if _, err := ParseUserQuery(" !? "); !errors.Is(err, ErrEmpty) {
+		t.Errorf("ParseUserQuery of punctuation = %v, want ErrEmpty", err)
+	}
+}
@@ -0,0 +1,204 @@
+// Package spam scores new chirps for signs of automated or repetitive
+// posting and decides whether they should be published, held for review or
+// rejected.
+package spam
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/textutil"
+)
+
+// Decision is what happens to a scored chirp.
+type Decision string
+
+const (
+	// Allow publishes the chirp.
+	Allow Decision = "allow"
+	// Hold saves the chirp hidden until a moderator reviews it.
+	Hold Decision = "hold"
+	// Reject refuses the chirp outright.
+	Reject Decision = "reject"
+)
+
+// Signals that can contribute to a score.
+const (
+	SignalDuplicate  = "duplicate"
+	SignalLinks      = "links"
+	SignalVelocity   = "velocity"
+	SignalNewAccount = "new_account"
+)
+
+const (
+	// NearDuplicateSimilarity is the Similarity at which two chirps count as
+	// the same text.
+	NearDuplicateSimilarity = 0.7
+	// VelocityWindow is how far back Input.RecentCount should count chirps.
+	VelocityWindow = 10 * time.Minute
+	// DuplicateWindow is how far back Input.Recent should go.
+	DuplicateWindow = 24 * time.Hour
+	// MaxRecent is how many recent chirps are worth comparing against.
+	MaxRecent = 50
+
+	duplicateScore    = 0.35
+	linkScore         = 0.1
+	linkDensityScore  = 0.3
+	maxLinkScore      = 0.5
+	velocityAllowance = 5
+	velocityScore     = 0.1
+	maxVelocityScore  = 0.5
+	newAccountAge     = time.Hour
+	newAccountScore   = 0.3
+	youngAccountAge   = 24 * time.Hour
+	youngAccountScore = 0.15
+	shingleSize       = 2
+	highLinkDensity   = 0.5
+	maxScore          = 1.0
+)
+
+// Thresholds are the scores at which a chirp is held or rejected.
+type Thresholds struct {
+	Hold   float64
+	Reject float64
+}
+
+// DefaultThresholds hold a chirp once two signals agree and reject it once
+// the evidence is overwhelming.
+var DefaultThresholds = Thresholds{Hold: 0.5, Reject: 0.9}
+
+// Validate reports whether the thresholds can be used.
+func (t Thresholds) Validate() error {
+	if t.Hold <= 0 || t.Reject <= 0 {
+		return errors.New("spam thresholds must be positive")
+	}
+	if t.Hold > t.Reject {
+		return fmt.Errorf("spam hold threshold %.2f is above reject threshold %.2f", t.Hold, t.Reject)
+	}
+	return nil
+}
+
+// Input is a chirp and what is known about its author's recent activity.
+type Input struct {
+	Body string
+	// Recent holds the bodies of the author's chirps from the last
+	// DuplicateWindow, newest first and at most MaxRecent of them.
+	Recent []string
+	// RecentCount is how many chirps the author posted in the last
+	// VelocityWindow.
+	RecentCount int
+	AccountAge  time.Duration
+}
+
+// Reason is one signal's contribution to a score.
+type Reason struct {
+	Signal string  `json:"signal"`
+	Score  float64 `json:"score"`
+	Detail string  `json:"detail"`
+}
+
+// Result is the outcome of scoring a chirp.
+type Result struct {
+	Score    float64
+	Decision Decision
+	Reasons  []Reason
+}
+
+var linkPattern = regexp.MustCompile(`(?i)(?:https?://|www\.)\S+`)
+
+// Score adds up the signals for in and decides what to do with it.
+func Score(in Input, thresholds Thresholds) Result {
+	result := Result{Reasons: []Reason{}}
+	add := func(signal string, score float64, detail string) {
+		if score <= 0 {
+			return
+		}
+		result.Score += score
+		result.Reasons = append(result.Reasons, Reason{Signal: signal, Score: score, Detail: detail})
+	}
+
+	body := shingles(in.Body)
+	duplicates := 0
+	for _, recent := range in.Recent {
+		if jaccard(body, shingles(recent)) >= NearDuplicateSimilarity {
+			duplicates++
+		}
+	}
+	add(SignalDuplicate, math.Min(maxScore, float64(duplicates)*duplicateScore),
+		fmt.Sprintf("%d near-duplicate chirps in the last %s", duplicates, DuplicateWindow))
+
+	links := len(linkPattern.FindAllString(in.Body, -1))
+	if links > 0 {
+		words := len(textutil.Words(linkPattern.ReplaceAllString(in.Body, " ")))
+		density := float64(links) / float64(links+words)
+		score := float64(links-1) * linkScore
+		if density >= highLinkDensity {
+			score += linkDensityScore
+		}
+		add(SignalLinks, math.Min(maxLinkScore, score),
+			fmt.Sprintf("%d links, %.0f%% of the chirp", links, density*100))
+	}
+
+	if extra := in.RecentCount - velocityAllowance; extra > 0 {
+		add(SignalVelocity, math.Min(maxVelocityScore, float64(extra)*velocityScore),
+			fmt.Sprintf("%d chirps in the last %s", in.RecentCount, VelocityWindow))
+	}
+
+	switch {
+	case in.AccountAge < newAccountAge:
+		add(SignalNewAccount, newAccountScore, "account is less than an hour old")
+	case in.AccountAge < youngAccountAge:
+		add(SignalNewAccount, youngAccountScore, "account is less than a day old")
+	}
+
+	result.Score = math.Min(maxScore, result.Score)
+	switch {
+	case result.Score >= thresholds.Reject:
+		result.Decision = Reject
+	case result.Score >= thresholds.Hold:
+		result.Decision = Hold
+	default:
+		result.Decision = Allow
+	}
+	return result
+}
+
+// Similarity is the Jaccard similarity of the word shingles of a and b: the
+// number of shingles they share over the number in either. Case, accents and
+// punctuation are ignored, so light edits to a repeated chirp still score
+// close to 1.
+func Similarity(a, b string) float64 {
+	return jaccard(shingles(a), shingles(b))
+}
+
+func jaccard(sa, sb map[string]struct{}) float64 {
+	if len(sa) == 0 || len(sb) == 0 {
+		return 0
+	}
+	shared := 0
+	for shingle := range sa {
+		if _, ok := sb[shingle]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(sa)+len(sb)-shared)
+}
+
+// shingles returns the runs of shingleSize consecutive words in text, or the
+// whole text as one shingle if it is shorter than that.
+func shingles(text string) map[string]struct{} {
+	words := textutil.Words(text)
+	set := make(map[string]struct{})
+	if len(words) == 0 {
+		return set
+	}
+	size := min(shingleSize, len(words))
+	for i := 0; i+size <= len(words); i++ {
+		set[strings.Join(words[i:i+size], " ")] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,103 @@
+package spam
+
+import (
+	"testing"
+	"time"
+)
+
+const established = 30 * 24 * time.Hour
+
+func TestSimilarity(t *testing.T) {
+	original := "Check out my new podcast episode about sourdough baking and the science of wild yeast starters tonight"
+	tests := []struct {
+		name string
+		text string
+		near bool
+	}{
+		{"identical", original, true},
+		{"case and punctuation", "CHECK out my new podcast episode about sourdough baking, and the science of wild yeast starters tonight!!", true},
+		{"one word changed", "Check out my new podcast episode about sourdough baking and the science of wild yeast starters today", true},
+		{"one word added", "Check out my brand new podcast episode about sourdough baking and the science of wild yeast starters tonight", true},
+		{"unrelated", "The council meeting was moved to Thursday because of the storm warning for the coast", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		got := Similarity(original, tt.text) >= NearDuplicateSimilarity
+		if got != tt.near {
+			t.Errorf("%s: Similarity = %.2f, near duplicate = %v, want %v", tt.name, Similarity(original, tt.text), got, tt.near)
+		}
+	}
+}
+
+func TestScoreAllowsOrdinaryChirp(t *testing.T) {
+	result := Score(Input{
+		Body:        "Had a lovely walk along the river this morning",
+		Recent:      []string{"Lunch was great today", "Anyone watching the match tonight?"},
+		RecentCount: 2,
+		AccountAge:  established,
+	}, DefaultThresholds)
+	if result.Decision != Allow || result.Score != 0 || len(result.Reasons) != 0 {
+		t.Errorf("Score() = %+v, want an allow with no reasons", result)
+	}
+}
+
+func TestScoreRepeatedBody(t *testing.T) {
+	body := "Buy followers cheap, best prices on the whole internet, message me"
+	tests := []struct {
+		copies int
+		want   Decision
+	}{
+		{1, Allow},
+		{2, Hold},
+		{3, Reject},
+	}
+	for _, tt := range tests {
+		recent := make([]string, tt.copies)
+		for i := range recent {
+			recent[i] = body
+		}
+		result := Score(Input{Body: body, Recent: recent, AccountAge: established}, DefaultThresholds)
+		if result.Decision != tt.want {
+			t.Errorf("%d earlier copies: Decision = %s (score %.2f), want %s", tt.copies, result.Decision, result.Score, tt.want)
+		}
+		if result.Reasons[0].Signal != SignalDuplicate {
+			t.Errorf("%d earlier copies: Reasons = %+v, want a duplicate reason", tt.copies, result.Reasons)
+		}
+	}
+}
+
+func TestScoreCombinesSignals(t *testing.T) {
+	result := Score(Input{
+		Body:       "https://example.com/a https://example.com/b deals",
+		AccountAge: 10 * time.Minute,
+	}, DefaultThresholds)
+	if result.Decision != Hold {
+		t.Fatalf("Decision = %s (score %.2f), want hold", result.Decision, result.Score)
+	}
+	signals := map[string]bool{}
+	for _, reason := range result.Reasons {
+		signals[reason.Signal] = true
+	}
+	if !signals[SignalLinks] || !signals[SignalNewAccount] {
+		t.Errorf("Reasons = %+v, want links and new_account", result.Reasons)
+	}
+}
+
+func TestScoreVelocity(t *testing.T) {
+	result := Score(Input{Body: "hello", RecentCount: 8, AccountAge: established}, DefaultThresholds)
+	if len(result.Reasons) != 1 || result.Reasons[0].Signal != SignalVelocity {
+		t.Fatalf("Reasons = %+v, want one velocity reason", result.Reasons)
+	}
+	if result.Score < 0.29 || result.Score > 0.31 {
+		t.Errorf("Score = %.2f, want 0.3", result.Score)
+	}
+}
+
+func TestThresholdsValidate(t *testing.T) {
+	if err := DefaultThresholds.Validate(); err != nil {
+		t.Errorf("DefaultThresholds.Validate() = %v", err)
+	}
+	if err := (Thresholds{Hold: 0.8, Reject: 0.5}).Validate(); err == nil {
+		t.Error("hold above reject should be invalid")
+	}
+}
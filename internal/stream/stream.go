@@ -0,0 +1,243 @@
+// Package stream fans messages out to many streaming clients and writes them
+// in the Server-Sent Events format. Publishing never waits on a client: one
+// that falls too far behind is disconnected, and is expected to reconnect
+// with Last-Event-ID and be sent what it missed.
+package stream
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// Message is one event for clients. ID is sent as the event ID, which clients
+// send back in Last-Event-ID when they reconnect; IDs increase. Private
+// messages are only for the user they concern; the broker delivers them like
+// any other, and it is up to subscribers to drop them for anyone else.
+type Message struct {
+	ID      int64
+	Event   string
+	Data    []byte
+	Private bool
+}
+
+// Subscription receives messages published to any of its topics. C is
+// closed when the subscription ends, either because the broker closed or
+// because the subscriber fell behind, in which case Lagged reports true.
+type Subscription struct {
+	C      <-chan Message
+	ch     chan Message
+	topics map[string]bool
+	ended  bool
+	lagged bool
+}
+
+// Lagged reports whether the subscription was ended for falling behind. It
+// is only meaningful once C has been closed.
+func (s *Subscription) Lagged() bool {
+	return s.lagged
+}
+
+// Broker delivers published messages to subscribers by topic.
+type Broker struct {
+	mu     sync.Mutex
+	buffer int
+	subs   map[*Subscription]bool
+	topics map[string]map[*Subscription]bool
+	closed bool
+}
+
+// NewBroker returns a broker that holds up to buffer unsent messages for each
+// subscriber.
+func NewBroker(buffer int) *Broker {
+	return &Broker{
+		buffer: buffer,
+		subs:   map[*Subscription]bool{},
+		topics: map[string]map[*Subscription]bool{},
+	}
+}
+
+// Subscribe returns a subscription to topics. On a closed broker the
+// subscription's channel is already closed.
+func (b *Broker) Subscribe(topics ...string) *Subscription {
+	ch := make(chan Message, b.buffer)
+	s := &Subscription{C: ch, ch: ch, topics: map[string]bool{}}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		s.ended = true
+		close(ch)
+		return s
+	}
+	b.subs[s] = true
+	b.join(s, topics)
+	return s
+}
+
+// Join adds topics to a live subscription. It does nothing once s has ended.
+func (b *Broker) Join(s *Subscription, topics ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s.ended {
+		return
+	}
+	b.join(s, topics)
+}
+
+// Leave removes topics from s without ending it.
+func (b *Broker) Leave(s *Subscription, topics ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, topic := range topics {
+		b.leave(s, topic)
+	}
+}
+
+// Unsubscribe ends s. It is safe to call more than once.
+func (b *Broker) Unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(s)
+}
+
+// Publish sends msg to every subscriber of any of topics, once each. A
+// subscriber whose buffer is full is ended instead.
+func (b *Broker) Publish(msg Message, topics ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sent := map[*Subscription]bool{}
+	for _, topic := range topics {
+		for s := range b.topics[topic] {
+			if sent[s] {
+				continue
+			}
+			sent[s] = true
+			select {
+			case s.ch <- msg:
+			default:
+				s.lagged = true
+				b.remove(s)
+			}
+		}
+	}
+}
+
+// Close ends every subscription and refuses new ones.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for s := range b.subs {
+		b.remove(s)
+	}
+}
+
+// join, leave and remove must be called with b.mu held.
+func (b *Broker) join(s *Subscription, topics []string) {
+	for _, topic := range topics {
+		subs, ok := b.topics[topic]
+		if !ok {
+			subs = map[*Subscription]bool{}
+			b.topics[topic] = subs
+		}
+		subs[s] = true
+		s.topics[topic] = true
+	}
+}
+
+func (b *Broker) leave(s *Subscription, topic string) {
+	delete(s.topics, topic)
+	subs := b.topics[topic]
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(b.topics, topic)
+	}
+}
+
+func (b *Broker) remove(s *Subscription) {
+	if s.ended {
+		return
+	}
+	s.ended = true
+	for topic := range s.topics {
+		b.leave(s, topic)
+	}
+	delete(b.subs, s)
+	close(s.ch)
+}
+
+// Writer writes an event stream to an HTTP response, flushing after each
+// write.
+type Writer struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// NewWriter sets the event stream headers and sends them.
+func NewWriter(w http.ResponseWriter) *Writer {
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	// Stop proxies such as nginx from buffering the stream.
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	sw := &Writer{w: w, rc: http.NewResponseController(w)}
+	sw.rc.Flush()
+	return sw
+}
+
+// Retry tells the client how long to wait, in milliseconds, before
+// reconnecting.
+func (sw *Writer) Retry(ms int) error {
+	return sw.write([]byte("retry: " + strconv.Itoa(ms) + "\n\n"))
+}
+
+// Send writes msg as one event. IDs of zero are left out.
+func (sw *Writer) Send(msg Message) error {
+	var buf bytes.Buffer
+	if msg.ID != 0 {
+		fmt.Fprintf(&buf, "id: %d\n", msg.ID)
+	}
+	if msg.Event != "" {
+		fmt.Fprintf(&buf, "event: %s\n", msg.Event)
+	}
+	for _, line := range bytes.Split(msg.Data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(bytes.TrimSuffix(line, []byte("\r")))
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	return sw.write(buf.Bytes())
+}
+
+// Heartbeat writes a comment, which clients ignore, to keep idle connections
+// and the proxies in front of them from timing out.
+func (sw *Writer) Heartbeat() error {
+	return sw.write([]byte(": heartbeat\n\n"))
+}
+
+func (sw *Writer) write(b []byte) error {
+	_, err := sw.w.Write(b)
+	if err != nil {
+		return err
+	}
+	return sw.rc.Flush()
+}
+
+// LastEventID reads the ID a reconnecting client last received, from the
+// Last-Event-ID header or, for clients that cannot set headers on a fresh
+// connection, the last_event_id query parameter. It is 0 if there is none.
+func LastEventID(r *http.Request) int64 {
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("last_event_id")
+	}
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || id < 0 {
+		return 0
+	}
+	return id
+}
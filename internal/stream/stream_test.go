@@ -0,0 +1,123 @@
+package stream
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBrokerFansOutOncePerSubscriber(t *testing.T) {
+	b := NewBroker(4)
+	both := b.Subscribe("public", "author:a")
+	public := b.Subscribe("public")
+	other := b.Subscribe("author:b")
+
+	b.Publish(Message{ID: 1}, "public", "author:a")
+
+	if msg := <-both.C; msg.ID != 1 {
+		t.Errorf("got ID %d, want 1", msg.ID)
+	}
+	if len(both.C) != 0 {
+		t.Error("subscriber to both topics got the message twice")
+	}
+	if len(public.C) != 1 {
+		t.Error("public subscriber did not get the message")
+	}
+	if len(other.C) != 0 {
+		t.Error("subscriber to another topic got the message")
+	}
+}
+
+func TestBrokerEndsLaggingSubscribers(t *testing.T) {
+	b := NewBroker(1)
+	slow := b.Subscribe("public")
+	fast := b.Subscribe("public")
+
+	b.Publish(Message{ID: 1}, "public")
+	<-fast.C
+	b.Publish(Message{ID: 2}, "public")
+
+	if msg := <-fast.C; msg.ID != 2 {
+		t.Errorf("fast subscriber got ID %d, want 2", msg.ID)
+	}
+	<-slow.C
+	if _, ok := <-slow.C; ok {
+		t.Fatal("slow subscriber's channel should be closed")
+	}
+	if !slow.Lagged() {
+		t.Error("slow subscriber should be marked lagged")
+	}
+	// Ending a subscription twice must not panic.
+	b.Unsubscribe(slow)
+}
+
+func TestBrokerClose(t *testing.T) {
+	b := NewBroker(1)
+	s := b.Subscribe("public")
+	b.Close()
+	if _, ok := <-s.C; ok {
+		t.Error("subscription should be closed with the broker")
+	}
+	if s.Lagged() {
+		t.Error("closing the broker is not lagging")
+	}
+	if _, ok := <-b.Subscribe("public").C; ok {
+		t.Error("subscribing to a closed broker should return a closed channel")
+	}
+}
+
+func TestWriterSend(t *testing.T) {
+	rec := httptest.NewRecorder()
+	sw := NewWriter(rec)
+	err := sw.Send(Message{ID: 7, Event: "chirp.created", Data: []byte("line one\nline two")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "id: 7\nevent: chirp.created\ndata: line one\ndata: line two\n\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q", got)
+	}
+}
+
+func TestLastEventID(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/stream/chirps?last_event_id=12", nil)
+	if got := LastEventID(r); got != 12 {
+		t.Errorf("from query: got %d, want 12", got)
+	}
+	r.Header.Set("Last-Event-ID", "40")
+	if got := LastEventID(r); got != 40 {
+		t.Errorf("header should win: got %d, want 40", got)
+	}
+	r.Header.Set("Last-Event-ID", "nope")
+	if got := LastEventID(r); got != 0 {
+		t.Errorf("invalid ID: got %d, want 0", got)
+	}
+}
+
+func TestBrokerJoinAndLeave(t *testing.T) {
+	b := NewBroker(4)
+	s := b.Subscribe()
+
+	b.Join(s, "public", "author:a")
+	b.Publish(Message{ID: 1}, "author:a")
+	b.Leave(s, "author:a")
+	b.Publish(Message{ID: 2}, "author:a")
+	b.Publish(Message{ID: 3}, "public")
+
+	if msg := <-s.C; msg.ID != 1 {
+		t.Errorf("got ID %d, want 1", msg.ID)
+	}
+	if msg := <-s.C; msg.ID != 3 {
+		t.Errorf("got ID %d, want 3 after leaving author:a", msg.ID)
+	}
+
+	// A subscription with no topics left still ends with the broker.
+	b.Leave(s, "public")
+	b.Close()
+	if _, ok := <-s.C; ok {
+		t.Error("subscription should be closed with the broker")
+	}
+	b.Join(s, "public")
+}
@@ -0,0 +1,185 @@
+// Package subscription tracks the Chirpy Red subscription lifecycle: how
+// billing events move a subscription between states and whether its owner
+// is entitled to Chirpy Red at a given moment.
+package subscription
+
+import (
+	"errors"
+	"time"
+)
+
+// Status is where a subscription is in its lifecycle.
+type Status string
+
+const (
+	// Active subscriptions are paid up to PeriodEnd.
+	Active Status = "active"
+	// PastDue subscriptions had a renewal payment fail and keep their
+	// benefits until GraceUntil.
+	PastDue Status = "past_due"
+	// Cancelled subscriptions will not renew but keep their benefits until
+	// PeriodEnd.
+	Cancelled Status = "cancelled"
+	// Expired subscriptions have no benefits.
+	Expired Status = "expired"
+)
+
+// Event is a billing event that changes a subscription.
+type Event string
+
+const (
+	EventUpgraded      Event = "user.upgraded"
+	EventDowngraded    Event = "user.downgraded"
+	EventRenewed       Event = "subscription.renewed"
+	EventPaymentFailed Event = "payment.failed"
+	EventCancelled     Event = "subscription.cancelled"
+	// EventExpired is not sent by the billing provider. It is recorded when
+	// a lapsed subscription is found to have run out.
+	EventExpired Event = "subscription.expired"
+)
+
+const (
+	// DefaultPlan is the plan of subscriptions whose events do not name one.
+	DefaultPlan = "chirpy_red"
+	// DefaultGracePeriod is how long a subscriber keeps their benefits after
+	// a failed payment.
+	DefaultGracePeriod = 7 * 24 * time.Hour
+)
+
+var (
+	// ErrUnknownEvent is returned for events this package does not handle.
+	ErrUnknownEvent = errors.New("unknown subscription event")
+	// ErrNoSubscription is returned for events that only make sense for an
+	// existing subscription, such as a failed payment for a user who never
+	// subscribed.
+	ErrNoSubscription = errors.New("no subscription")
+)
+
+// State is a subscription. Zero times mean unset: a zero PeriodEnd is an
+// open-ended period, as created by upgrades that do not say when they end.
+type State struct {
+	Plan        string
+	Status      Status
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	GraceUntil  time.Time
+	CancelledAt time.Time
+}
+
+// Change carries the optional details of an event. Zero fields are filled in
+// from the current state.
+type Change struct {
+	Plan        string
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+}
+
+// Known reports whether ev is an event Apply understands.
+func Known(ev Event) bool {
+	switch ev {
+	case EventUpgraded, EventDowngraded, EventRenewed, EventPaymentFailed, EventCancelled:
+		return true
+	}
+	return false
+}
+
+// Apply returns the state after ev. exists is false if the user has no
+// subscription yet, in which case only upgrades and renewals create one.
+// A subscription whose benefits have run out by now comes back Expired.
+func Apply(s State, exists bool, ev Event, c Change, now time.Time, grace time.Duration) (State, error) {
+	if !Known(ev) {
+		return s, ErrUnknownEvent
+	}
+	if !exists {
+		if ev != EventUpgraded && ev != EventRenewed {
+			return s, ErrNoSubscription
+		}
+		s = State{Plan: DefaultPlan}
+	}
+	if c.Plan != "" {
+		s.Plan = c.Plan
+	}
+
+	switch ev {
+	case EventUpgraded:
+		s.Status = Active
+		s.PeriodStart = orDefault(c.PeriodStart, now)
+		s.PeriodEnd = c.PeriodEnd
+		s.GraceUntil = time.Time{}
+		s.CancelledAt = time.Time{}
+	case EventRenewed:
+		start := orDefault(c.PeriodStart, orDefault(s.PeriodEnd, now))
+		end := c.PeriodEnd
+		if end.IsZero() && !s.PeriodStart.IsZero() && !s.PeriodEnd.IsZero() {
+			// Without an explicit end, the new period is as long as the last.
+			end = start.Add(s.PeriodEnd.Sub(s.PeriodStart))
+		}
+		s.Status = Active
+		s.PeriodStart = start
+		s.PeriodEnd = end
+		s.GraceUntil = time.Time{}
+		s.CancelledAt = time.Time{}
+	case EventPaymentFailed:
+		if s.Status == Expired {
+			return s, nil
+		}
+		from := now
+		if s.PeriodEnd.After(now) {
+			from = s.PeriodEnd
+		}
+		s.Status = PastDue
+		s.GraceUntil = from.Add(grace)
+	case EventCancelled:
+		if s.Status == Expired {
+			return s, nil
+		}
+		s.Status = Cancelled
+		s.GraceUntil = time.Time{}
+		s.CancelledAt = now
+		if s.PeriodEnd.IsZero() {
+			// An open-ended period has no paid-up end to run to.
+			s.PeriodEnd = now
+		}
+	case EventDowngraded:
+		s.Status = Expired
+		s.GraceUntil = time.Time{}
+		if s.PeriodEnd.IsZero() || s.PeriodEnd.After(now) {
+			s.PeriodEnd = now
+		}
+	}
+	return Refresh(s, now), nil
+}
+
+// Refresh expires s if its benefits have run out by now.
+func Refresh(s State, now time.Time) State {
+	if s.Status != Expired && !Entitled(s, now) {
+		s.Status = Expired
+		s.GraceUntil = time.Time{}
+	}
+	return s
+}
+
+// Entitled reports whether s grants Chirpy Red at now.
+func Entitled(s State, now time.Time) bool {
+	if s.Status == Expired {
+		return false
+	}
+	until := s.AccessUntil()
+	return until.IsZero() || now.Before(until)
+}
+
+// AccessUntil is when the benefits of s run out, or the zero time if they do
+// not. It is meaningless for expired subscriptions.
+func (s State) AccessUntil() time.Time {
+	if s.Status == PastDue {
+		return s.GraceUntil
+	}
+	return s.PeriodEnd
+}
+
+func orDefault(t, def time.Time) time.Time {
+	if t.IsZero() {
+		return def
+	}
+	return t
+}
@@ -0,0 +1,124 @@
+package subscription
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var (
+	now   = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	month = 30 * 24 * time.Hour
+)
+
+func TestApplyUpgradeWithoutPeriodIsOpenEnded(t *testing.T) {
+	s, err := Apply(State{}, false, EventUpgraded, Change{}, now, DefaultGracePeriod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Status != Active || s.Plan != DefaultPlan || !s.PeriodEnd.IsZero() {
+		t.Fatalf("Apply() = %+v, want an open-ended active %s subscription", s, DefaultPlan)
+	}
+	if !Entitled(s, now.Add(10*365*24*time.Hour)) {
+		t.Error("open-ended subscription should stay entitled")
+	}
+}
+
+func TestApplyRequiresSubscription(t *testing.T) {
+	for _, ev := range []Event{EventDowngraded, EventPaymentFailed, EventCancelled} {
+		_, err := Apply(State{}, false, ev, Change{}, now, DefaultGracePeriod)
+		if !errors.Is(err, ErrNoSubscription) {
+			t.Errorf("%s without a subscription: err = %v, want ErrNoSubscription", ev, err)
+		}
+	}
+	_, err := Apply(State{}, false, "user.exploded", Change{}, now, DefaultGracePeriod)
+	if !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("unknown event: err = %v, want ErrUnknownEvent", err)
+	}
+}
+
+func TestApplyRenewalKeepsPeriodLength(t *testing.T) {
+	s := State{Plan: DefaultPlan, Status: Active, PeriodStart: now.Add(-month), PeriodEnd: now}
+	s, err := Apply(s, true, EventRenewed, Change{}, now, DefaultGracePeriod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.PeriodStart.Equal(now) || !s.PeriodEnd.Equal(now.Add(month)) {
+		t.Errorf("renewed period = %s to %s, want %s to %s", s.PeriodStart, s.PeriodEnd, now, now.Add(month))
+	}
+}
+
+func TestApplyPaymentFailedGracePeriod(t *testing.T) {
+	s := State{Plan: DefaultPlan, Status: Active, PeriodStart: now.Add(-month), PeriodEnd: now.Add(time.Hour)}
+	s, err := Apply(s, true, EventPaymentFailed, Change{}, now, DefaultGracePeriod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantGrace := now.Add(time.Hour + DefaultGracePeriod)
+	if s.Status != PastDue || !s.GraceUntil.Equal(wantGrace) {
+		t.Fatalf("Apply() = %+v, want past_due until %s", s, wantGrace)
+	}
+	if !Entitled(s, wantGrace.Add(-time.Second)) {
+		t.Error("should be entitled during the grace period")
+	}
+	if Entitled(s, wantGrace) {
+		t.Error("should not be entitled once the grace period ends")
+	}
+
+	s, err = Apply(s, true, EventRenewed, Change{}, now.Add(2*time.Hour), DefaultGracePeriod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Status != Active || !s.GraceUntil.IsZero() {
+		t.Errorf("renewal after a failed payment = %+v, want active with no grace", s)
+	}
+}
+
+func TestApplyCancelRunsToPeriodEnd(t *testing.T) {
+	end := now.Add(10 * 24 * time.Hour)
+	s := State{Plan: DefaultPlan, Status: Active, PeriodStart: now.Add(-month), PeriodEnd: end}
+	s, err := Apply(s, true, EventCancelled, Change{}, now, DefaultGracePeriod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Status != Cancelled || !s.CancelledAt.Equal(now) || !Entitled(s, now) {
+		t.Fatalf("Apply() = %+v, want an entitled cancelled subscription", s)
+	}
+	if got := Refresh(s, end); got.Status != Expired {
+		t.Errorf("Refresh at period end = %s, want expired", got.Status)
+	}
+
+	open := State{Plan: DefaultPlan, Status: Active, PeriodStart: now.Add(-month)}
+	open, err = Apply(open, true, EventCancelled, Change{}, now, DefaultGracePeriod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if open.Status != Expired {
+		t.Errorf("cancelling an open-ended subscription = %s, want expired", open.Status)
+	}
+}
+
+func TestApplyDowngradeExpiresImmediately(t *testing.T) {
+	s := State{Plan: DefaultPlan, Status: Active, PeriodStart: now.Add(-month), PeriodEnd: now.Add(month)}
+	s, err := Apply(s, true, EventDowngraded, Change{}, now, DefaultGracePeriod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Status != Expired || !s.PeriodEnd.Equal(now) || Entitled(s, now) {
+		t.Errorf("Apply() = %+v, want expired now", s)
+	}
+}
+
+func TestApplyLapsedUpgradeExpires(t *testing.T) {
+	s, err := Apply(State{}, false, EventUpgraded, Change{
+		Plan:        "chirpy_red_yearly",
+		PeriodStart: now.Add(-2 * month),
+		PeriodEnd:   now.Add(-month),
+	}, now, DefaultGracePeriod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Status != Expired || s.Plan != "chirpy_red_yearly" {
+		t.Errorf("Apply() = %+v, want an expired chirpy_red_yearly subscription", s)
+	}
+}
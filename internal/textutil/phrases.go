@@ -0,0 +1,60 @@
+package textutil
+
+import "strings"
+
+// PhraseSet matches phrases of one or more words against text. Like rules in
+// the content filter, phrases match whole words after normalization, so
+// "cat" matches "Cat!" but not "category", and "new york" matches "New
+// York's" but not "new yorker".
+type PhraseSet struct {
+	byFirst map[string][][]string
+}
+
+// NewPhraseSet returns a set of phrases. Phrases with no words are skipped.
+func NewPhraseSet(phrases []string) *PhraseSet {
+	s := &PhraseSet{byFirst: make(map[string][][]string)}
+	for _, phrase := range phrases {
+		words := Words(phrase)
+		if len(words) == 0 {
+			continue
+		}
+		s.byFirst[words[0]] = append(s.byFirst[words[0]], words)
+	}
+	return s
+}
+
+// Empty reports whether the set has no phrases.
+func (s *PhraseSet) Empty() bool {
+	return len(s.byFirst) == 0
+}
+
+// Contains reports whether any phrase in the set appears in text.
+func (s *PhraseSet) Contains(text string) bool {
+	if s.Empty() {
+		return false
+	}
+	words := Words(text)
+	for i, word := range words {
+		for _, phrase := range s.byFirst[word] {
+			if i+len(phrase) <= len(words) && equalWords(words[i:i+len(phrase)], phrase) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Key returns the normalized form of a phrase, for telling whether two
+// phrases are the same. It is empty if the phrase has no words.
+func Key(phrase string) string {
+	return strings.Join(Words(phrase), " ")
+}
+
+func equalWords(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
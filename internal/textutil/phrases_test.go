@@ -0,0 +1,37 @@
+package textutil
+
+import "testing"
+
+func TestPhraseSetContains(t *testing.T) {
+	set := NewPhraseSet([]string{"Cat", "new york", "  "})
+	tests := map[string]bool{
+		"my cat!":                 true,
+		"CAT":                     true,
+		"a category of things":    false,
+		"I love New York's parks": true,
+		"I love New York, truly":  true,
+		"new yorker magazine":     false,
+		"york new":                false,
+		"":                        false,
+	}
+	for text, want := range tests {
+		if got := set.Contains(text); got != want {
+			t.Errorf("Contains(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestPhraseSetEmpty(t *testing.T) {
+	if !NewPhraseSet(nil).Empty() || !NewPhraseSet([]string{"!!"}).Empty() {
+		t.Error("a set with no words should be empty")
+	}
+	if NewPhraseSet(nil).Contains("anything") {
+		t.Error("an empty set should not match")
+	}
+}
+
+func TestKey(t *testing.T) {
+	if got := Key("  New   YORK! "); got != "new york" {
+		t.Errorf("Key() = %q, want %q", got, "new york")
+	}
+}
@@ -0,0 +1,82 @@
+// Package textutil normalizes and tokenizes user-written text so that
+// features matching words against it agree on what a word is.
+package textutil
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Token is a word in a piece of text. Start and End are byte offsets of the
+// word in the original text, and Text is its normalized form.
+type Token struct {
+	Text  string
+	Start int
+	End   int
+}
+
+var folder = cases.Fold()
+
+// Normalize folds s to a canonical form for matching: compatibility
+// characters such as fullwidth letters become their plain equivalents, accents
+// and invisible formatting characters are dropped, and case is folded.
+func Normalize(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(s) {
+		if unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Cf, r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return folder.String(norm.NFC.String(b.String()))
+}
+
+// Tokenize splits s into words. A word is a run of letters and digits;
+// punctuation, spaces and symbols separate words. Combining marks and
+// invisible formatting characters such as zero-width spaces are kept inside a
+// word, so they cannot be used to split one.
+func Tokenize(s string) []Token {
+	var tokens []Token
+	start := -1
+	for i, r := range s {
+		if isWordRune(r) || (start >= 0 && (unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Cf, r))) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = appendToken(tokens, s, start, i)
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = appendToken(tokens, s, start, len(s))
+	}
+	return tokens
+}
+
+// Words returns the normalized words of s.
+func Words(s string) []string {
+	tokens := Tokenize(s)
+	words := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		words = append(words, token.Text)
+	}
+	return words
+}
+
+func appendToken(tokens []Token, s string, start, end int) []Token {
+	text := Normalize(s[start:end])
+	if text == "" {
+		return tokens
+	}
+	return append(tokens, Token{Text: text, Start: start, End: end})
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
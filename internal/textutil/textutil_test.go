@@ -0,0 +1,41 @@
+package textutil
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"Kerfuffle":      "kerfuffle",
+		"k\u00e9rfuffle": "kerfuffle",
+		"\uff2b\uff25\uff32\uff26\uff35\uff26\uff26\uff2c\uff25": "kerfuffle",
+		"ker\u200bfuffle": "kerfuffle",
+		"Straße":          "strasse",
+	}
+	for input, want := range tests {
+		if got := Normalize(input); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	input := "What a Kerfuffle! fornax, ok?"
+	tokens := Tokenize(input)
+	want := []string{"what", "a", "kerfuffle", "fornax", "ok"}
+	if got := Words(input); !slices.Equal(got, want) {
+		t.Fatalf("Words() = %q, want %q", got, want)
+	}
+	if got := input[tokens[2].Start:tokens[2].End]; got != "Kerfuffle" {
+		t.Errorf("token offsets point at %q, want %q", got, "Kerfuffle")
+	}
+}
+
+func TestTokenizeKeepsInvisibleCharactersInsideWords(t *testing.T) {
+	input := "a ker\u200bfuffle b"
+	want := []string{"a", "kerfuffle", "b"}
+	if got := Words(input); !slices.Equal(got, want) {
+		t.Errorf("Words() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,201 @@
+// Package webhooks sends Chirpy events to endpoints registered by
+// integrations. Each delivery is a JSON envelope signed the same way Polka
+// signs the webhooks Chirpy receives, so receivers can check it with
+// auth.VerifySignature.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/events"
+	"github.com/google/uuid"
+)
+
+// Event types an endpoint can subscribe to.
+const (
+	EventChirpCreated = events.ChirpCreated
+	EventChirpDeleted = events.ChirpDeleted
+	EventChirpEdited  = events.ChirpEdited
+	EventUserFollowed = events.UserFollowed
+	EventUserUpgraded = events.UserUpgraded
+)
+
+// EventTypes lists every event type, in the order they are documented.
+var EventTypes = []string{EventChirpCreated, EventChirpDeleted, EventChirpEdited, EventUserFollowed, EventUserUpgraded}
+
+// Headers sent with every delivery. SignatureHeader holds "v1=" and the hex
+// HMAC-SHA256 of the timestamp, a dot and the body.
+const (
+	SignatureHeader = "Chirpy-Signature"
+	TimestampHeader = "Chirpy-Timestamp"
+	EventHeader     = "Chirpy-Event"
+	DeliveryHeader  = "Chirpy-Delivery"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it is given
+	// up on.
+	MaxAttempts = 8
+
+	initialBackoff  = 30 * time.Second
+	maxBackoff      = 6 * time.Hour
+	maxResponseBody = 64 << 10
+)
+
+// ErrDisallowedAddress is returned for deliveries to addresses that are not
+// on the public internet.
+var ErrDisallowedAddress = errors.New("address is not publicly routable")
+
+// PublicAddr reports whether deliveries may go to ip: it must not be a
+// loopback, private, link-local, unspecified or multicast address.
+func PublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsValid() && !ip.IsLoopback() && !ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsUnspecified() && !ip.IsMulticast()
+}
+
+// ValidEventType reports whether eventType is one endpoints can subscribe to.
+func ValidEventType(eventType string) bool {
+	for _, known := range EventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Envelope is the body of every delivery. ID is the ID of the event and is
+// the same for every endpoint and every attempt, so receivers can use it to
+// ignore duplicates.
+type Envelope struct {
+	ID        uuid.UUID       `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// NewSecret returns a random signing secret for a new endpoint.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Backoff is how long to wait before retrying a delivery that has failed
+// attempts times: 30 seconds after the first failure, doubling each time up
+// to 6 hours.
+func Backoff(attempts int) time.Duration {
+	d := initialBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+// Delivery is one event on its way to one endpoint.
+type Delivery struct {
+	ID        uuid.UUID
+	EventType string
+	URL       string
+	Secret    string
+	Body      []byte
+}
+
+// Result is the outcome of one attempt. StatusCode is 0 if no response was
+// received.
+type Result struct {
+	StatusCode int
+	Duration   time.Duration
+	Err        error
+}
+
+// OK reports whether the endpoint accepted the delivery.
+func (r Result) OK() bool {
+	return r.Err == nil
+}
+
+// Sender posts deliveries.
+type Sender struct {
+	client *http.Client
+}
+
+// NewSender returns a Sender that gives up on an attempt after timeout.
+// Redirects are not followed: an endpoint that has moved should be
+// re-registered. Unless allowPrivate is set, connections to addresses that
+// fail PublicAddr are refused. The check is made on the address being
+// dialled, after DNS resolution, so a hostname cannot be pointed at an
+// internal service after the endpoint is registered.
+func NewSender(timeout time.Duration, allowPrivate bool) *Sender {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !PublicAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrDisallowedAddress, address)
+			}
+			return nil
+		}
+	}
+	return &Sender{client: &http.Client{
+		Timeout: timeout,
+		// No proxy: the dialler has to see the endpoint's own address.
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// Send makes one attempt at d, signed at now. Any 2xx response counts as
+// success. Only the status code of a failure is reported: endpoint owners
+// can read the errors back, so response bodies are never kept.
+func (s *Sender) Send(ctx context.Context, d Delivery, now time.Time) Result {
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Body))
+	if err != nil {
+		return Result{Err: err}
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks/1.0")
+	req.Header.Set(SignatureHeader, "v1="+auth.SignWebhook(d.Secret, timestamp, d.Body))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(EventHeader, d.EventType)
+	req.Header.Set(DeliveryHeader, d.ID.String())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Result{Duration: time.Since(start), Err: err}
+	}
+	defer resp.Body.Close()
+	// Drain some of the body so the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+	result := Result{StatusCode: resp.StatusCode, Duration: time.Since(start)}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		result.Err = fmt.Errorf("endpoint returned status %d", resp.StatusCode)
+	}
+	return result
+}
@@ -0,0 +1,158 @@
+package webhooks_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/webhooks"
+	"github.com/Throne-of-Doom/chirpy/internal/webhooks/webhookstest"
+	"github.com/google/uuid"
+)
+
+func newDelivery(t *testing.T, url, secret string) webhooks.Delivery {
+	t.Helper()
+	body, err := json.Marshal(webhooks.Envelope{
+		ID:        uuid.New(),
+		Type:      webhooks.EventChirpCreated,
+		CreatedAt: time.Now(),
+		Data:      json.RawMessage(`{"body":"hello"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return webhooks.Delivery{
+		ID:        uuid.New(),
+		EventType: webhooks.EventChirpCreated,
+		URL:       url,
+		Secret:    secret,
+		Body:      body,
+	}
+}
+
+func TestSendSignedDelivery(t *testing.T) {
+	receiver := webhookstest.NewReceiver("whsec_test")
+	defer receiver.Close()
+	sender := webhooks.NewSender(5*time.Second, true)
+
+	d := newDelivery(t, receiver.URL, "whsec_test")
+	result := sender.Send(context.Background(), d, time.Now())
+	if !result.OK() || result.StatusCode != 204 {
+		t.Fatalf("Send() = %+v, want a 204", result)
+	}
+	received := receiver.Received()
+	if len(received) != 1 {
+		t.Fatalf("receiver got %d deliveries, want 1", len(received))
+	}
+	if received[0].DeliveryID != d.ID.String() || received[0].Event != webhooks.EventChirpCreated {
+		t.Errorf("received %+v, want delivery %s of %s", received[0], d.ID, webhooks.EventChirpCreated)
+	}
+	if string(received[0].Envelope.Data) != `{"body":"hello"}` {
+		t.Errorf("data = %s", received[0].Envelope.Data)
+	}
+}
+
+func TestSendWrongSecret(t *testing.T) {
+	receiver := webhookstest.NewReceiver("whsec_test")
+	defer receiver.Close()
+	sender := webhooks.NewSender(5*time.Second, true)
+
+	result := sender.Send(context.Background(), newDelivery(t, receiver.URL, "whsec_other"), time.Now())
+	if result.OK() || result.StatusCode != 401 {
+		t.Errorf("Send() = %+v, want a failed 401", result)
+	}
+	if result.Err != nil && result.Err.Error() != "endpoint returned status 401" {
+		t.Errorf("error = %q, want only the status", result.Err)
+	}
+	if len(receiver.Received()) != 0 {
+		t.Error("receiver accepted a delivery with the wrong signature")
+	}
+}
+
+func TestSendRetryAfterFailure(t *testing.T) {
+	receiver := webhookstest.NewReceiver("whsec_test")
+	defer receiver.Close()
+	receiver.FailNext(1)
+	sender := webhooks.NewSender(5*time.Second, true)
+	d := newDelivery(t, receiver.URL, "whsec_test")
+
+	if result := sender.Send(context.Background(), d, time.Now()); result.OK() || result.StatusCode != 500 {
+		t.Fatalf("first Send() = %+v, want a failed 500", result)
+	}
+	if result := sender.Send(context.Background(), d, time.Now()); !result.OK() {
+		t.Fatalf("second Send() = %+v, want success", result)
+	}
+	if got := len(receiver.Received()); got != 1 {
+		t.Errorf("receiver got %d deliveries, want 1", got)
+	}
+}
+
+func TestSendUnreachable(t *testing.T) {
+	receiver := webhookstest.NewReceiver("whsec_test")
+	url := receiver.URL
+	receiver.Close()
+
+	result := webhooks.NewSender(time.Second, true).Send(context.Background(), newDelivery(t, url, "whsec_test"), time.Now())
+	if result.OK() || result.StatusCode != 0 {
+		t.Errorf("Send() = %+v, want a connection error", result)
+	}
+}
+
+func TestSendRefusesPrivateAddress(t *testing.T) {
+	receiver := webhookstest.NewReceiver("whsec_test")
+	defer receiver.Close()
+
+	result := webhooks.NewSender(time.Second, false).Send(context.Background(), newDelivery(t, receiver.URL, "whsec_test"), time.Now())
+	if result.OK() || !errors.Is(result.Err, webhooks.ErrDisallowedAddress) {
+		t.Errorf("Send() = %+v, want ErrDisallowedAddress", result)
+	}
+	if len(receiver.Received()) != 0 {
+		t.Error("delivery reached a loopback address")
+	}
+}
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+		{"::1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := webhooks.PublicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("PublicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{20, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := webhooks.Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
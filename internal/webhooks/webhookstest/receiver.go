@@ -0,0 +1,94 @@
+// Package webhookstest provides a local webhook receiver for tests that
+// exercise outgoing webhooks end to end.
+package webhookstest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/webhooks"
+)
+
+// Tolerance is how far a delivery's timestamp may be from the receiver's
+// clock.
+const Tolerance = 5 * time.Minute
+
+// Received is a delivery the receiver accepted.
+type Received struct {
+	DeliveryID string
+	Event      string
+	Envelope   webhooks.Envelope
+}
+
+// Receiver is an HTTP server that checks the signature on every delivery and
+// records the ones that verify. Deliveries with a bad signature get a 401.
+type Receiver struct {
+	*httptest.Server
+
+	secret string
+
+	mu       sync.Mutex
+	received []Received
+	failures int
+}
+
+// NewReceiver starts a receiver that verifies deliveries with secret. Close
+// it when done.
+func NewReceiver(secret string) *Receiver {
+	rec := &Receiver{secret: secret}
+	rec.Server = httptest.NewServer(http.HandlerFunc(rec.serveHTTP))
+	return rec
+}
+
+// FailNext makes the next n deliveries fail with a 500, to exercise retries.
+func (rec *Receiver) FailNext(n int) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.failures = n
+}
+
+// Received returns the deliveries accepted so far, oldest first.
+func (rec *Receiver) Received() []Received {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]Received(nil), rec.received...)
+}
+
+func (rec *Receiver) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = auth.VerifySignature(r.Header.Get(webhooks.SignatureHeader), r.Header.Get(webhooks.TimestampHeader),
+		body, []string{rec.secret}, time.Now(), Tolerance)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var envelope webhooks.Envelope
+	err = json.Unmarshal(body, &envelope)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.failures > 0 {
+		rec.failures--
+		http.Error(w, "failing on purpose", http.StatusInternalServerError)
+		return
+	}
+	rec.received = append(rec.received, Received{
+		DeliveryID: r.Header.Get(webhooks.DeliveryHeader),
+		Event:      r.Header.Get(webhooks.EventHeader),
+		Envelope:   envelope,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
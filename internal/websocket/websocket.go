@@ -0,0 +1,348 @@
+// Package websocket is a small server-side implementation of the WebSocket
+// protocol (RFC 6455). It supports what the API needs and no more: text and
+// binary messages, fragmentation, pings and the closing handshake. Extensions
+// such as compression are not negotiated.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// acceptGUID is appended to the client's key to prove the server understood
+// the handshake.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Message types, as sent in the frame opcode.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+
+	opContinuation = 0
+	opClose        = 8
+	opPing         = 9
+	opPong         = 10
+)
+
+// Close codes used by the API.
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseInvalidData     = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+	CloseTryAgainLater   = 1013
+	// closeNoStatus is reported when a close frame has no code. It is never
+	// sent.
+	closeNoStatus = 1005
+)
+
+// maxControlPayload is the largest payload a ping, pong or close frame may
+// carry.
+const maxControlPayload = 125
+
+// ErrBadHandshake is returned by Accept for requests that are not valid
+// WebSocket upgrades. Nothing has been written to the response.
+var ErrBadHandshake = errors.New("not a websocket handshake")
+
+// CloseError is returned by ReadMessage when the peer closes the connection.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket closed: %d %s", e.Code, e.Reason)
+}
+
+// Conn is a server-side WebSocket connection. One goroutine may read while
+// others write; writes are serialized.
+type Conn struct {
+	conn         net.Conn
+	br           *bufio.Reader
+	readLimit    int64
+	idleTimeout  time.Duration
+	writeTimeout time.Duration
+
+	wmu       sync.Mutex
+	closeSent bool
+}
+
+// Accept completes the WebSocket handshake for r and takes over its
+// connection. It returns ErrBadHandshake, without writing a response, if r
+// is not an upgrade request, so the caller can reply with a normal error.
+func Accept(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, ErrBadHandshake
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(decoded) != 16 {
+		return nil, ErrBadHandshake
+	}
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// The server's deadlines no longer apply to a hijacked connection.
+	conn.SetDeadline(time.Time{})
+	_, err = fmt.Fprintf(brw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", acceptKey(key))
+	if err == nil {
+		err = brw.Flush()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Conn{conn: conn, br: brw.Reader}, nil
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerContains reports whether any comma-separated value of header name is
+// token, ignoring case.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, part := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SetReadLimit sets the largest message ReadMessage accepts, in bytes. A
+// larger message closes the connection with CloseMessageTooBig. Zero means
+// no limit.
+func (c *Conn) SetReadLimit(n int64) {
+	c.readLimit = n
+}
+
+// SetIdleTimeout makes ReadMessage fail if no frame at all, pongs included,
+// arrives for d. Zero means no timeout.
+func (c *Conn) SetIdleTimeout(d time.Duration) {
+	c.idleTimeout = d
+}
+
+// SetWriteTimeout makes a write fail if the peer does not take it within d.
+// Zero means no timeout.
+func (c *Conn) SetWriteTimeout(d time.Duration) {
+	c.writeTimeout = d
+}
+
+// ReadMessage returns the next text or binary message. Pings are answered
+// and pongs skipped along the way. When the peer closes the connection the
+// close is acknowledged and a *CloseError returned; protocol errors close the
+// connection with a suitable code before being returned.
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	var (
+		msgType int
+		msg     []byte
+	)
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case opPing:
+			err = c.writeFrame(opPong, payload)
+			if err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			return 0, nil, c.handleClose(payload)
+		case opContinuation:
+			if msgType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		case TextMessage, BinaryMessage:
+			if msgType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "expected continuation frame")
+			}
+			msgType = op
+		default:
+			return 0, nil, c.fail(CloseProtocolError, "unknown opcode")
+		}
+
+		if c.readLimit > 0 && int64(len(msg)+len(payload)) > c.readLimit {
+			return 0, nil, c.fail(CloseMessageTooBig, "message too big")
+		}
+		msg = append(msg, payload...)
+		if fin {
+			if msgType == TextMessage && !utf8.Valid(msg) {
+				return 0, nil, c.fail(CloseInvalidData, "invalid UTF-8")
+			}
+			return msgType, msg, nil
+		}
+	}
+}
+
+// readFrame reads one frame and unmasks its payload.
+func (c *Conn) readFrame() (fin bool, op int, payload []byte, err error) {
+	if c.idleTimeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.idleTimeout))
+	}
+	var head [2]byte
+	_, err = io.ReadFull(c.br, head[:])
+	if err != nil {
+		return false, 0, nil, err
+	}
+	fin = head[0]&0x80 != 0
+	op = int(head[0] & 0x0f)
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "reserved bits set")
+	}
+	if head[1]&0x80 == 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "client frames must be masked")
+	}
+	control := op >= opClose
+
+	length := uint64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		_, err = io.ReadFull(c.br, ext[:])
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		_, err = io.ReadFull(c.br, ext[:])
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if err != nil {
+		return false, 0, nil, err
+	}
+	if control && (!fin || length > maxControlPayload) {
+		return false, 0, nil, c.fail(CloseProtocolError, "invalid control frame")
+	}
+	if c.readLimit > 0 && length > uint64(c.readLimit) {
+		return false, 0, nil, c.fail(CloseMessageTooBig, "message too big")
+	}
+
+	var mask [4]byte
+	_, err = io.ReadFull(c.br, mask[:])
+	if err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, length)
+	_, err = io.ReadFull(c.br, payload)
+	if err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+// handleClose answers a close frame from the peer and returns it as an error.
+func (c *Conn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: closeNoStatus}
+	if len(payload) >= 2 {
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Reason = string(payload[2:])
+	}
+	// Echo the code back, as the protocol asks. A close without one is
+	// answered with a normal closure.
+	code := closeErr.Code
+	if code == closeNoStatus {
+		code = CloseNormal
+	}
+	c.Close(code, "")
+	return closeErr
+}
+
+// fail closes the connection for a protocol violation and returns the
+// matching error.
+func (c *Conn) fail(code int, reason string) error {
+	c.Close(code, reason)
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// WriteMessage sends data as a single message of msgType.
+func (c *Conn) WriteMessage(msgType int, data []byte) error {
+	if msgType != TextMessage && msgType != BinaryMessage {
+		return errors.New("websocket: invalid message type")
+	}
+	return c.writeFrame(msgType, data)
+}
+
+// Ping sends a ping. The peer's pong is skipped by ReadMessage, but any frame
+// arriving shows the peer is alive.
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// Close starts the closing handshake by sending a close frame with code and
+// reason. The peer is expected to answer, which ReadMessage reports as a
+// *CloseError; after that, or after a timeout, call CloseNow. Only the first
+// close frame is sent.
+func (c *Conn) Close(code int, reason string) error {
+	if len(reason) > maxControlPayload-2 {
+		reason = reason[:maxControlPayload-2]
+	}
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+	return c.writeFrame(opClose, payload)
+}
+
+// CloseNow closes the underlying connection without a handshake.
+func (c *Conn) CloseNow() error {
+	return c.conn.Close()
+}
+
+// writeFrame writes one unmasked, final frame. Nothing is written after a
+// close frame.
+func (c *Conn) writeFrame(op int, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return net.ErrClosed
+	}
+	if op == opClose {
+		c.closeSent = true
+	}
+
+	head := make([]byte, 2, 10+len(payload))
+	head[0] = 0x80 | byte(op)
+	switch n := len(payload); {
+	case n < 126:
+		head[1] = byte(n)
+	case n <= 0xffff:
+		head[1] = 126
+		head = binary.BigEndian.AppendUint16(head, uint16(n))
+	default:
+		head[1] = 127
+		head = binary.BigEndian.AppendUint64(head, uint64(n))
+	}
+	if c.writeTimeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	}
+	_, err := c.conn.Write(append(head, payload...))
+	return err
+}
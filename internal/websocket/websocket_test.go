@@ -0,0 +1,210 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testClient speaks just enough of the client side of the protocol to drive
+// a Conn.
+type testClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dial(t *testing.T, handler func(*Conn)) *testClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Accept(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		defer c.CloseNow()
+		handler(c)
+	}))
+	t.Cleanup(srv.Close)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	_, err = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 101 {
+		t.Fatalf("handshake status = %d, want 101", resp.StatusCode)
+	}
+	// The example key and answer from RFC 6455.
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept = %q", got)
+	}
+	return &testClient{conn: conn, br: br}
+}
+
+func (tc *testClient) write(t *testing.T, fin bool, op int, payload []byte, masked bool) {
+	t.Helper()
+	b0 := byte(op)
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0, byte(len(payload))}
+	if masked {
+		mask := []byte{1, 2, 3, 4}
+		frame[1] |= 0x80
+		frame = append(frame, mask...)
+		for i, c := range payload {
+			frame = append(frame, c^mask[i%4])
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+	_, err := tc.conn.Write(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (tc *testClient) read(t *testing.T) (int, []byte) {
+	t.Helper()
+	var head [2]byte
+	_, err := io.ReadFull(tc.br, head[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head[1]&0x80 != 0 {
+		t.Fatal("server frames must not be masked")
+	}
+	payload := make([]byte, head[1]&0x7f)
+	_, err = io.ReadFull(tc.br, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int(head[0] & 0x0f), payload
+}
+
+func closeCode(payload []byte) int {
+	if len(payload) < 2 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint16(payload))
+}
+
+func echo(c *Conn) {
+	for {
+		msgType, msg, err := c.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.WriteMessage(msgType, msg)
+	}
+}
+
+func TestEchoFragmentedMessage(t *testing.T) {
+	tc := dial(t, echo)
+	tc.write(t, false, TextMessage, []byte("hel"), true)
+	// A ping between fragments is answered straight away.
+	tc.write(t, true, opPing, []byte("p"), true)
+	tc.write(t, true, opContinuation, []byte("lo"), true)
+
+	op, payload := tc.read(t)
+	if op != opPong || string(payload) != "p" {
+		t.Fatalf("got op %d %q, want pong \"p\"", op, payload)
+	}
+	op, payload = tc.read(t)
+	if op != TextMessage || string(payload) != "hello" {
+		t.Fatalf("got op %d %q, want text \"hello\"", op, payload)
+	}
+}
+
+func TestClientClose(t *testing.T) {
+	result := make(chan error, 1)
+	tc := dial(t, func(c *Conn) {
+		_, _, err := c.ReadMessage()
+		result <- err
+	})
+	tc.write(t, true, opClose, []byte{0x03, 0xe8, 'b', 'y', 'e'}, true)
+
+	op, payload := tc.read(t)
+	if op != opClose || closeCode(payload) != CloseNormal {
+		t.Fatalf("got op %d code %d, want close 1000", op, closeCode(payload))
+	}
+	var closeErr *CloseError
+	err := <-result
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseNormal || closeErr.Reason != "bye" {
+		t.Fatalf("ReadMessage error = %v, want close 1000 \"bye\"", err)
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int64
+		fin     bool
+		op      int
+		payload []byte
+		masked  bool
+		code    int
+	}{
+		{"unmasked frame", 0, true, TextMessage, []byte("hi"), false, CloseProtocolError},
+		{"too big", 4, true, TextMessage, []byte("hello"), true, CloseMessageTooBig},
+		{"invalid UTF-8", 0, true, TextMessage, []byte{0xff}, true, CloseInvalidData},
+		{"orphan continuation", 0, true, opContinuation, []byte("x"), true, CloseProtocolError},
+		{"fragmented ping", 0, false, opPing, nil, true, CloseProtocolError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := dial(t, func(c *Conn) {
+				c.SetReadLimit(tt.limit)
+				c.ReadMessage()
+			})
+			tc.write(t, tt.fin, tt.op, tt.payload, tt.masked)
+			op, payload := tc.read(t)
+			if op != opClose || closeCode(payload) != tt.code {
+				t.Fatalf("got op %d code %d, want close %d", op, closeCode(payload), tt.code)
+			}
+		})
+	}
+}
+
+func TestAcceptRejectsPlainRequests(t *testing.T) {
+	rec := httptest.NewRecorder()
+	_, err := Accept(rec, httptest.NewRequest("GET", "/", nil))
+	if !errors.Is(err, ErrBadHandshake) {
+		t.Fatalf("err = %v, want ErrBadHandshake", err)
+	}
+	if rec.Body.Len() != 0 {
+		t.Error("Accept should not write a response for a bad handshake")
+	}
+}
+
+func TestNothingIsWrittenAfterClose(t *testing.T) {
+	result := make(chan error, 1)
+	tc := dial(t, func(c *Conn) {
+		c.Close(CloseGoingAway, "shutting down")
+		result <- c.WriteMessage(TextMessage, []byte("late"))
+		c.ReadMessage()
+	})
+	op, payload := tc.read(t)
+	if op != opClose || closeCode(payload) != CloseGoingAway || string(payload[2:]) != "shutting down" {
+		t.Fatalf("got op %d %q, want close 1001", op, payload)
+	}
+	if err := <-result; !errors.Is(err, net.ErrClosed) {
+		t.Errorf("write after close: err = %v, want net.ErrClosed", err)
+	}
+}
@@ -1,13 +1,29 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/events"
+	"github.com/Throne-of-Doom/chirpy/internal/mail"
+	"github.com/Throne-of-Doom/chirpy/internal/media"
+	"github.com/Throne-of-Doom/chirpy/internal/moderation"
+	"github.com/Throne-of-Doom/chirpy/internal/ratelimit"
+	"github.com/Throne-of-Doom/chirpy/internal/spam"
+	"github.com/Throne-of-Doom/chirpy/internal/stream"
+	"github.com/Throne-of-Doom/chirpy/internal/subscription"
+	"github.com/Throne-of-Doom/chirpy/internal/webhooks"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 )
 
 const filepathRoot = "."
@@ -33,30 +49,262 @@ func main() {
 	}
 	secret := os.Getenv("SECRET")
 	apikey := os.Getenv("POLKA_KEY")
+	adminKey := os.Getenv("ADMIN_KEY")
+	apiCFG.db = db
 	apiCFG.dbQueries = dbQueries
 	apiCFG.PLATFORM = platform
 	apiCFG.SECRET = secret
 	apiCFG.POLKA_KEY = apikey
+	apiCFG.ADMIN_KEY = adminKey
+	for _, secret := range strings.Split(os.Getenv("POLKA_WEBHOOK_SECRETS"), ",") {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			apiCFG.polkaSecrets = append(apiCFG.polkaSecrets, secret)
+		}
+	}
+
+	mediaRoot := os.Getenv("MEDIA_ROOT")
+	if mediaRoot == "" {
+		mediaRoot = "media"
+	}
+	blobs, err := media.NewLocalStore(mediaRoot)
+	if err != nil {
+		log.Fatal("An error has occured", err)
+	}
+	apiCFG.blobs = blobs
+
+	apiCFG.moderation = moderation.NewFilter(nil)
+	apiCFG.moderationRulesFile = os.Getenv("MODERATION_RULES_FILE")
+	err = apiCFG.reloadModerationRules(context.Background())
+	if err != nil {
+		log.Printf("couldn't load moderation rules: %v", err)
+	}
+
+	var pgLimiter *ratelimit.PostgresStore
+	switch os.Getenv("RATE_LIMIT_STORE") {
+	case "", "memory":
+		apiCFG.limiter = ratelimit.NewMemoryStore()
+	case "postgres":
+		pgLimiter = ratelimit.NewPostgresStore(db, dbQueries)
+		apiCFG.limiter = pgLimiter
+	default:
+		log.Fatal("RATE_LIMIT_STORE must be memory or postgres")
+	}
+	apiCFG.trustProxy = os.Getenv("TRUST_PROXY") == "true"
+
+	apiCFG.spamThresholds = spam.DefaultThresholds
+	if v := os.Getenv("SPAM_HOLD_THRESHOLD"); v != "" {
+		apiCFG.spamThresholds.Hold, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			log.Fatal("SPAM_HOLD_THRESHOLD must be a number: ", err)
+		}
+	}
+	if v := os.Getenv("SPAM_REJECT_THRESHOLD"); v != "" {
+		apiCFG.spamThresholds.Reject, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			log.Fatal("SPAM_REJECT_THRESHOLD must be a number: ", err)
+		}
+	}
+	err = apiCFG.spamThresholds.Validate()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	apiCFG.subscriptionGrace = subscription.DefaultGracePeriod
+	if v := os.Getenv("SUBSCRIPTION_GRACE_PERIOD"); v != "" {
+		apiCFG.subscriptionGrace, err = time.ParseDuration(v)
+		if err != nil {
+			log.Fatal("SUBSCRIPTION_GRACE_PERIOD must be a duration: ", err)
+		}
+	}
+
+	apiCFG.outbox = events.NewPostgresStore(db, dbQueries)
+	var pgBus *events.PostgresBus
+	switch os.Getenv("EVENT_BUS") {
+	case "", "memory":
+		apiCFG.bus = events.NewChannelBus(outboxBusBuffer)
+	case "postgres":
+		pgBus, err = events.NewPostgresBus(dbURL, apiCFG.outbox, outboxBusBuffer)
+		if err != nil {
+			log.Fatal("couldn't listen for events: ", err)
+		}
+		apiCFG.bus = pgBus
+	default:
+		log.Fatal("EVENT_BUS must be memory or postgres")
+	}
+	apiCFG.broker = stream.NewBroker(streamBuffer)
+	apiCFG.webhookSender = webhooks.NewSender(webhookDeliveryTimeout, apiCFG.PLATFORM == "dev")
+
+	apiCFG.publicURL = strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/")
+	if apiCFG.publicURL == "" {
+		apiCFG.publicURL = "http://localhost:8080"
+	}
+	mailFrom := os.Getenv("MAIL_FROM")
+	if mailFrom == "" {
+		mailFrom = "Chirpy <no-reply@localhost>"
+	}
+	switch os.Getenv("MAILER") {
+	case "":
+	case "smtp":
+		apiCFG.mailer = mail.NewSMTPMailer(os.Getenv("SMTP_ADDR"), mailFrom, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+	case "file":
+		mailDir := os.Getenv("MAIL_DIR")
+		if mailDir == "" {
+			mailDir = "mail"
+		}
+		apiCFG.mailer, err = mail.NewFileMailer(mailDir, mailFrom)
+		if err != nil {
+			log.Fatal("couldn't create mail directory: ", err)
+		}
+	default:
+		log.Fatal("MAILER must be smtp or file")
+	}
+
+	trashRetention := defaultTrashRetention
+	if v := os.Getenv("TRASH_RETENTION"); v != "" {
+		trashRetention, err = time.ParseDuration(v)
+		if err != nil {
+			log.Fatal("TRASH_RETENTION must be a duration: ", err)
+		}
+	}
+
 	mux := http.NewServeMux()
 	mux.Handle("/app/", apiCFG.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filepathRoot)))))
 	mux.HandleFunc("GET /api/healthz", readinessHandler)
 	mux.HandleFunc("GET /admin/metrics", apiCFG.metricsHandler)
 	mux.HandleFunc("GET /api/chirps", apiCFG.getChirpsHandler)
 	mux.HandleFunc("POST /admin/reset", apiCFG.resetHandler)
-	mux.HandleFunc("POST /api/chirps", apiCFG.createChirpsHandler)
-	mux.HandleFunc("POST /api/users", apiCFG.createUserHandler)
+	mux.Handle("POST /api/chirps", apiCFG.middlewareRateLimit(rateLimitPost, http.HandlerFunc(apiCFG.createChirpsHandler)))
+	mux.Handle("POST /api/users", apiCFG.middlewareRateLimit(rateLimitSignup, http.HandlerFunc(apiCFG.createUserHandler)))
 	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCFG.getChirpHandler)
-	mux.HandleFunc("POST /api/login", apiCFG.loginHandler)
-	mux.HandleFunc("POST /api/refresh", apiCFG.refreshHandler)
+	mux.Handle("POST /api/login", apiCFG.middlewareRateLimit(rateLimitLogin, http.HandlerFunc(apiCFG.loginHandler)))
+	mux.Handle("POST /api/refresh", apiCFG.middlewareRateLimit(rateLimitLogin, http.HandlerFunc(apiCFG.refreshHandler)))
 	mux.HandleFunc("POST /api/revoke", apiCFG.revokeHandler)
 	mux.HandleFunc("PUT /api/users", apiCFG.UpdateUserHandler)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", apiCFG.editChirpHandler)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCFG.deleteChirpsHandler)
+	mux.HandleFunc("GET /api/chirps/trash", apiCFG.getTrashHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/restore", apiCFG.restoreChirpHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/pin", apiCFG.pinChirpHandler)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/pin", apiCFG.unpinChirpHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/like", apiCFG.likeChirpHandler)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", apiCFG.unlikeChirpHandler)
 	mux.HandleFunc("POST /api/polka/webhooks", apiCFG.upgradeChirpyHandler)
+	mux.HandleFunc("GET /api/users/{handle}", apiCFG.getProfileHandler)
+	mux.HandleFunc("PATCH /api/users/me", apiCFG.updateProfileHandler)
+	mux.HandleFunc("POST /api/users/{handle}/follow", apiCFG.followHandler)
+	mux.HandleFunc("DELETE /api/users/{handle}/follow", apiCFG.unfollowHandler)
+	mux.HandleFunc("PUT /api/users/me/avatar", apiCFG.uploadAvatarHandler)
+	mux.HandleFunc("GET /api/me/entitlements", apiCFG.getEntitlementsHandler)
+	mux.HandleFunc("GET /api/webhooks", apiCFG.listWebhookEndpointsHandler)
+	mux.HandleFunc("POST /api/webhooks", apiCFG.createWebhookEndpointHandler)
+	mux.HandleFunc("DELETE /api/webhooks/{endpointID}", apiCFG.deleteWebhookEndpointHandler)
+	mux.HandleFunc("GET /api/webhooks/{endpointID}/deliveries", apiCFG.listWebhookDeliveriesHandler)
+	mux.HandleFunc("GET /api/webhooks/{endpointID}/deliveries/{deliveryID}", apiCFG.getWebhookDeliveryHandler)
+	mux.HandleFunc("POST /api/webhooks/{endpointID}/deliveries/{deliveryID}/redeliver", apiCFG.redeliverWebhookHandler)
+	mux.HandleFunc("GET /api/users/me/subscription", apiCFG.getSubscriptionHandler)
+	mux.HandleFunc("GET /api/notifications", apiCFG.listNotificationsHandler)
+	mux.HandleFunc("GET /api/notifications/unread-count", apiCFG.unreadNotificationCountHandler)
+	mux.HandleFunc("POST /api/notifications/read", apiCFG.markAllNotificationsReadHandler)
+	mux.HandleFunc("POST /api/notifications/{notificationID}/read", apiCFG.markNotificationReadHandler)
+	mux.HandleFunc("GET /api/stream/chirps", apiCFG.streamChirpsHandler)
+	mux.HandleFunc("GET /api/stream/timeline", apiCFG.streamTimelineHandler)
+	mux.HandleFunc("GET /api/stream/notifications", apiCFG.streamNotificationsHandler)
+	mux.HandleFunc("GET /api/ws", apiCFG.websocketHandler)
+	mux.HandleFunc("GET /api/digest/preferences", apiCFG.getDigestPreferenceHandler)
+	mux.HandleFunc("PUT /api/digest/preferences", apiCFG.updateDigestPreferenceHandler)
+	mux.HandleFunc("GET /api/digest/unsubscribe", apiCFG.unsubscribePageHandler)
+	mux.HandleFunc("POST /api/digest/unsubscribe", apiCFG.unsubscribeHandler)
+	mux.HandleFunc("GET /api/search", apiCFG.searchChirpsHandler)
+	mux.HandleFunc("GET /api/search/users", apiCFG.searchUsersHandler)
+	mux.HandleFunc("GET /api/notifications/preferences", apiCFG.getNotificationPreferencesHandler)
+	mux.HandleFunc("PUT /api/notifications/preferences", apiCFG.updateNotificationPreferencesHandler)
+	mux.HandleFunc("GET /api/users/me/muted-keywords", apiCFG.listMutedKeywordsHandler)
+	mux.HandleFunc("POST /api/users/me/muted-keywords", apiCFG.muteKeywordHandler)
+	mux.HandleFunc("DELETE /api/users/me/muted-keywords/{keywordID}", apiCFG.unmuteKeywordHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/media", apiCFG.uploadChirpMediaHandler)
+	mux.HandleFunc("GET /media/{key...}", apiCFG.serveMediaHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/poll/votes", apiCFG.votePollHandler)
+	mux.HandleFunc("GET /admin/moderation/rules", apiCFG.listModerationRulesHandler)
+	mux.HandleFunc("POST /admin/moderation/rules", apiCFG.createModerationRuleHandler)
+	mux.HandleFunc("PUT /admin/moderation/rules/{ruleID}", apiCFG.updateModerationRuleHandler)
+	mux.HandleFunc("DELETE /admin/moderation/rules/{ruleID}", apiCFG.deleteModerationRuleHandler)
+	mux.HandleFunc("POST /admin/moderation/rules/reload", apiCFG.reloadModerationRulesHandler)
+	mux.HandleFunc("GET /admin/moderation/flags", apiCFG.listChirpFlagsHandler)
+	mux.HandleFunc("PUT /admin/users/{userID}/role", apiCFG.setUserRoleHandler)
+	mux.HandleFunc("GET /admin/users/{userID}/suspensions", apiCFG.listSuspensionsHandler)
+	mux.HandleFunc("POST /admin/users/{userID}/suspensions", apiCFG.createSuspensionHandler)
+	mux.HandleFunc("DELETE /admin/users/{userID}/suspensions", apiCFG.liftSuspensionsHandler)
+	mux.HandleFunc("POST /api/reports", apiCFG.createReportHandler)
+	mux.HandleFunc("GET /api/moderation/reports", apiCFG.listReportsHandler)
+	mux.HandleFunc("GET /api/moderation/reports/{reportID}", apiCFG.getReportHandler)
+	mux.HandleFunc("POST /api/moderation/reports/{reportID}/triage", apiCFG.triageReportHandler)
+	mux.HandleFunc("POST /api/moderation/reports/{reportID}/resolve", apiCFG.resolveReportHandler)
+	mux.HandleFunc("GET /api/moderation/audit", apiCFG.listAuditLogHandler)
+	mux.HandleFunc("GET /api/moderation/spam", apiCFG.listSpamScoresHandler)
+	mux.HandleFunc("POST /api/moderation/spam/{scoreID}/review", apiCFG.reviewSpamScoreHandler)
+	mux.HandleFunc("PUT /api/moderation/chirps/{chirpID}/visibility", apiCFG.setChirpVisibilityHandler)
+	mux.HandleFunc("PUT /api/moderation/users/{userID}/visibility", apiCFG.setUserVisibilityHandler)
+	mux.HandleFunc("PUT /api/moderation/chirps/{chirpID}/content-warning", apiCFG.setChirpContentWarningHandler)
+	mux.HandleFunc("GET /api/drafts", apiCFG.listDraftsHandler)
+	mux.Handle("POST /api/drafts", apiCFG.middlewareRateLimit(rateLimitPost, http.HandlerFunc(apiCFG.createDraftHandler)))
+	mux.HandleFunc("PUT /api/drafts/{draftID}", apiCFG.updateDraftHandler)
+	mux.HandleFunc("DELETE /api/drafts/{draftID}", apiCFG.deleteDraftHandler)
+	mux.HandleFunc("POST /api/drafts/{draftID}/publish", apiCFG.publishDraftHandler)
 
 	srv := &http.Server{
 		Addr:    ":8080",
 		Handler: mux,
 	}
+	// Streams and WebSockets never finish on their own, so they are ended
+	// on shutdown.
+	srv.RegisterOnShutdown(apiCFG.broker.Close)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go apiCFG.runScheduler(ctx, schedulerInterval)
+	go apiCFG.runTrashPurge(ctx, trashPurgeInterval, trashRetention)
+	go apiCFG.runModerationReload(ctx, moderationReloadInterval)
+	go apiCFG.runSubscriptionExpiry(ctx, subscriptionExpiryInterval)
+	if pgBus != nil {
+		go pgBus.Run(ctx)
+	}
+	go apiCFG.runOutboxRelay(ctx, outboxRelayInterval)
+	go apiCFG.runOutboxPrune(ctx, outboxPruneInterval, outboxRetention)
+	go events.Run(ctx, apiCFG.bus, apiCFG.outbox, events.Consumer{
+		Name:   webhookConsumer,
+		Handle: apiCFG.enqueueWebhookDeliveries,
+	}, outboxPollInterval)
+	go events.Run(ctx, apiCFG.bus, apiCFG.outbox, events.Consumer{
+		Name:   notificationConsumer,
+		Handle: apiCFG.createNotifications,
+	}, outboxPollInterval)
+	go events.Run(ctx, apiCFG.bus, apiCFG.outbox.Tail(), events.Consumer{
+		Name:   streamConsumer,
+		Handle: apiCFG.publishToStreams,
+	}, outboxPollInterval)
+	go apiCFG.runWebhookDelivery(ctx, webhookDeliveryInterval)
+	if apiCFG.mailer != nil {
+		go apiCFG.runDigests(ctx, digestInterval)
+	}
+	if pgLimiter != nil {
+		go runRateLimitCleanup(ctx, pgLimiter, rateLimitCleanupInterval)
+	}
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+		// Shutdown does not wait for hijacked connections.
+		apiCFG.waitForSockets(shutdownCtx)
+	}()
 
-	srv.ListenAndServe()
+	err = srv.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	// ListenAndServe returns as soon as shutdown starts; wait for open
+	// requests and connections to finish.
+	<-shutdownDone
 }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/entitlements"
+	"github.com/Throne-of-Doom/chirpy/internal/ratelimit"
+	"github.com/google/uuid"
+)
+
+const (
+	rateLimitSignup = entitlements.RateLimitSignup
+	rateLimitLogin  = entitlements.RateLimitLogin
+	rateLimitPost   = entitlements.RateLimitPost
+
+	rateLimitCleanupInterval = time.Hour
+	rateLimitMaxPeriod       = time.Hour
+)
+
+// anonymousRateLimits apply to requests without a valid access token and are
+// keyed by client IP. Signed-in users get the limits of their plan, keyed by
+// user ID.
+var anonymousRateLimits = map[string]ratelimit.Limit{
+	rateLimitSignup: {Burst: 5, Period: time.Hour},
+	rateLimitLogin:  {Burst: 10, Period: time.Minute},
+	rateLimitPost:   {Burst: 10, Period: time.Minute},
+}
+
+// middlewareRateLimit limits requests in a route class. If the limiter store
+// fails the request is let through, since refusing all traffic because the
+// limiter is down would be worse than briefly not limiting it.
+func (cfg *apiConfig) middlewareRateLimit(class string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, limit := cfg.rateLimitKey(r, class)
+		result, err := cfg.limiter.Take(r.Context(), class+":"+key, limit, time.Now().UTC())
+		if err != nil {
+			log.Printf("rate limiter failed, allowing request: %v", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			respondWithError(w, http.StatusTooManyRequests, "rate limit exceeded", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitKey picks the bucket for a request: the user's if it carries a
+// valid access token, otherwise the client IP's.
+func (cfg *apiConfig) rateLimitKey(r *http.Request, class string) (string, ratelimit.Limit) {
+	if userID := cfg.viewerID(r); userID != uuid.Nil {
+		limits, err := cfg.entitlementsForUser(r.Context(), userID)
+		if err == nil {
+			return "user:" + userID.String(), limits.RateLimits[class]
+		}
+	}
+	return "ip:" + cfg.clientIP(r), anonymousRateLimits[class]
+}
+
+// clientIP returns the address the request came from. Behind a reverse proxy
+// every request comes from the proxy, so with TRUST_PROXY set the last address
+// in X-Forwarded-For, the one the proxy itself appended, is used instead.
+func (cfg *apiConfig) clientIP(r *http.Request) string {
+	if cfg.trustProxy {
+		forwarded := r.Header.Get("X-Forwarded-For")
+		if forwarded != "" {
+			parts := strings.Split(forwarded, ",")
+			return strings.TrimSpace(parts[len(parts)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// runRateLimitCleanup deletes idle buckets from the Postgres store every
+// interval until ctx is cancelled.
+func runRateLimitCleanup(ctx context.Context, store *ratelimit.PostgresStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		_, err := store.DeleteStale(ctx, time.Now().UTC().Add(-rateLimitMaxPeriod))
+		if err != nil {
+			log.Printf("couldn't delete stale rate limit buckets: %v", err)
+		}
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
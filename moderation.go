@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/moderation"
+	"github.com/google/uuid"
+)
+
+const moderationReloadInterval = time.Minute
+
+// reloadModerationRules replaces the content filter's rules with those in the
+// database plus any loaded from MODERATION_RULES_FILE.
+func (cfg *apiConfig) reloadModerationRules(ctx context.Context) error {
+	var rules []moderation.Rule
+	if cfg.moderationRulesFile != "" {
+		fileRules, err := moderation.LoadFile(cfg.moderationRulesFile)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, fileRules...)
+	}
+	dbRules, err := cfg.dbQueries.ListModerationRules(ctx)
+	if err != nil {
+		return err
+	}
+	for _, rule := range dbRules {
+		rules = append(rules, moderation.Rule{
+			ID:     rule.ID.String(),
+			Term:   rule.Term,
+			Action: moderation.Action(rule.Action),
+		})
+	}
+	cfg.moderation.SetRules(rules)
+	return nil
+}
+
+// runModerationReload reloads the content filter every interval, so rule
+// changes made through another instance are picked up, until ctx is
+// cancelled.
+func (cfg *apiConfig) runModerationReload(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := cfg.reloadModerationRules(ctx)
+		if err != nil {
+			log.Printf("couldn't reload moderation rules: %v", err)
+		}
+	}
+}
+
+// recordFlags stores the flag rules that fired for a chirp so it shows up for
+// review.
+func recordFlags(ctx context.Context, qtx *database.Queries, chirpID uuid.UUID, matches []moderation.Match) error {
+	for _, match := range matches {
+		if match.Action != moderation.ActionFlag {
+			continue
+		}
+		err := qtx.CreateChirpFlag(ctx, database.CreateChirpFlagParams{
+			ChirpID: chirpID,
+			RuleID:  match.RuleID,
+			Term:    match.Term,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/events"
+	"github.com/Throne-of-Doom/chirpy/internal/notifications"
+	"github.com/google/uuid"
+)
+
+// notificationConsumer is the name notifications checkpoint under.
+const notificationConsumer = "notifications"
+
+// notificationActorsShown is how many of a notification's actors are named
+// in it; the rest are only counted.
+const notificationActorsShown = 3
+
+// pendingNotification is one user to notify about an event. ChirpID and
+// ActorID are uuid.Nil when the notification has no chirp or no actor.
+type pendingNotification struct {
+	UserID  uuid.UUID
+	Type    notifications.Type
+	ChirpID uuid.UUID
+	ActorID uuid.UUID
+}
+
+// createNotifications notifies the users ev concerns. Events may be handled
+// more than once; notification_events makes sure each user hears about each
+// event only once.
+func (cfg *apiConfig) createNotifications(ctx context.Context, ev events.Event) error {
+	pending, err := cfg.notificationsFor(ctx, ev)
+	if err != nil {
+		return err
+	}
+	for _, n := range pending {
+		err = cfg.notify(ctx, ev, n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notificationsFor works out who to notify about ev. Nobody is notified of
+// their own actions.
+func (cfg *apiConfig) notificationsFor(ctx context.Context, ev events.Event) ([]pendingNotification, error) {
+	switch ev.Type {
+	case events.ChirpCreated:
+		var chirp chirpEvent
+		err := json.Unmarshal(ev.Payload, &chirp)
+		if err != nil {
+			return nil, err
+		}
+		return cfg.chirpNotifications(ctx, chirp)
+	case events.ChirpLiked:
+		var like chirpLikedEvent
+		err := json.Unmarshal(ev.Payload, &like)
+		if err != nil || like.LikerID == like.UserID {
+			return nil, err
+		}
+		return []pendingNotification{{UserID: like.UserID, Type: notifications.Like, ChirpID: like.ChirpID, ActorID: like.LikerID}}, nil
+	case events.UserFollowed:
+		var follow userFollowedEvent
+		err := json.Unmarshal(ev.Payload, &follow)
+		if err != nil {
+			return nil, err
+		}
+		return []pendingNotification{{UserID: follow.UserID, Type: notifications.Follow, ActorID: follow.FollowerID}}, nil
+	case events.UserUpgraded:
+		return []pendingNotification{{UserID: ev.UserID, Type: notifications.Upgrade}}, nil
+	}
+	return nil, nil
+}
+
+// chirpNotifications notifies the author of the chirp being replied to and
+// everyone mentioned who can see the chirp. A reply that also mentions the
+// author it replies to only notifies them once, as a reply.
+func (cfg *apiConfig) chirpNotifications(ctx context.Context, chirp chirpEvent) ([]pendingNotification, error) {
+	var pending []pendingNotification
+	notified := map[uuid.UUID]bool{chirp.UserID: true}
+	if chirp.ReplyToID != nil {
+		parent, err := cfg.dbQueries.GetChirp(ctx, database.GetChirpParams{
+			ID:       *chirp.ReplyToID,
+			ViewerID: chirp.UserID,
+		})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if err == nil && !notified[parent.UserID] {
+			notified[parent.UserID] = true
+			pending = append(pending, pendingNotification{UserID: parent.UserID, Type: notifications.Reply, ChirpID: parent.ID, ActorID: chirp.UserID})
+		}
+	}
+	for _, handle := range notifications.Mentions(chirp.Body) {
+		mentioned, err := cfg.dbQueries.GetUserByHandle(ctx, handle)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if notified[mentioned.ID] {
+			continue
+		}
+		notified[mentioned.ID] = true
+		_, err = cfg.dbQueries.GetChirp(ctx, database.GetChirpParams{
+			ID:       chirp.ID,
+			ViewerID: mentioned.ID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, pendingNotification{UserID: mentioned.ID, Type: notifications.Mention, ChirpID: chirp.ID, ActorID: chirp.UserID})
+	}
+	return pending, nil
+}
+
+// notify records n, folding it into the user's unread notification with the
+// same group key if there is one, unless the user has turned its type off or
+// has already been notified of ev.
+func (cfg *apiConfig) notify(ctx context.Context, ev events.Event, n pendingNotification) error {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	enabled, err := qtx.NotificationEnabled(ctx, database.NotificationEnabledParams{
+		UserID: n.UserID,
+		Type:   string(n.Type),
+	})
+	if err != nil || !enabled {
+		return err
+	}
+	recorded, err := qtx.RecordNotificationEvent(ctx, database.RecordNotificationEventParams{
+		EventID: ev.ID,
+		UserID:  n.UserID,
+	})
+	if err != nil || recorded == 0 {
+		return err
+	}
+	notification, err := qtx.UpsertNotification(ctx, database.UpsertNotificationParams{
+		CreatedAt: ev.CreatedAt,
+		UserID:    n.UserID,
+		Type:      string(n.Type),
+		GroupKey:  notifications.GroupKey(n.Type, n.ChirpID, ev.ID),
+		ChirpID:   uuid.NullUUID{UUID: n.ChirpID, Valid: n.ChirpID != uuid.Nil},
+	})
+	if err != nil {
+		return err
+	}
+	if n.ActorID != uuid.Nil {
+		err = qtx.AddNotificationActor(ctx, database.AddNotificationActorParams{
+			NotificationID: notification.ID,
+			ActorID:        n.ActorID,
+			CreatedAt:      ev.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	err = events.Append(ctx, qtx, events.NotificationUpdated, n.UserID, notificationEvent{
+		ID:     notification.ID,
+		UserID: n.UserID,
+		Type:   string(n.Type),
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
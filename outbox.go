@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	outboxRelayInterval  = time.Second
+	outboxRelayBatchSize = 100
+	outboxBusBuffer      = 256
+	// outboxPollInterval is how often consumers check the outbox for events
+	// the bus did not bring them.
+	outboxPollInterval  = 30 * time.Second
+	outboxPruneInterval = time.Hour
+	// outboxRetention is how long published events are kept for consumers
+	// that were stopped to catch up on.
+	outboxRetention = 7 * 24 * time.Hour
+)
+
+// chirpEvent is a chirp as carried by chirp.created events.
+type chirpEvent struct {
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UserID         uuid.UUID  `json:"user_id"`
+	Body           string     `json:"body"`
+	ContentWarning string     `json:"content_warning,omitempty"`
+	Sensitive      bool       `json:"sensitive"`
+	ReplyToID      *uuid.UUID `json:"reply_to_id,omitempty"`
+	EditedAt       *time.Time `json:"edited_at,omitempty"`
+}
+
+// chirpDeletedEvent is the payload of chirp.deleted events.
+type chirpDeletedEvent struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// chirpLikedEvent is the payload of chirp.liked events, which concern the
+// author of the chirp.
+type chirpLikedEvent struct {
+	ChirpID     uuid.UUID `json:"chirp_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	LikerID     uuid.UUID `json:"liker_id"`
+	LikerHandle string    `json:"liker_handle"`
+}
+
+// userFollowedEvent is the payload of user.followed events, which concern
+// the user who was followed.
+type userFollowedEvent struct {
+	UserID         uuid.UUID `json:"user_id"`
+	FollowerID     uuid.UUID `json:"follower_id"`
+	FollowerHandle string    `json:"follower_handle"`
+}
+
+// userUpgradedEvent is the payload of user.upgraded events.
+type userUpgradedEvent struct {
+	UserID uuid.UUID `json:"user_id"`
+	Plan   string    `json:"plan"`
+}
+
+// notificationEvent is the payload of notification.updated events, which
+// concern the user notified.
+type notificationEvent struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	Type   string    `json:"type"`
+}
+
+func newChirpEvent(chirp database.Chirp) chirpEvent {
+	ev := chirpEvent{
+		ID:             chirp.ID,
+		CreatedAt:      chirp.CreatedAt,
+		UserID:         chirp.UserID,
+		Body:           chirp.Body,
+		ContentWarning: chirp.ContentWarning,
+		Sensitive:      chirp.Sensitive,
+	}
+	if chirp.ReplyToID.Valid {
+		replyToID := chirp.ReplyToID.UUID
+		ev.ReplyToID = &replyToID
+	}
+	if chirp.EditedAt.Valid {
+		editedAt := chirp.EditedAt.Time
+		ev.EditedAt = &editedAt
+	}
+	return ev
+}
+
+// runOutboxRelay publishes outbox events to the bus every interval until ctx
+// is cancelled.
+func (cfg *apiConfig) runOutboxRelay(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for {
+			published, err := cfg.outbox.Relay(ctx, cfg.bus, outboxRelayBatchSize)
+			if err != nil {
+				log.Printf("couldn't relay outbox events: %v", err)
+				break
+			}
+			if published < outboxRelayBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// runOutboxPrune deletes events published more than retention ago, checking
+// every interval until ctx is cancelled.
+func (cfg *apiConfig) runOutboxPrune(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		_, err := cfg.outbox.Prune(ctx, time.Now().UTC().Add(-retention))
+		if err != nil {
+			log.Printf("couldn't prune outbox events: %v", err)
+		}
+	}
+}
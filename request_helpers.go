@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+)
+
+// parseLimit reads the "limit" query parameter, falling back to def when it
+// is absent and rejecting values outside 1 to max.
+func parseLimit(r *http.Request, def, max int) (int32, error) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return int32(def), nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > max {
+		return 0, fmt.Errorf("limit must be between 1 and %d", max)
+	}
+	return int32(n), nil
+}
+
+// listCursor marks a place in a list ordered newest first by a timestamp and
+// then by ID. Clients get it back as an opaque string.
+type listCursor struct {
+	Time time.Time
+	ID   uuid.UUID
+}
+
+func (c listCursor) String() string {
+	raw := c.Time.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// parseCursor reads the "cursor" query parameter. ok is false when it is
+// absent.
+func parseCursor(r *http.Request) (cursor listCursor, ok bool, err error) {
+	return parseCursorParam(r, "cursor")
+}
+
+// parseCursorParam reads a cursor from the named query parameter. ok is false
+// when it is absent.
+func parseCursorParam(r *http.Request, name string) (cursor listCursor, ok bool, err error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return listCursor{}, false, nil
+	}
+	errInvalid := fmt.Errorf("invalid %s", name)
+	raw, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return listCursor{}, false, errInvalid
+	}
+	timePart, idPart, found := strings.Cut(string(raw), "|")
+	if !found {
+		return listCursor{}, false, errInvalid
+	}
+	cursor.Time, err = time.Parse(time.RFC3339Nano, timePart)
+	if err != nil {
+		return listCursor{}, false, errInvalid
+	}
+	cursor.ID, err = uuid.Parse(idPart)
+	if err != nil {
+		return listCursor{}, false, errInvalid
+	}
+	return cursor, true, nil
+}
+
+// parseTimeParam reads an RFC 3339 time from the named query parameter. ok
+// is false when it is absent.
+func parseTimeParam(r *http.Request, name string) (t time.Time, ok bool, err error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, false, nil
+	}
+	t, err = time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%s must be an RFC 3339 time", name)
+	}
+	return t.UTC(), true, nil
+}
+
+// pageLink is a Link header value pointing at the request's URL with the
+// cursor parameters replaced by param set to cursor.
+func pageLink(r *http.Request, rel, param string, cursor listCursor) string {
+	query := r.URL.Query()
+	query.Del("cursor")
+	query.Del("before")
+	query.Set(param, cursor.String())
+	u := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return fmt.Sprintf("<%s>; rel=%q", u.String(), rel)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	schedulerInterval  = 15 * time.Second
+	schedulerBatchSize = 50
+	// maxDraftPublishAttempts is how many times the scheduler tries to
+	// publish a draft before leaving it for its author to edit.
+	maxDraftPublishAttempts = 5
+	draftRetryDelay         = time.Minute
+)
+
+// runScheduler publishes due drafts every interval until ctx is cancelled.
+func (cfg *apiConfig) runScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for {
+			tried, err := cfg.publishDueDrafts(ctx)
+			if err != nil {
+				log.Printf("couldn't publish scheduled chirps: %v", err)
+				break
+			}
+			if tried < schedulerBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// publishDueDrafts tries to publish a batch of drafts whose publish_at has
+// passed and returns how many it tried. Each draft is published in its own
+// transaction, so one that fails does not hold up the rest: its error is
+// recorded on the draft, which is tried again after draftRetryDelay, doubling
+// with each failure, up to maxDraftPublishAttempts times.
+func (cfg *apiConfig) publishDueDrafts(ctx context.Context) (int, error) {
+	for tried := 0; tried < schedulerBatchSize; tried++ {
+		draft, err := cfg.publishNextDueDraft(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return tried, nil
+		}
+		if err != nil && draft.ID == uuid.Nil {
+			return tried, err
+		}
+		if err != nil {
+			log.Printf("couldn't publish draft %s: %v", draft.ID, err)
+			err = cfg.dbQueries.RecordDraftFailure(ctx, database.RecordDraftFailureParams{
+				ID:            draft.ID,
+				LastError:     err.Error(),
+				NextAttemptAt: time.Now().UTC().Add(draftRetryDelay << draft.PublishAttempts),
+			})
+			if err != nil {
+				return tried, err
+			}
+		}
+	}
+	return schedulerBatchSize, nil
+}
+
+// publishNextDueDraft publishes the earliest due draft and returns it, or
+// sql.ErrNoRows if there is none. The draft is claimed with FOR UPDATE SKIP
+// LOCKED, so when several instances run at once each draft is locked by
+// exactly one of them, and it is deleted in the same transaction that creates
+// its chirp.
+func (cfg *apiConfig) publishNextDueDraft(ctx context.Context) (database.Draft, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Draft{}, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	draft, err := qtx.ClaimDueDraft(ctx, maxDraftPublishAttempts)
+	if err != nil {
+		return database.Draft{}, err
+	}
+	_, err = cfg.publishDraft(ctx, qtx, draft)
+	if err != nil {
+		return draft, err
+	}
+	return draft, tx.Commit()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/spam"
+	"github.com/google/uuid"
+)
+
+// scoreChirp runs the spam check on a chirp body against its author's recent
+// activity. editing is the chirp whose body is being replaced, or uuid.Nil
+// for a new chirp; its earlier versions are left out, so that fixing a typo
+// does not count as posting the same chirp twice. It does not record
+// anything; callers pass the result to recordSpamScore once they know
+// whether a chirp was created or edited.
+func (cfg *apiConfig) scoreChirp(ctx context.Context, qtx *database.Queries, userID, editing uuid.UUID, body string) (spam.Result, error) {
+	now := time.Now().UTC()
+	dbUser, err := qtx.GetUserByID(ctx, userID)
+	if err != nil {
+		return spam.Result{}, err
+	}
+	recent, err := qtx.GetRecentSpamBodies(ctx, database.GetRecentSpamBodiesParams{
+		UserID:         userID,
+		CreatedAt:      now.Add(-spam.DuplicateWindow),
+		ExcludeChirpID: editing,
+		RowLimit:       spam.MaxRecent,
+	})
+	if err != nil {
+		return spam.Result{}, err
+	}
+	count, err := qtx.CountRecentSpamScores(ctx, database.CountRecentSpamScoresParams{
+		UserID:         userID,
+		CreatedAt:      now.Add(-spam.VelocityWindow),
+		ExcludeChirpID: editing,
+	})
+	if err != nil {
+		return spam.Result{}, err
+	}
+	return spam.Score(spam.Input{
+		Body:        body,
+		Recent:      recent,
+		RecentCount: int(count),
+		AccountAge:  now.Sub(dbUser.CreatedAt),
+	}, cfg.spamThresholds), nil
+}
+
+// recordSpamScore stores a spam check result. chirpID is not valid when the
+// chirp was rejected. Rejected chirps are recorded too, so that a bot
+// retrying the same body keeps counting as a repeat.
+func recordSpamScore(ctx context.Context, qtx *database.Queries, userID uuid.UUID, chirpID uuid.NullUUID, body string, result spam.Result) error {
+	reasons, err := json.Marshal(result.Reasons)
+	if err != nil {
+		return err
+	}
+	_, err = qtx.CreateSpamScore(ctx, database.CreateSpamScoreParams{
+		UserID:   userID,
+		ChirpID:  chirpID,
+		Body:     body,
+		Score:    result.Score,
+		Decision: string(result.Decision),
+		Reasons:  reasons,
+	})
+	return err
+}
@@ -0,0 +1,17 @@
+-- name: CreateAuditLogEntry :exec
+INSERT INTO moderation_audit_log (id, created_at, actor_id, action, report_id, chirp_id, target_user_id, details)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+);
+
+-- name: ListAuditLog :many
+SELECT * FROM moderation_audit_log
+ORDER BY created_at DESC
+LIMIT $1;
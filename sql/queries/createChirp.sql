@@ -1,10 +1,25 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, hidden_at, content_warning, sensitive, reply_to_id)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
 )
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: EditChirp :one
+-- hold hides the chirp for review, as the spam check does for new chirps.
+UPDATE chirps
+SET
+    body = sqlc.arg(body)::text,
+    updated_at = NOW(),
+    edited_at = NOW(),
+    hidden_at = CASE WHEN sqlc.arg(hold)::boolean THEN COALESCE(hidden_at, NOW()) ELSE hidden_at END
+WHERE id = sqlc.arg(id)::uuid AND deleted_at IS NULL
+RETURNING *;
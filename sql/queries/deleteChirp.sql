@@ -1,3 +0,0 @@
--- name: DeleteChirp :exec
-DELETE FROM chirps
-WHERE ID = $1;
\ No newline at end of file
@@ -0,0 +1,63 @@
+-- name: GetDigestPreference :one
+SELECT * FROM digest_preferences
+WHERE user_id = $1;
+
+-- name: SetDigestFrequency :one
+INSERT INTO digest_preferences (user_id, frequency, updated_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (user_id) DO UPDATE SET frequency = EXCLUDED.frequency, updated_at = EXCLUDED.updated_at
+RETURNING *;
+
+-- name: ClaimDueDigests :many
+SELECT
+    users.id,
+    users.email,
+    users.handle,
+    COALESCE(digest_preferences.frequency, sqlc.arg(default_frequency)::text)::text AS frequency,
+    digest_preferences.last_sent_at
+FROM users
+LEFT JOIN digest_preferences ON digest_preferences.user_id = users.id
+WHERE NOT is_user_suspended(users.id)
+    AND (users.last_active_at IS NULL OR users.last_active_at < sqlc.arg(active_before)::timestamp)
+    AND (
+        (COALESCE(digest_preferences.frequency, sqlc.arg(default_frequency)::text) = 'daily'
+            AND (digest_preferences.last_sent_at IS NULL OR digest_preferences.last_sent_at < sqlc.arg(daily_before)::timestamp))
+        OR (COALESCE(digest_preferences.frequency, sqlc.arg(default_frequency)::text) = 'weekly'
+            AND (digest_preferences.last_sent_at IS NULL OR digest_preferences.last_sent_at < sqlc.arg(weekly_before)::timestamp))
+    )
+ORDER BY users.id
+LIMIT sqlc.arg(row_limit)::int
+FOR UPDATE OF users SKIP LOCKED;
+
+-- name: MarkDigestSent :exec
+INSERT INTO digest_preferences (user_id, frequency, last_sent_at, updated_at)
+VALUES (
+    sqlc.arg(user_id)::uuid,
+    sqlc.arg(frequency)::text,
+    sqlc.arg(sent_at)::timestamp,
+    NOW()
+)
+ON CONFLICT (user_id) DO UPDATE SET last_sent_at = EXCLUDED.last_sent_at;
+
+-- name: ListTopFolloweeChirps :many
+SELECT
+    chirps.id,
+    chirps.body,
+    chirps.user_id,
+    chirps.content_warning,
+    users.handle,
+    COUNT(chirp_likes.user_id) AS likes
+FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id AND follows.follower_id = sqlc.arg(viewer_id)::uuid
+JOIN users ON users.id = chirps.user_id
+LEFT JOIN chirp_likes ON chirp_likes.chirp_id = chirps.id
+WHERE chirps.created_at > sqlc.arg(since)::timestamp
+    AND chirps.deleted_at IS NULL AND chirps.hidden_at IS NULL AND NOT is_user_suspended(chirps.user_id)
+    AND chirp_visible(chirps.visibility, chirps.user_id, sqlc.arg(viewer_id)::uuid, FALSE)
+GROUP BY chirps.id, users.handle
+ORDER BY likes DESC, chirps.created_at DESC
+LIMIT sqlc.arg(row_limit)::int;
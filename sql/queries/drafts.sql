@@ -0,0 +1,65 @@
+-- name: CreateDraft :one
+INSERT INTO drafts (id, created_at, updated_at, user_id, body, publish_at, content_warning, sensitive)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetDraft :one
+SELECT * FROM drafts WHERE id = $1;
+
+-- name: GetDraftForUpdate :one
+SELECT * FROM drafts WHERE id = $1 FOR UPDATE;
+
+-- name: ListDrafts :many
+SELECT * FROM drafts
+WHERE user_id = $1
+ORDER BY publish_at ASC NULLS LAST, created_at DESC;
+
+-- name: ListScheduledDrafts :many
+SELECT * FROM drafts
+WHERE user_id = $1 AND publish_at IS NOT NULL
+ORDER BY publish_at ASC;
+
+-- name: UpdateDraft :one
+UPDATE drafts
+SET
+    body = $2,
+    publish_at = $3,
+    content_warning = $4,
+    sensitive = $5,
+    publish_attempts = 0,
+    last_error = '',
+    next_attempt_at = NULL,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteDraft :exec
+DELETE FROM drafts WHERE id = $1;
+
+-- name: ClaimDueDraft :one
+-- Drafts that have failed max_attempts times are left where they are, and
+-- ones that failed more recently wait until their next_attempt_at.
+SELECT * FROM drafts
+WHERE publish_at <= NOW() AND NOT is_user_suspended(user_id)
+    AND publish_attempts < sqlc.arg(max_attempts)::int
+    AND (next_attempt_at IS NULL OR next_attempt_at <= NOW())
+ORDER BY publish_at ASC
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: RecordDraftFailure :exec
+UPDATE drafts
+SET
+    publish_attempts = publish_attempts + 1,
+    last_error = sqlc.arg(last_error),
+    next_attempt_at = sqlc.arg(next_attempt_at)::timestamp
+WHERE id = sqlc.arg(id);
@@ -1,2 +1,11 @@
 -- name: GetChirp :one
-SELECT * FROM chirps WHERE id = $1;
\ No newline at end of file
+SELECT * FROM chirps
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL AND hidden_at IS NULL AND NOT is_user_suspended(user_id)
+    AND chirp_visible(visibility, user_id, sqlc.arg(viewer_id)::uuid, FALSE);
+
+-- name: GetChirpEffectiveVisibility :one
+SELECT effective_visibility(visibility, user_id)::text AS visibility FROM chirps
+WHERE id = $1 AND deleted_at IS NULL AND hidden_at IS NULL AND NOT is_user_suspended(user_id);
+
+-- name: GetChirpForUpdate :one
+SELECT * FROM chirps WHERE id = $1 AND deleted_at IS NULL FOR UPDATE;
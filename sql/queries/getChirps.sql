@@ -1,2 +1,25 @@
--- name: GetChirps :many
-SELECT * FROM chirps ORDER BY created_at ASC;
\ No newline at end of file
+-- name: GetChirpsAscending :many
+-- A page of chirps, oldest first, starting after the cursor. The ORDER BY is
+-- fixed so that even a generic plan can walk the (created_at, id) indexes
+-- instead of sorting.
+SELECT * FROM chirps
+WHERE deleted_at IS NULL AND hidden_at IS NULL AND NOT is_user_suspended(user_id)
+    AND chirp_visible(visibility, user_id, sqlc.arg(viewer_id)::uuid, sqlc.arg(discovery)::boolean)
+    AND (NOT sqlc.arg(has_author)::boolean OR user_id = sqlc.arg(author_id)::uuid)
+    AND (NOT sqlc.arg(has_since)::boolean OR created_at >= sqlc.arg(since)::timestamp)
+    AND (NOT sqlc.arg(has_until)::boolean OR created_at < sqlc.arg(until)::timestamp)
+    AND (NOT sqlc.arg(has_cursor)::boolean OR (created_at, id) > (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid))
+ORDER BY created_at, id
+LIMIT sqlc.arg(row_limit)::int;
+
+-- name: GetChirpsDescending :many
+-- Like GetChirpsAscending, newest first.
+SELECT * FROM chirps
+WHERE deleted_at IS NULL AND hidden_at IS NULL AND NOT is_user_suspended(user_id)
+    AND chirp_visible(visibility, user_id, sqlc.arg(viewer_id)::uuid, sqlc.arg(discovery)::boolean)
+    AND (NOT sqlc.arg(has_author)::boolean OR user_id = sqlc.arg(author_id)::uuid)
+    AND (NOT sqlc.arg(has_since)::boolean OR created_at >= sqlc.arg(since)::timestamp)
+    AND (NOT sqlc.arg(has_until)::boolean OR created_at < sqlc.arg(until)::timestamp)
+    AND (NOT sqlc.arg(has_cursor)::boolean OR (created_at, id) < (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(row_limit)::int;
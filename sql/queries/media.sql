@@ -0,0 +1,43 @@
+-- name: CreateMediaObject :one
+INSERT INTO media_objects (id, created_at, user_id, chirp_id, content_type, size_bytes, width, height, storage_key, thumbnail_key)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9
+)
+RETURNING *;
+
+-- name: GetMediaObject :one
+SELECT * FROM media_objects WHERE id = $1;
+
+-- name: GetMediaForChirps :many
+SELECT * FROM media_objects
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY created_at ASC;
+
+-- name: CountChirpMedia :one
+SELECT COUNT(*) FROM media_objects WHERE chirp_id = $1;
+
+-- name: DeleteMediaObject :exec
+DELETE FROM media_objects WHERE id = $1;
+
+-- name: SetUserAvatar :exec
+UPDATE users SET avatar_id = $2, updated_at = NOW() WHERE id = $1;
+
+-- name: GetServableMedia :one
+-- The media object stored under key, if the viewer may see it: avatars
+-- always, and attachments only while their chirp is visible to the viewer.
+SELECT media_objects.* FROM media_objects
+LEFT JOIN chirps ON chirps.id = media_objects.chirp_id
+WHERE (media_objects.storage_key = sqlc.arg(key)::text OR media_objects.thumbnail_key = sqlc.arg(key)::text)
+    AND (media_objects.chirp_id IS NULL OR (
+        chirps.deleted_at IS NULL AND chirps.hidden_at IS NULL AND NOT is_user_suspended(chirps.user_id)
+        AND chirp_visible(chirps.visibility, chirps.user_id, sqlc.arg(viewer_id)::uuid, FALSE)
+    ));
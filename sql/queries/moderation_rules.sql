@@ -0,0 +1,40 @@
+-- name: ListModerationRules :many
+SELECT * FROM moderation_rules ORDER BY created_at ASC;
+
+-- name: CreateModerationRule :one
+INSERT INTO moderation_rules (id, created_at, updated_at, term, action)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2
+)
+RETURNING *;
+
+-- name: UpdateModerationRule :one
+UPDATE moderation_rules
+SET
+    term = $2,
+    action = $3,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteModerationRule :execrows
+DELETE FROM moderation_rules WHERE id = $1;
+
+-- name: CreateChirpFlag :exec
+INSERT INTO chirp_flags (chirp_id, rule_id, term, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: ListChirpFlags :many
+SELECT * FROM chirp_flags
+ORDER BY created_at DESC
+LIMIT $1;
@@ -0,0 +1,28 @@
+-- name: MuteKeyword :one
+INSERT INTO muted_keywords (id, created_at, user_id, keyword, normalized, expires_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (user_id, normalized) DO UPDATE
+SET keyword = EXCLUDED.keyword, expires_at = EXCLUDED.expires_at
+RETURNING *;
+
+-- name: ListMutedKeywords :many
+SELECT * FROM muted_keywords
+WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > NOW())
+ORDER BY created_at ASC;
+
+-- name: CountMutedKeywords :one
+SELECT COUNT(*) FROM muted_keywords
+WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: DeleteMutedKeyword :execrows
+DELETE FROM muted_keywords WHERE id = $1 AND user_id = $2;
+
+-- name: DeleteExpiredMutedKeywords :exec
+DELETE FROM muted_keywords WHERE user_id = $1 AND expires_at <= NOW();
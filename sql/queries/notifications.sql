@@ -0,0 +1,102 @@
+-- name: RecordNotificationEvent :execrows
+INSERT INTO notification_events (event_id, user_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UpsertNotification :one
+INSERT INTO notifications (id, created_at, updated_at, user_id, type, group_key, chirp_id)
+VALUES (
+    gen_random_uuid(),
+    sqlc.arg(created_at)::timestamp,
+    sqlc.arg(created_at)::timestamp,
+    sqlc.arg(user_id)::uuid,
+    sqlc.arg(type)::text,
+    sqlc.arg(group_key)::text,
+    sqlc.narg(chirp_id)::uuid
+)
+ON CONFLICT (user_id, group_key) WHERE read_at IS NULL DO UPDATE SET
+    updated_at = GREATEST(notifications.updated_at, EXCLUDED.updated_at)
+RETURNING *;
+
+-- name: AddNotificationActor :exec
+INSERT INTO notification_actors (notification_id, actor_id, created_at)
+VALUES (
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (notification_id, actor_id) DO UPDATE SET
+    created_at = GREATEST(notification_actors.created_at, EXCLUDED.created_at);
+
+-- name: ListNotifications :many
+SELECT
+    notifications.*,
+    (SELECT COUNT(*) FROM notification_actors WHERE notification_actors.notification_id = notifications.id) AS actor_count
+FROM notifications
+WHERE user_id = sqlc.arg(user_id)::uuid
+    AND (NOT sqlc.arg(unread_only)::boolean OR read_at IS NULL)
+    AND (NOT sqlc.arg(has_cursor)::boolean OR (updated_at, id) < (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid))
+ORDER BY updated_at DESC, id DESC
+LIMIT sqlc.arg(row_limit)::int;
+
+-- name: ListNotificationActors :many
+WITH ranked AS (
+    SELECT
+        notification_actors.notification_id,
+        notification_actors.actor_id,
+        users.handle,
+        ROW_NUMBER() OVER (PARTITION BY notification_actors.notification_id ORDER BY notification_actors.created_at DESC) AS rank
+    FROM notification_actors
+    JOIN users ON users.id = notification_actors.actor_id
+    WHERE notification_actors.notification_id = ANY(sqlc.arg(notification_ids)::uuid[])
+)
+SELECT notification_id, actor_id, handle FROM ranked
+WHERE rank <= sqlc.arg(per_notification)::int
+ORDER BY notification_id, rank;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationRead :execrows
+UPDATE notifications
+SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1 AND user_id = $2;
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: ListNotificationPreferences :many
+SELECT * FROM notification_preferences
+WHERE user_id = $1;
+
+-- name: SetNotificationPreference :exec
+INSERT INTO notification_preferences (user_id, type, enabled, updated_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+ON CONFLICT (user_id, type) DO UPDATE SET
+    enabled = EXCLUDED.enabled,
+    updated_at = NOW();
+
+-- name: NotificationEnabled :one
+SELECT COALESCE(
+    (SELECT enabled FROM notification_preferences WHERE user_id = sqlc.arg(user_id)::uuid AND type = sqlc.arg(type)::text),
+    TRUE
+)::boolean AS enabled;
+
+-- name: GetNotification :one
+SELECT
+    notifications.*,
+    (SELECT COUNT(*) FROM notification_actors WHERE notification_actors.notification_id = notifications.id) AS actor_count
+FROM notifications
+WHERE id = $1;
@@ -0,0 +1,61 @@
+-- name: CreateOutboxEvent :exec
+INSERT INTO outbox_events (id, created_at, event_type, user_id, payload)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+);
+
+-- name: TryLockOutboxRelay :one
+SELECT pg_try_advisory_xact_lock(sqlc.arg(lock_key)::bigint)::boolean AS locked;
+
+-- name: PublishOutboxEvents :many
+UPDATE outbox_events
+SET position = batch.position, published_at = NOW()
+FROM (
+    SELECT pending.seq, nextval('outbox_position_seq') AS position
+    FROM (
+        SELECT seq FROM outbox_events
+        WHERE position IS NULL
+        ORDER BY seq
+        LIMIT sqlc.arg(row_limit)::int
+    ) pending
+) batch
+WHERE outbox_events.seq = batch.seq
+RETURNING outbox_events.*;
+
+-- name: ListOutboxEventsAfter :many
+SELECT * FROM outbox_events
+WHERE position > sqlc.arg(after)::bigint
+ORDER BY position
+LIMIT sqlc.arg(row_limit)::int;
+
+-- name: GetOutboxCheckpoint :one
+SELECT position FROM outbox_checkpoints WHERE consumer = $1;
+
+-- name: SaveOutboxCheckpoint :exec
+INSERT INTO outbox_checkpoints (consumer, position, updated_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (consumer) DO UPDATE SET
+    position = GREATEST(outbox_checkpoints.position, EXCLUDED.position),
+    updated_at = NOW();
+
+-- name: DeletePublishedOutboxEvents :execrows
+DELETE FROM outbox_events
+WHERE published_at < sqlc.arg(cutoff)::timestamp;
+
+-- name: GetOutboxEventByPosition :one
+SELECT * FROM outbox_events
+WHERE position = sqlc.arg(position)::bigint;
+
+-- name: GetLatestOutboxPosition :one
+SELECT COALESCE(MAX(position), 0)::bigint AS position FROM outbox_events;
+
+-- name: NotifyOutboxEvent :exec
+SELECT pg_notify('outbox_events', sqlc.arg(payload)::text);
@@ -0,0 +1,32 @@
+-- name: PinChirp :execrows
+INSERT INTO pinned_chirps (chirp_id, user_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnpinChirp :execrows
+DELETE FROM pinned_chirps WHERE chirp_id = $1;
+
+-- name: UnpinUserChirp :execrows
+DELETE FROM pinned_chirps WHERE chirp_id = $1 AND user_id = $2;
+
+-- name: IsChirpPinned :one
+SELECT EXISTS (SELECT 1 FROM pinned_chirps WHERE chirp_id = $1)::boolean AS pinned;
+
+-- name: CountUserPins :one
+-- Pins of chirps that are deleted or hidden for review are not shown, so they
+-- do not count towards the limit either.
+SELECT COUNT(*) FROM pinned_chirps
+JOIN chirps ON chirps.id = pinned_chirps.chirp_id
+WHERE pinned_chirps.user_id = $1 AND chirps.deleted_at IS NULL AND chirps.hidden_at IS NULL;
+
+-- name: GetPinnedChirps :many
+SELECT chirps.* FROM chirps
+JOIN pinned_chirps ON pinned_chirps.chirp_id = chirps.id
+WHERE pinned_chirps.user_id = sqlc.arg(user_id) AND chirps.deleted_at IS NULL AND chirps.hidden_at IS NULL
+    AND NOT is_user_suspended(chirps.user_id)
+    AND chirp_visible(chirps.visibility, chirps.user_id, sqlc.arg(viewer_id)::uuid, FALSE)
+ORDER BY pinned_chirps.created_at DESC;
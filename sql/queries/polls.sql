@@ -0,0 +1,55 @@
+-- name: CreatePoll :one
+INSERT INTO polls (id, chirp_id, created_at, closes_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    NOW(),
+    $2
+)
+RETURNING *;
+
+-- name: CreatePollOption :one
+INSERT INTO poll_options (id, poll_id, position, label)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3
+)
+RETURNING *;
+
+-- name: GetPollByChirpForUpdate :one
+SELECT * FROM polls WHERE chirp_id = $1 FOR UPDATE;
+
+-- name: GetPollOption :one
+SELECT * FROM poll_options WHERE id = $1 AND poll_id = $2;
+
+-- name: CreatePollVote :execrows
+INSERT INTO poll_votes (poll_id, user_id, option_id, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: GetPollsForChirps :many
+SELECT * FROM polls WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: GetPollResults :many
+SELECT
+    poll_options.id,
+    poll_options.poll_id,
+    poll_options.position,
+    poll_options.label,
+    COUNT(poll_votes.user_id) AS votes
+FROM poll_options
+LEFT JOIN poll_votes ON poll_votes.option_id = poll_options.id
+WHERE poll_options.poll_id = ANY(sqlc.arg(poll_ids)::uuid[])
+GROUP BY poll_options.id
+ORDER BY poll_options.poll_id, poll_options.position;
+
+-- name: GetUserPollVotes :many
+SELECT poll_id, option_id FROM poll_votes
+WHERE user_id = sqlc.arg(user_id) AND poll_id = ANY(sqlc.arg(poll_ids)::uuid[]);
@@ -0,0 +1,34 @@
+-- name: GetUserProfile :one
+SELECT
+    users.id,
+    users.created_at,
+    users.handle,
+    users.display_name,
+    users.bio,
+    users.website,
+    is_user_chirpy_red(users.id)::boolean AS is_chirpy_red,
+    avatar.storage_key AS avatar_key,
+    avatar.thumbnail_key AS avatar_thumbnail_key,
+    (SELECT COUNT(*) FROM follows WHERE follows.followee_id = users.id) AS follower_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.follower_id = users.id) AS following_count,
+    (SELECT COUNT(*) FROM chirps WHERE chirps.user_id = users.id AND chirps.deleted_at IS NULL AND chirps.hidden_at IS NULL AND NOT is_user_suspended(users.id)
+        AND chirp_visible(chirps.visibility, users.id, sqlc.arg(viewer_id)::uuid, FALSE)) AS chirp_count
+FROM users
+LEFT JOIN media_objects avatar ON avatar.id = users.avatar_id
+WHERE LOWER(users.handle) = LOWER(sqlc.arg(handle));
+
+-- name: GetUserByHandle :one
+SELECT * FROM users
+WHERE LOWER(handle) = LOWER(sqlc.arg(handle));
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET
+    handle = $2,
+    display_name = $3,
+    bio = $4,
+    website = $5,
+    expand_sensitive = $6,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,81 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, updated_at, reporter_id, reported_user_id, chirp_id, reason, details, status)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    'open'
+)
+RETURNING *;
+
+-- name: GetReport :one
+SELECT * FROM reports WHERE id = $1;
+
+-- name: GetReportForUpdate :one
+SELECT * FROM reports WHERE id = $1 FOR UPDATE;
+
+-- name: ListReports :many
+SELECT * FROM reports
+WHERE status = $1
+ORDER BY created_at ASC
+LIMIT $2;
+
+-- name: TriageReport :one
+UPDATE reports
+SET
+    status = 'triaged',
+    assignee_id = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ResolveReport :one
+UPDATE reports
+SET
+    status = 'resolved',
+    resolution = sqlc.arg(resolution)::text,
+    resolution_note = sqlc.arg(resolution_note),
+    resolved_by = sqlc.arg(resolved_by)::uuid,
+    resolved_at = NOW(),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: GetChirpForModeration :one
+SELECT * FROM chirps WHERE id = $1;
+
+-- name: HideChirp :exec
+UPDATE chirps SET hidden_at = NOW() WHERE id = $1 AND hidden_at IS NULL;
+
+-- name: CreateSuspension :one
+INSERT INTO user_suspensions (id, created_at, user_id, created_by, reason, expires_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: SetUserRole :execrows
+UPDATE users SET role = $2, updated_at = NOW() WHERE id = $1;
+
+-- name: SetChirpVisibility :execrows
+UPDATE chirps SET visibility = $2 WHERE id = $1;
+
+-- name: SetUserVisibility :execrows
+UPDATE users SET visibility = $2 WHERE id = $1;
+
+-- name: GetWarnedChirpIDs :many
+SELECT id FROM chirps
+WHERE id = ANY(sqlc.arg(chirp_ids)::uuid[]) AND effective_visibility(visibility, user_id) = 'warned';
+
+-- name: SetChirpContentWarning :exec
+UPDATE chirps SET content_warning = $2, sensitive = $3 WHERE id = $1;
@@ -0,0 +1,55 @@
+-- name: SearchChirps :many
+-- An empty query matches every chirp, for searches made only of operators.
+-- Chirps are ranked by relevance when by_relevance is set and by recency
+-- otherwise; the cursor is only meaningful for recency.
+WITH search AS (
+    SELECT websearch_to_tsquery('english', sqlc.arg(query)::text) AS tsquery
+)
+SELECT
+    chirps.*,
+    ts_headline('english', chirps.body, search.tsquery,
+        'StartSel=' || chr(57344) || ', StopSel=' || chr(57345) || ', MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=" … "')::text AS snippet
+FROM chirps
+JOIN chirp_search ON chirp_search.chirp_id = chirps.id
+JOIN users ON users.id = chirps.user_id
+CROSS JOIN search
+WHERE chirps.deleted_at IS NULL AND chirps.hidden_at IS NULL AND NOT is_user_suspended(chirps.user_id)
+    AND chirp_visible(chirps.visibility, chirps.user_id, sqlc.arg(viewer_id)::uuid, TRUE)
+    AND (sqlc.arg(query)::text = '' OR chirp_search.search_vector @@ search.tsquery)
+    AND chirps.body ~* ALL(sqlc.arg(tag_patterns)::text[])
+    AND (cardinality(sqlc.arg(from_handles)::text[]) = 0 OR LOWER(users.handle) = ANY(sqlc.arg(from_handles)::text[]))
+    AND (NOT sqlc.arg(has_since)::boolean OR chirps.created_at >= sqlc.arg(since)::timestamp)
+    AND (NOT sqlc.arg(has_until)::boolean OR chirps.created_at < sqlc.arg(until)::timestamp)
+    AND (NOT sqlc.arg(has_cursor)::boolean OR (chirps.created_at, chirps.id) < (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid))
+ORDER BY
+    CASE WHEN sqlc.arg(by_relevance)::boolean THEN ts_rank_cd(chirp_search.search_vector, search.tsquery) END DESC,
+    chirps.created_at DESC,
+    chirps.id DESC
+LIMIT sqlc.arg(row_limit)::int;
+
+-- name: SearchUsers :many
+-- Exact handle matches come first, then handles starting with the query,
+-- then the best matches on words of handles and display names. Users a
+-- moderator limited or made author_only are left out, except to themselves.
+SELECT
+    users.id,
+    users.handle,
+    users.display_name,
+    users.bio,
+    is_user_chirpy_red(users.id)::boolean AS is_chirpy_red,
+    avatar.storage_key AS avatar_key,
+    avatar.thumbnail_key AS avatar_thumbnail_key
+FROM users
+LEFT JOIN media_objects avatar ON avatar.id = users.avatar_id
+WHERE (users.visibility NOT IN ('limited', 'author_only') OR users.id = sqlc.arg(viewer_id)::uuid)
+    AND NOT is_user_suspended(users.id)
+    AND (
+        (sqlc.arg(handle_pattern)::text <> '' AND LOWER(users.handle) LIKE sqlc.arg(handle_pattern)::text)
+        OR (sqlc.arg(words)::text <> '' AND to_tsvector('simple', users.handle || ' ' || users.display_name) @@ to_tsquery('simple', sqlc.arg(words)::text))
+    )
+ORDER BY
+    LOWER(users.handle) = sqlc.arg(handle)::text DESC,
+    (sqlc.arg(handle_pattern)::text <> '' AND LOWER(users.handle) LIKE sqlc.arg(handle_pattern)::text) DESC,
+    CASE WHEN sqlc.arg(words)::text <> '' THEN ts_rank(to_tsvector('simple', users.handle || ' ' || users.display_name), to_tsquery('simple', sqlc.arg(words)::text)) END DESC NULLS LAST,
+    users.handle
+LIMIT sqlc.arg(row_limit)::int;
@@ -0,0 +1,49 @@
+-- name: CreateSpamScore :one
+INSERT INTO spam_scores (id, created_at, user_id, chirp_id, body, score, decision, reasons)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING *;
+
+-- name: GetRecentSpamBodies :many
+-- exclude_chirp_id leaves out the earlier versions of a chirp being edited;
+-- uuid.Nil excludes nothing.
+SELECT body FROM spam_scores
+WHERE user_id = sqlc.arg(user_id)::uuid AND created_at > sqlc.arg(created_at)::timestamp
+    AND chirp_id IS DISTINCT FROM sqlc.arg(exclude_chirp_id)::uuid
+ORDER BY created_at DESC
+LIMIT sqlc.arg(row_limit)::int;
+
+-- name: CountRecentSpamScores :one
+SELECT COUNT(*) FROM spam_scores
+WHERE user_id = sqlc.arg(user_id)::uuid AND created_at > sqlc.arg(created_at)::timestamp
+    AND chirp_id IS DISTINCT FROM sqlc.arg(exclude_chirp_id)::uuid;
+
+-- name: ListSpamScores :many
+SELECT * FROM spam_scores
+WHERE decision = sqlc.arg(decision)::text
+    AND (NOT sqlc.arg(pending)::boolean OR review IS NULL)
+ORDER BY created_at DESC
+LIMIT sqlc.arg(row_limit)::int;
+
+-- name: GetSpamScoreForUpdate :one
+SELECT * FROM spam_scores WHERE id = $1 FOR UPDATE;
+
+-- name: ReviewSpamScore :one
+UPDATE spam_scores
+SET
+    review = $2,
+    reviewed_by = $3,
+    reviewed_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UnhideChirp :exec
+UPDATE chirps SET hidden_at = NULL WHERE id = $1;
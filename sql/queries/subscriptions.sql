@@ -0,0 +1,57 @@
+-- name: GetSubscriptionByUserID :one
+SELECT * FROM subscriptions WHERE user_id = $1;
+
+-- name: GetSubscriptionByUserIDForUpdate :one
+SELECT * FROM subscriptions WHERE user_id = $1 FOR UPDATE;
+
+-- name: SaveSubscription :one
+INSERT INTO subscriptions (
+    id, created_at, updated_at, user_id, plan, status,
+    current_period_start, current_period_end, grace_until, cancelled_at
+)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+ON CONFLICT (user_id) DO UPDATE SET
+    updated_at = NOW(),
+    plan = EXCLUDED.plan,
+    status = EXCLUDED.status,
+    current_period_start = EXCLUDED.current_period_start,
+    current_period_end = EXCLUDED.current_period_end,
+    grace_until = EXCLUDED.grace_until,
+    cancelled_at = EXCLUDED.cancelled_at
+RETURNING *;
+
+-- name: ClaimLapsedSubscriptions :many
+SELECT * FROM subscriptions
+WHERE status <> 'expired' AND COALESCE(grace_until, current_period_end) <= sqlc.arg(now)::timestamp
+ORDER BY COALESCE(grace_until, current_period_end)
+LIMIT sqlc.arg(row_limit)::int
+FOR UPDATE SKIP LOCKED;
+
+-- name: CreateSubscriptionEvent :exec
+INSERT INTO subscription_events (id, created_at, subscription_id, event, status, current_period_end, webhook_event_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+);
+
+-- name: ListSubscriptionEvents :many
+SELECT * FROM subscription_events
+WHERE subscription_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
@@ -0,0 +1,24 @@
+-- name: GetActiveSuspension :one
+SELECT * FROM user_suspensions
+WHERE user_id = $1
+    AND lifted_at IS NULL
+    AND (expires_at IS NULL OR expires_at > NOW())
+ORDER BY expires_at DESC NULLS FIRST
+LIMIT 1;
+
+-- name: ListUserSuspensions :many
+SELECT * FROM user_suspensions
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: LiftSuspensions :execrows
+UPDATE user_suspensions
+SET lifted_at = NOW()
+WHERE user_id = $1
+    AND lifted_at IS NULL
+    AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
@@ -0,0 +1,30 @@
+-- name: TrashChirp :exec
+UPDATE chirps
+SET deleted_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: GetTrashedChirp :one
+SELECT * FROM chirps WHERE id = $1 AND deleted_at IS NOT NULL AND hidden_at IS NULL;
+
+-- name: GetTrashedChirps :many
+SELECT * FROM chirps
+WHERE user_id = $1 AND deleted_at IS NOT NULL AND hidden_at IS NULL
+ORDER BY deleted_at DESC;
+
+-- name: RestoreChirp :one
+UPDATE chirps
+SET deleted_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: PurgeTrashedChirps :many
+-- Returns the IDs of the purged chirps with the blob keys of their media,
+-- once per attachment, or once with NULL keys for chirps without any. The
+-- media rows are deleted along with the chirps, but the join still sees them.
+WITH purged AS (
+    DELETE FROM chirps WHERE deleted_at < sqlc.arg(cutoff)::timestamp
+    RETURNING id
+)
+SELECT purged.id::uuid AS id, media_objects.storage_key, media_objects.thumbnail_key
+FROM purged
+LEFT JOIN media_objects ON media_objects.chirp_id = purged.id;
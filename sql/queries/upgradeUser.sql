@@ -1,5 +0,0 @@
--- name: UpgradeUser :exec
-UPDATE users
-SET is_chirpy_red = TRUE
-WHERE id = $1
-RETURNING *;
\ No newline at end of file
@@ -1,11 +1,12 @@
 -- name: CreateUser :one
-Insert INTO users (id, created_at, updated_at, email, hashed_password)
+Insert INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
 RETURNING *;
 
@@ -13,6 +14,10 @@ RETURNING *;
 SELECT * FROM users
 WHERE email = $1;
 
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
 -- name: UpdateUser :one
 UPDATE users 
 SET 
@@ -20,4 +25,12 @@ SET
     hashed_password = $3,
     updated_at = NOW()
     WHERE id = $1
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: GetUserByIDForUpdate :one
+SELECT * FROM users
+WHERE id = $1
+FOR UPDATE;
+
+-- name: TouchUserActivity :exec
+UPDATE users SET last_active_at = NOW() WHERE id = $1;
@@ -0,0 +1,9 @@
+-- name: RecordWebhookEvent :execrows
+INSERT INTO webhook_events (source, event_id, event, received_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,31 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN handle TEXT,
+    ADD COLUMN display_name TEXT NOT NULL DEFAULT '',
+    ADD COLUMN bio TEXT NOT NULL DEFAULT '',
+    ADD COLUMN website TEXT NOT NULL DEFAULT '';
+
+UPDATE users SET handle = 'user_' || SUBSTRING(REPLACE(id::TEXT, '-', '') FOR 10);
+
+ALTER TABLE users ALTER COLUMN handle SET NOT NULL;
+
+CREATE UNIQUE INDEX users_handle_lower_idx ON users (LOWER(handle));
+
+CREATE TABLE follows (
+    follower_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    followee_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (follower_id, followee_id),
+    CHECK (follower_id <> followee_id)
+);
+
+CREATE INDEX follows_followee_id_idx ON follows (followee_id);
+
+-- +goose Down
+DROP TABLE follows;
+DROP INDEX users_handle_lower_idx;
+ALTER TABLE users
+    DROP COLUMN website,
+    DROP COLUMN bio,
+    DROP COLUMN display_name,
+    DROP COLUMN handle;
@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE media_objects (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+    content_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    storage_key TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL
+);
+
+CREATE INDEX media_objects_chirp_id_idx ON media_objects (chirp_id);
+
+ALTER TABLE users ADD COLUMN avatar_id UUID REFERENCES media_objects(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN avatar_id;
+DROP TABLE media_objects;
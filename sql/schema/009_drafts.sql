@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE drafts (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    publish_at TIMESTAMP
+);
+
+CREATE INDEX drafts_user_id_idx ON drafts (user_id);
+CREATE INDEX drafts_publish_at_idx ON drafts (publish_at) WHERE publish_at IS NOT NULL;
+
+-- +goose Down
+DROP TABLE drafts;
@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE moderation_rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    term TEXT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('mask', 'reject', 'flag'))
+);
+
+INSERT INTO moderation_rules (id, created_at, updated_at, term, action)
+VALUES
+    (gen_random_uuid(), NOW(), NOW(), 'kerfuffle', 'mask'),
+    (gen_random_uuid(), NOW(), NOW(), 'sharbert', 'mask'),
+    (gen_random_uuid(), NOW(), NOW(), 'fornax', 'mask');
+
+-- rule_id is text because rules loaded from a file have no UUID.
+CREATE TABLE chirp_flags (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    rule_id TEXT NOT NULL,
+    term TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, rule_id)
+);
+
+CREATE INDEX chirp_flags_created_at_idx ON chirp_flags (created_at);
+
+-- +goose Down
+DROP TABLE chirp_flags;
+DROP TABLE moderation_rules;
@@ -0,0 +1,63 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'moderator', 'admin'));
+
+ALTER TABLE chirps ADD COLUMN hidden_at TIMESTAMP;
+
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reported_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'self_harm', 'impersonation', 'other')),
+    details TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('open', 'triaged', 'resolved')),
+    assignee_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    resolution TEXT CHECK (resolution IN ('dismiss', 'hide_chirp', 'suspend_user')),
+    resolution_note TEXT NOT NULL DEFAULT '',
+    resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP
+);
+
+CREATE INDEX reports_status_created_at_idx ON reports (status, created_at);
+
+-- A reporter can only have one unresolved report about the same chirp or user.
+CREATE UNIQUE INDEX reports_open_unique_idx
+    ON reports (reporter_id, reported_user_id, COALESCE(chirp_id, '00000000-0000-0000-0000-000000000000'))
+    WHERE status <> 'resolved';
+
+CREATE TABLE user_suspensions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL,
+    expires_at TIMESTAMP,
+    lifted_at TIMESTAMP
+);
+
+CREATE INDEX user_suspensions_user_id_idx ON user_suspensions (user_id);
+
+-- The audit log has no foreign keys so that entries outlive what they
+-- describe. actor_id is NULL for actions taken with the admin API key.
+CREATE TABLE moderation_audit_log (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    actor_id UUID,
+    action TEXT NOT NULL,
+    report_id UUID,
+    chirp_id UUID,
+    target_user_id UUID,
+    details TEXT NOT NULL
+);
+
+CREATE INDEX moderation_audit_log_created_at_idx ON moderation_audit_log (created_at);
+
+-- +goose Down
+DROP TABLE moderation_audit_log;
+DROP TABLE user_suspensions;
+DROP TABLE reports;
+ALTER TABLE chirps DROP COLUMN hidden_at;
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE INDEX user_suspensions_active_idx ON user_suspensions (user_id)
+    WHERE lifted_at IS NULL;
+
+-- A suspension with no expires_at is a permanent ban.
+-- +goose StatementBegin
+CREATE FUNCTION is_user_suspended(uid UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM user_suspensions
+        WHERE user_suspensions.user_id = uid
+            AND user_suspensions.lifted_at IS NULL
+            AND (user_suspensions.expires_at IS NULL OR user_suspensions.expires_at > NOW())
+    )
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION is_user_suspended(UUID);
+DROP INDEX user_suspensions_active_idx;
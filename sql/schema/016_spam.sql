@@ -0,0 +1,23 @@
+-- +goose Up
+-- Every chirp that goes through the spam check gets a row, including ones
+-- that were allowed or rejected, so thresholds can be tuned against the full
+-- distribution of scores. chirp_id is NULL for rejected chirps.
+CREATE TABLE spam_scores (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    decision TEXT NOT NULL CHECK (decision IN ('allow', 'hold', 'reject')),
+    reasons JSONB NOT NULL,
+    review TEXT CHECK (review IN ('approve', 'remove')),
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP
+);
+
+CREATE INDEX spam_scores_user_id_created_at_idx ON spam_scores (user_id, created_at);
+CREATE INDEX spam_scores_decision_created_at_idx ON spam_scores (decision, created_at);
+
+-- +goose Down
+DROP TABLE spam_scores;
@@ -0,0 +1,46 @@
+-- +goose Up
+-- Reduced-visibility states set by moderators, from least to most
+-- restrictive:
+--   warned: shown everywhere behind a content warning interstitial
+--   limited: left out of discovery, such as the global timeline, but still
+--     shown on the author's profile and by direct link
+--   author_only: only the author can see it
+-- A user's visibility applies to all of their chirps, and the stricter of the
+-- user's and the chirp's states wins.
+ALTER TABLE chirps ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public'
+    CHECK (visibility IN ('public', 'warned', 'limited', 'author_only'));
+ALTER TABLE users ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public'
+    CHECK (visibility IN ('public', 'warned', 'limited', 'author_only'));
+
+-- +goose StatementBegin
+CREATE FUNCTION effective_visibility(chirp_visibility TEXT, author UUID) RETURNS TEXT
+LANGUAGE sql STABLE AS $$
+    SELECT CASE
+        WHEN 'author_only' IN (chirp_visibility, users.visibility) THEN 'author_only'
+        WHEN 'limited' IN (chirp_visibility, users.visibility) THEN 'limited'
+        WHEN 'warned' IN (chirp_visibility, users.visibility) THEN 'warned'
+        ELSE 'public'
+    END
+    FROM users WHERE users.id = author
+$$;
+-- +goose StatementEnd
+
+-- chirp_visible reports whether viewer may see a chirp. The author always
+-- can. discovery is true for listings that surface chirps to people who did
+-- not go looking for them, which leave out limited chirps.
+-- +goose StatementBegin
+CREATE FUNCTION chirp_visible(chirp_visibility TEXT, author UUID, viewer UUID, discovery BOOLEAN) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+    SELECT author = viewer OR CASE effective_visibility(chirp_visibility, author)
+        WHEN 'author_only' THEN FALSE
+        WHEN 'limited' THEN NOT discovery
+        ELSE TRUE
+    END
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION chirp_visible(TEXT, UUID, UUID, BOOLEAN);
+DROP FUNCTION effective_visibility(TEXT, UUID);
+ALTER TABLE users DROP COLUMN visibility;
+ALTER TABLE chirps DROP COLUMN visibility;
@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN content_warning TEXT NOT NULL DEFAULT '';
+ALTER TABLE chirps ADD COLUMN sensitive BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE drafts ADD COLUMN content_warning TEXT NOT NULL DEFAULT '';
+ALTER TABLE drafts ADD COLUMN sensitive BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Whether chirps with a content warning or sensitive media are shown
+-- expanded to this user instead of collapsed.
+ALTER TABLE users ADD COLUMN expand_sensitive BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN expand_sensitive;
+ALTER TABLE drafts DROP COLUMN sensitive;
+ALTER TABLE drafts DROP COLUMN content_warning;
+ALTER TABLE chirps DROP COLUMN sensitive;
+ALTER TABLE chirps DROP COLUMN content_warning;
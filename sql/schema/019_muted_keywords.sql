@@ -0,0 +1,15 @@
+-- +goose Up
+-- normalized is the keyword's words after Unicode normalization, so that
+-- muting "New York" twice updates the same row.
+CREATE TABLE muted_keywords (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    keyword TEXT NOT NULL,
+    normalized TEXT NOT NULL,
+    expires_at TIMESTAMP,
+    UNIQUE (user_id, normalized)
+);
+
+-- +goose Down
+DROP TABLE muted_keywords;
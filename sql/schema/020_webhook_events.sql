@@ -0,0 +1,13 @@
+-- +goose Up
+-- Webhook deliveries that have been processed, so that retries of the same
+-- event are acknowledged without being applied twice.
+CREATE TABLE webhook_events (
+    source TEXT NOT NULL,
+    event_id TEXT NOT NULL,
+    event TEXT NOT NULL,
+    received_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (source, event_id)
+);
+
+-- +goose Down
+DROP TABLE webhook_events;
@@ -0,0 +1,46 @@
+-- +goose Up
+-- Chirpy Red subscriptions, one per user. users.is_chirpy_red is kept in
+-- step with these so that existing readers of the flag keep working.
+--   active: paid up to current_period_end, or open-ended if it is NULL
+--   past_due: a renewal payment failed; benefits last until grace_until
+--   cancelled: will not renew; benefits last until current_period_end
+--   expired: no benefits
+CREATE TABLE subscriptions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    plan TEXT NOT NULL,
+    status TEXT NOT NULL
+        CHECK (status IN ('active', 'past_due', 'cancelled', 'expired')),
+    current_period_start TIMESTAMP NOT NULL,
+    current_period_end TIMESTAMP,
+    grace_until TIMESTAMP,
+    cancelled_at TIMESTAMP
+);
+
+CREATE INDEX subscriptions_lapse_idx ON subscriptions (COALESCE(grace_until, current_period_end))
+    WHERE status <> 'expired';
+
+-- Every change to a subscription, newest last. webhook_event_id links the
+-- change to the delivery that caused it, if any.
+CREATE TABLE subscription_events (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    subscription_id UUID NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+    event TEXT NOT NULL,
+    status TEXT NOT NULL,
+    current_period_end TIMESTAMP,
+    webhook_event_id TEXT
+);
+
+CREATE INDEX subscription_events_subscription_idx ON subscription_events (subscription_id, created_at);
+
+-- Users upgraded before subscriptions were tracked never had an end date.
+INSERT INTO subscriptions (id, created_at, updated_at, user_id, plan, status, current_period_start)
+SELECT gen_random_uuid(), NOW(), NOW(), id, 'chirpy_red', 'active', updated_at
+FROM users WHERE is_chirpy_red;
+
+-- +goose Down
+DROP TABLE subscription_events;
+DROP TABLE subscriptions;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- When the author last edited the chirp's body, if ever.
+ALTER TABLE chirps ADD COLUMN edited_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE chirps DROP COLUMN edited_at;
@@ -0,0 +1,37 @@
+-- +goose Up
+-- Domain events, written in the same transaction as the change they describe
+-- and handed to consumers by the relay. position is assigned by the relay,
+-- which holds an advisory lock while it does so, so positions become visible
+-- in order and a consumer that has handled position N has seen every
+-- earlier event.
+CREATE SEQUENCE outbox_position_seq;
+
+CREATE TABLE outbox_events (
+    seq BIGSERIAL PRIMARY KEY,
+    id UUID NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    event_type TEXT NOT NULL,
+    user_id UUID NOT NULL,
+    payload JSONB NOT NULL,
+    position BIGINT UNIQUE,
+    published_at TIMESTAMP
+);
+
+CREATE INDEX outbox_events_unpublished_idx ON outbox_events (seq) WHERE position IS NULL;
+
+-- How far each consumer has got, by event position.
+CREATE TABLE outbox_checkpoints (
+    consumer TEXT PRIMARY KEY,
+    position BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Consumers may see an event more than once, so queuing the same event for
+-- the same endpoint twice must be a no-op.
+CREATE UNIQUE INDEX webhook_deliveries_endpoint_event_idx ON webhook_deliveries (endpoint_id, event_id);
+
+-- +goose Down
+DROP INDEX webhook_deliveries_endpoint_event_idx;
+DROP TABLE outbox_checkpoints;
+DROP TABLE outbox_events;
+DROP SEQUENCE outbox_position_seq;
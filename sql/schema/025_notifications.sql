@@ -0,0 +1,66 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN reply_to_id UUID REFERENCES chirps(id) ON DELETE SET NULL;
+
+CREATE INDEX chirps_reply_to_id_idx ON chirps (reply_to_id) WHERE reply_to_id IS NOT NULL;
+
+CREATE TABLE chirp_likes (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, user_id)
+);
+
+CREATE INDEX chirp_likes_user_id_idx ON chirp_likes (user_id);
+
+-- A notification may stand for several events of the same kind, such as
+-- everyone who liked a chirp. Events fold into the unread notification with
+-- the same group_key; once it has been read the next one starts a new
+-- notification.
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type TEXT NOT NULL CHECK (type IN ('mention', 'reply', 'like', 'follow', 'upgrade')),
+    group_key TEXT NOT NULL,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+    read_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX notifications_unread_group_idx ON notifications (user_id, group_key) WHERE read_at IS NULL;
+CREATE INDEX notifications_user_id_updated_at_idx ON notifications (user_id, updated_at DESC, id DESC);
+
+-- The users behind a notification, such as the people who liked a chirp.
+CREATE TABLE notification_actors (
+    notification_id UUID NOT NULL REFERENCES notifications(id) ON DELETE CASCADE,
+    actor_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (notification_id, actor_id)
+);
+
+-- Events already turned into a notification for a user, so that an event
+-- handled twice does not notify twice.
+CREATE TABLE notification_events (
+    event_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (event_id, user_id)
+);
+
+-- Notification types each user has turned on or off. Types without a row are
+-- on.
+CREATE TABLE notification_preferences (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, type)
+);
+
+-- +goose Down
+DROP TABLE notification_preferences;
+DROP TABLE notification_events;
+DROP TABLE notification_actors;
+DROP TABLE notifications;
+DROP TABLE chirp_likes;
+ALTER TABLE chirps DROP COLUMN reply_to_id;
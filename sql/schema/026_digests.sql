@@ -0,0 +1,17 @@
+-- +goose Up
+-- Set when a user signs in or refreshes their access token, so digests only
+-- go to users who have not been around lately.
+ALTER TABLE users ADD COLUMN last_active_at TIMESTAMP;
+
+-- Email digest settings. Users without a row get the weekly digest.
+-- last_sent_at is when the last digest was due and claimed for sending.
+CREATE TABLE digest_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    frequency TEXT NOT NULL CHECK (frequency IN ('off', 'daily', 'weekly')),
+    last_sent_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE digest_preferences;
+ALTER TABLE users DROP COLUMN last_active_at;
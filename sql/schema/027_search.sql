@@ -0,0 +1,53 @@
+-- +goose Up
+-- chirp_search_vector is the text chirps are searched by: the body, weighted
+-- above the content warning.
+-- +goose StatementBegin
+CREATE FUNCTION chirp_search_vector(body TEXT, content_warning TEXT) RETURNS TSVECTOR
+LANGUAGE sql IMMUTABLE AS $$
+    SELECT setweight(to_tsvector('english', body), 'A')
+        || setweight(to_tsvector('english', content_warning), 'B')
+$$;
+-- +goose StatementEnd
+
+-- The search vectors live in their own table rather than a column on chirps
+-- so that the many queries selecting whole chirps do not load them. A
+-- trigger keeps them in step with edits.
+CREATE TABLE chirp_search (
+    chirp_id UUID PRIMARY KEY REFERENCES chirps(id) ON DELETE CASCADE,
+    search_vector TSVECTOR NOT NULL
+);
+
+CREATE INDEX chirp_search_vector_idx ON chirp_search USING GIN (search_vector);
+
+-- +goose StatementBegin
+CREATE FUNCTION update_chirp_search() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+BEGIN
+    INSERT INTO chirp_search (chirp_id, search_vector)
+    VALUES (NEW.id, chirp_search_vector(NEW.body, NEW.content_warning))
+    ON CONFLICT (chirp_id) DO UPDATE SET search_vector = EXCLUDED.search_vector;
+    RETURN NULL;
+END
+$$;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirps_search_update
+    AFTER INSERT OR UPDATE OF body, content_warning ON chirps
+    FOR EACH ROW EXECUTE FUNCTION update_chirp_search();
+
+INSERT INTO chirp_search (chirp_id, search_vector)
+SELECT id, chirp_search_vector(body, content_warning) FROM chirps;
+
+-- User search matches words of handles and display names by prefix, and
+-- whole handles by prefix with LIKE, which needs text_pattern_ops.
+CREATE INDEX users_search_idx ON users
+    USING GIN (to_tsvector('simple', handle || ' ' || display_name));
+CREATE INDEX users_handle_prefix_idx ON users (LOWER(handle) text_pattern_ops);
+
+-- +goose Down
+DROP INDEX users_handle_prefix_idx;
+DROP INDEX users_search_idx;
+DROP TRIGGER chirps_search_update ON chirps;
+DROP FUNCTION update_chirp_search();
+DROP TABLE chirp_search;
+DROP FUNCTION chirp_search_vector(TEXT, TEXT);
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Chirp listings page through live chirps in (created_at, id) order, either
+-- all of them or one author's.
+CREATE INDEX chirps_created_at_id_idx ON chirps (created_at, id)
+    WHERE deleted_at IS NULL AND hidden_at IS NULL;
+CREATE INDEX chirps_user_id_created_at_id_idx ON chirps (user_id, created_at, id)
+    WHERE deleted_at IS NULL AND hidden_at IS NULL;
+
+-- +goose Down
+DROP INDEX chirps_user_id_created_at_id_idx;
+DROP INDEX chirps_created_at_id_idx;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Failed deliveries used to keep the start of the endpoint's response body,
+-- which endpoint owners can read back. Keep only the status code.
+UPDATE webhook_deliveries
+SET last_error = 'endpoint returned status ' || substring(last_error FROM '^endpoint returned ([0-9]+)')
+WHERE last_error ~ '^endpoint returned [0-9]+';
+UPDATE webhook_delivery_attempts
+SET error = 'endpoint returned status ' || substring(error FROM '^endpoint returned ([0-9]+)')
+WHERE error ~ '^endpoint returned [0-9]+';
+
+-- +goose Down
@@ -0,0 +1,23 @@
+-- +goose Up
+-- Whether a user has Chirpy Red is worked out from their subscription when
+-- it is read, by the same rules as subscription.Entitled, instead of being
+-- stored on users where it went stale until the expiry job caught up.
+-- +goose StatementBegin
+CREATE FUNCTION is_user_chirpy_red(uid UUID) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM subscriptions
+        WHERE subscriptions.user_id = uid
+            AND subscriptions.status <> 'expired'
+            AND (CASE WHEN subscriptions.status = 'past_due' THEN subscriptions.grace_until ELSE subscriptions.current_period_end END IS NULL
+                OR CASE WHEN subscriptions.status = 'past_due' THEN subscriptions.grace_until ELSE subscriptions.current_period_end END > NOW())
+    )
+$$;
+-- +goose StatementEnd
+
+ALTER TABLE users DROP COLUMN is_chirpy_red;
+
+-- +goose Down
+ALTER TABLE users ADD COLUMN is_chirpy_red BOOL NOT NULL DEFAULT FALSE;
+UPDATE users SET is_chirpy_red = is_user_chirpy_red(id);
+DROP FUNCTION is_user_chirpy_red(UUID);
@@ -0,0 +1,9 @@
+-- +goose Up
+-- The scheduler records why a draft could not be published and gives up on
+-- it after a few attempts, until its author edits it.
+ALTER TABLE drafts ADD COLUMN publish_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE drafts ADD COLUMN last_error TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE drafts DROP COLUMN last_error;
+ALTER TABLE drafts DROP COLUMN publish_attempts;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Media is looked up by key when it is served, to check that whoever is
+-- asking may still see the chirp it is attached to.
+CREATE UNIQUE INDEX media_objects_storage_key_idx ON media_objects (storage_key);
+CREATE UNIQUE INDEX media_objects_thumbnail_key_idx ON media_objects (thumbnail_key);
+
+-- +goose Down
+DROP INDEX media_objects_thumbnail_key_idx;
+DROP INDEX media_objects_storage_key_idx;
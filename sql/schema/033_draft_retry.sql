@@ -0,0 +1,7 @@
+-- +goose Up
+-- A draft that failed to publish waits before the scheduler tries it again,
+-- rather than being claimed again straight away.
+ALTER TABLE drafts ADD COLUMN next_attempt_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE drafts DROP COLUMN next_attempt_at;
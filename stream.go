@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/events"
+	"github.com/Throne-of-Doom/chirpy/internal/stream"
+	"github.com/google/uuid"
+)
+
+const (
+	// streamConsumer is the name the stream dispatcher consumes events under.
+	streamConsumer = "streams"
+	// streamBuffer is how many messages a client can fall behind by before
+	// it is disconnected to catch up with Last-Event-ID.
+	streamBuffer            = 64
+	streamHeartbeatInterval = 15 * time.Second
+	streamRetryMillis       = 3000
+	// streamReplayLimit is how many missed events a reconnecting client is
+	// caught up on. Clients further behind are sent a reset event instead.
+	streamReplayLimit = 1000
+
+	streamTopicPublic = "public"
+)
+
+// Stream topics are keyed by the user an event concerns: a chirp's author, or
+// the user a notification is for.
+func authorTopic(userID uuid.UUID) string {
+	return "author:" + userID.String()
+}
+
+func notificationTopic(userID uuid.UUID) string {
+	return "notifications:" + userID.String()
+}
+
+// threadTopic carries the replies to a chirp. Only WebSocket clients
+// subscribe to it.
+func threadTopic(chirpID uuid.UUID) string {
+	return "thread:" + chirpID.String()
+}
+
+// publishToStreams sends ev to the clients streaming it on this instance.
+func (cfg *apiConfig) publishToStreams(ctx context.Context, ev events.Event) error {
+	topics, msg, err := cfg.streamRoute(ctx, ev)
+	if err != nil || len(topics) == 0 {
+		return err
+	}
+	cfg.broker.Publish(msg, topics...)
+	return nil
+}
+
+// streamRoute works out which topics ev is streamed on and the message they
+// are sent. Event IDs are outbox positions, so a reconnecting client can be
+// caught up from the outbox. Chirps that are limited stay out of the public
+// stream and their thread, chirps only their author can see are sent
+// privately on the author's topic, and chirps that have been deleted or
+// hidden since they were posted are skipped.
+func (cfg *apiConfig) streamRoute(ctx context.Context, ev events.Event) ([]string, stream.Message, error) {
+	msg := stream.Message{ID: ev.Position, Event: ev.Type, Data: ev.Payload}
+	switch ev.Type {
+	case events.ChirpCreated, events.ChirpEdited:
+		var chirp chirpEvent
+		err := json.Unmarshal(ev.Payload, &chirp)
+		if err != nil {
+			return nil, msg, err
+		}
+		visibility, err := cfg.dbQueries.GetChirpEffectiveVisibility(ctx, chirp.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, msg, nil
+		}
+		if err != nil {
+			return nil, msg, err
+		}
+		switch visibility {
+		case "author_only":
+			msg.Private = true
+			return []string{authorTopic(ev.UserID)}, msg, nil
+		case "limited":
+			return []string{authorTopic(ev.UserID)}, msg, nil
+		}
+		topics := []string{streamTopicPublic, authorTopic(ev.UserID)}
+		if chirp.ReplyToID != nil {
+			topics = append(topics, threadTopic(*chirp.ReplyToID))
+		}
+		return topics, msg, nil
+	case events.ChirpDeleted:
+		return []string{streamTopicPublic, authorTopic(ev.UserID)}, msg, nil
+	case events.NotificationUpdated:
+		var notification notificationEvent
+		err := json.Unmarshal(ev.Payload, &notification)
+		if err != nil {
+			return nil, msg, err
+		}
+		row, err := cfg.dbQueries.GetNotification(ctx, notification.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, msg, nil
+		}
+		if err != nil {
+			return nil, msg, err
+		}
+		resp, err := cfg.notificationResponses(ctx, []database.ListNotificationsRow{database.ListNotificationsRow(row)})
+		if err != nil {
+			return nil, msg, err
+		}
+		msg.Data, err = json.Marshal(resp[0])
+		if err != nil {
+			return nil, msg, err
+		}
+		return []string{notificationTopic(ev.UserID)}, msg, nil
+	}
+	return nil, msg, nil
+}
+
+// serveStream streams topics to the client until it disconnects, falls too
+// far behind or the server shuts down. A client that sends Last-Event-ID is
+// first sent what it missed.
+func (cfg *apiConfig) serveStream(w http.ResponseWriter, r *http.Request, topics []string, mutes keywordMutes) {
+	// Subscribing before replaying means nothing published during the replay
+	// is missed; anything sent twice is skipped by ID.
+	sub := cfg.broker.Subscribe(topics...)
+	defer cfg.broker.Unsubscribe(sub)
+	subscribed := make(map[string]bool, len(topics))
+	for _, topic := range topics {
+		subscribed[topic] = true
+	}
+
+	sw := stream.NewWriter(w)
+	err := sw.Retry(streamRetryMillis)
+	if err != nil {
+		return
+	}
+	last := stream.LastEventID(r)
+	if last > 0 {
+		last, err = cfg.replayStream(r.Context(), sw.Send, subscribed, mutes, last)
+		if err != nil {
+			log.Printf("couldn't replay stream: %v", err)
+			return
+		}
+	}
+
+	ticker := time.NewTicker(streamHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			err = sw.Heartbeat()
+		case msg, ok := <-sub.C:
+			if !ok {
+				// The client reconnects and catches up from last.
+				return
+			}
+			// Messages without an ID, such as typing indicators, are only
+			// for WebSocket clients and are skipped here too.
+			if msg.ID <= last || streamHides(mutes, msg) {
+				continue
+			}
+			err = sw.Send(msg)
+			last = msg.ID
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// replayStream passes the events after last that belong on the subscribed
+// topics to send and returns the ID of the last event it looked at. A client that
+// has missed more than streamReplayLimit events is sent a reset event, with
+// the latest ID, and should reload whatever it is showing.
+func (cfg *apiConfig) replayStream(ctx context.Context, send func(stream.Message) error, subscribed map[string]bool, mutes keywordMutes, last int64) (int64, error) {
+	for scanned := 0; scanned < streamReplayLimit; {
+		batch, err := cfg.outbox.EventsAfter(ctx, last, outboxRelayBatchSize)
+		if err != nil {
+			return last, err
+		}
+		if len(batch) == 0 {
+			return last, nil
+		}
+		for _, ev := range batch {
+			last = ev.Position
+			if !mayConcern(subscribed, ev) {
+				continue
+			}
+			topics, msg, err := cfg.streamRoute(ctx, ev)
+			if err != nil {
+				return last, err
+			}
+			if !anySubscribed(subscribed, topics) || streamHides(mutes, msg) {
+				continue
+			}
+			err = send(msg)
+			if err != nil {
+				return last, err
+			}
+		}
+		scanned += len(batch)
+	}
+	latest, err := cfg.outbox.Latest(ctx)
+	if err != nil {
+		return last, err
+	}
+	return latest, send(stream.Message{ID: latest, Event: "reset", Data: []byte("{}")})
+}
+
+// mayConcern rules out, without a query, most events that cannot belong on
+// the subscribed topics. Every topic but a thread is public or keyed by the
+// user the event concerns; threads only carry new and edited chirps.
+func mayConcern(subscribed map[string]bool, ev events.Event) bool {
+	if subscribed[streamTopicPublic] || subscribed[authorTopic(ev.UserID)] || subscribed[notificationTopic(ev.UserID)] {
+		return true
+	}
+	if ev.Type != events.ChirpCreated && ev.Type != events.ChirpEdited {
+		return false
+	}
+	for topic := range subscribed {
+		if strings.HasPrefix(topic, "thread:") {
+			return true
+		}
+	}
+	return false
+}
+
+func anySubscribed(subscribed map[string]bool, topics []string) bool {
+	for _, topic := range topics {
+		if subscribed[topic] {
+			return true
+		}
+	}
+	return false
+}
+
+// streamHides reports whether msg is a chirp the viewer has muted, or a
+// private one they did not write.
+func streamHides(mutes keywordMutes, msg stream.Message) bool {
+	if msg.Event != events.ChirpCreated && msg.Event != events.ChirpEdited {
+		return false
+	}
+	var chirp chirpEvent
+	err := json.Unmarshal(msg.Data, &chirp)
+	if err != nil {
+		return msg.Private
+	}
+	if msg.Private && chirp.UserID != mutes.viewerID {
+		return true
+	}
+	return mutes.hides(chirp.UserID, chirp.Body, chirp.ContentWarning)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/subscription"
+	"github.com/google/uuid"
+)
+
+const (
+	subscriptionExpiryInterval  = time.Minute
+	subscriptionExpiryBatchSize = 50
+)
+
+// saveSubscription stores a subscription's new state and records the event
+// that produced it in the subscription's history. webhookEventID is empty for
+// changes not caused by a webhook delivery.
+func saveSubscription(ctx context.Context, qtx *database.Queries, userID uuid.UUID, state subscription.State, event subscription.Event, webhookEventID string) (database.Subscription, error) {
+	saved, err := qtx.SaveSubscription(ctx, database.SaveSubscriptionParams{
+		UserID:             userID,
+		Plan:               state.Plan,
+		Status:             string(state.Status),
+		CurrentPeriodStart: state.PeriodStart,
+		CurrentPeriodEnd:   nullTime(state.PeriodEnd),
+		GraceUntil:         nullTime(state.GraceUntil),
+		CancelledAt:        nullTime(state.CancelledAt),
+	})
+	if err != nil {
+		return database.Subscription{}, err
+	}
+	err = qtx.CreateSubscriptionEvent(ctx, database.CreateSubscriptionEventParams{
+		SubscriptionID:   saved.ID,
+		Event:            string(event),
+		Status:           saved.Status,
+		CurrentPeriodEnd: saved.CurrentPeriodEnd,
+		WebhookEventID:   sql.NullString{String: webhookEventID, Valid: webhookEventID != ""},
+	})
+	if err != nil {
+		return database.Subscription{}, err
+	}
+	return saved, nil
+}
+
+// runSubscriptionExpiry expires lapsed subscriptions every interval until ctx
+// is cancelled.
+func (cfg *apiConfig) runSubscriptionExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for {
+			expired, err := cfg.expireLapsedSubscriptions(ctx, time.Now().UTC())
+			if err != nil {
+				log.Printf("couldn't expire subscriptions: %v", err)
+				break
+			}
+			if expired < subscriptionExpiryBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// expireLapsedSubscriptions expires one batch of subscriptions whose period
+// or grace period ended before now and returns how many it claimed. Rows are
+// claimed with FOR UPDATE SKIP LOCKED so that several instances can run the
+// job at once.
+func (cfg *apiConfig) expireLapsedSubscriptions(ctx context.Context, now time.Time) (int, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	lapsed, err := qtx.ClaimLapsedSubscriptions(ctx, database.ClaimLapsedSubscriptionsParams{
+		Now:      now,
+		RowLimit: subscriptionExpiryBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, dbSub := range lapsed {
+		state := subscription.Refresh(subscriptionState(dbSub), now)
+		_, err = saveSubscription(ctx, qtx, dbSub.UserID, state, subscription.EventExpired, "")
+		if err != nil {
+			return 0, err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return len(lapsed), nil
+}
+
+func subscriptionState(dbSub database.Subscription) subscription.State {
+	return subscription.State{
+		Plan:        dbSub.Plan,
+		Status:      subscription.Status(dbSub.Status),
+		PeriodStart: dbSub.CurrentPeriodStart,
+		PeriodEnd:   dbSub.CurrentPeriodEnd.Time,
+		GraceUntil:  dbSub.GraceUntil.Time,
+		CancelledAt: dbSub.CancelledAt.Time,
+	}
+}
+
+// nullTime maps the zero time to NULL.
+func nullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// timePtr maps NULL to nil.
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -1,27 +1,123 @@
 package main
 
 import (
+	"database/sql"
+	"encoding/json"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/events"
+	"github.com/Throne-of-Doom/chirpy/internal/mail"
+	"github.com/Throne-of-Doom/chirpy/internal/media"
+	"github.com/Throne-of-Doom/chirpy/internal/moderation"
+	"github.com/Throne-of-Doom/chirpy/internal/ratelimit"
+	"github.com/Throne-of-Doom/chirpy/internal/spam"
+	"github.com/Throne-of-Doom/chirpy/internal/stream"
+	"github.com/Throne-of-Doom/chirpy/internal/webhooks"
 	"github.com/google/uuid"
 )
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
+	db             *sql.DB
 	dbQueries      *database.Queries
 	PLATFORM       string
 	SECRET         string
 	POLKA_KEY      string
+	polkaSecrets   []string
+	blobs          media.BlobStore
+	ADMIN_KEY      string
+
+	moderation          *moderation.Filter
+	moderationRulesFile string
+
+	limiter    ratelimit.Store
+	trustProxy bool
+
+	spamThresholds spam.Thresholds
+
+	subscriptionGrace time.Duration
+
+	outbox *events.PostgresStore
+	bus    events.Bus
+	broker *stream.Broker
+
+	// sockets counts open WebSocket connections, which the server does not
+	// track once they are hijacked, so shutdown can wait for them.
+	sockets  sync.WaitGroup
+	presence presenceTracker
+
+	webhookSender *webhooks.Sender
+
+	// mailer is nil when no mailer is configured, and digests are not sent.
+	mailer    mail.Mailer
+	publicURL string
 }
 
+// ChirpResponse is a chirp as seen by a particular viewer. Collapsed tells
+// clients to hide the body and media behind the content warning, and is set
+// for chirps with a warning or sensitive media unless the viewer has chosen
+// to expand them.
 type ChirpResponse struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID             uuid.UUID       `json:"id"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	Body           string          `json:"body"`
+	UserID         uuid.UUID       `json:"user_id"`
+	ContentWarning string          `json:"content_warning,omitempty"`
+	Sensitive      bool            `json:"sensitive"`
+	Collapsed      bool            `json:"collapsed,omitempty"`
+	ReplyToID      *uuid.UUID      `json:"reply_to_id,omitempty"`
+	Media          []mediaResponse `json:"media"`
+	Poll           *pollResponse   `json:"poll,omitempty"`
+	Likes          int64           `json:"likes"`
+	Liked          bool            `json:"liked,omitempty"`
+	Pinned         bool            `json:"pinned,omitempty"`
+	Hidden         bool            `json:"hidden,omitempty"`
+	Warned         bool            `json:"warned,omitempty"`
+	EditedAt       *time.Time      `json:"edited_at,omitempty"`
+	DeletedAt      *time.Time      `json:"deleted_at,omitempty"`
+}
+
+// pollResponse hides vote counts until the viewer has voted or the poll has
+// closed, so TotalVotes and each option's Votes are nil until then.
+type pollResponse struct {
+	ID             uuid.UUID            `json:"id"`
+	ClosesAt       time.Time            `json:"closes_at"`
+	Closed         bool                 `json:"closed"`
+	ResultsVisible bool                 `json:"results_visible"`
+	TotalVotes     *int64               `json:"total_votes,omitempty"`
+	ViewerVote     *uuid.UUID           `json:"viewer_vote,omitempty"`
+	Options        []pollOptionResponse `json:"options"`
+}
+
+type pollOptionResponse struct {
+	ID    uuid.UUID `json:"id"`
+	Label string    `json:"label"`
+	Votes *int64    `json:"votes,omitempty"`
+}
+
+type mediaResponse struct {
+	ID           uuid.UUID `json:"id"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+}
+
+type draftResponse struct {
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	Body           string     `json:"body"`
+	ContentWarning string     `json:"content_warning,omitempty"`
+	Sensitive      bool       `json:"sensitive"`
+	UserID         uuid.UUID  `json:"user_id"`
+	PublishAt      *time.Time `json:"publish_at"`
+	PublishError   string     `json:"publish_error,omitempty"`
 }
 
 type loginResponse struct {
@@ -29,6 +125,7 @@ type loginResponse struct {
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 	Email        string    `json:"email"`
+	Handle       string    `json:"handle"`
 	Token        string    `json:"token"`
 	RefreshToken string    `json:"refresh_token"`
 	IsChirpyRed  bool      `json:"is_chirpy_red"`
@@ -39,10 +136,257 @@ type CredentialsRequest struct {
 	Password string `json:"password"`
 }
 
+// user is the account owner's view of themselves. It includes the email
+// address, so it must never be returned to anyone else; use profile for
+// public responses.
 type user struct {
+	ID              uuid.UUID `json:"id"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	Email           string    `json:"email"`
+	Handle          string    `json:"handle"`
+	DisplayName     string    `json:"display_name"`
+	Bio             string    `json:"bio"`
+	Website         string    `json:"website"`
+	IsChirpyRed     bool      `json:"is_chirpy_red"`
+	ExpandSensitive bool      `json:"expand_sensitive"`
+}
+
+// profile is the public view of a user.
+type profile struct {
+	ID             uuid.UUID       `json:"id"`
+	CreatedAt      time.Time       `json:"created_at"`
+	Handle         string          `json:"handle"`
+	DisplayName    string          `json:"display_name"`
+	Bio            string          `json:"bio"`
+	Website        string          `json:"website"`
+	AvatarURL      string          `json:"avatar_url,omitempty"`
+	AvatarThumbURL string          `json:"avatar_thumbnail_url,omitempty"`
+	IsChirpyRed    bool            `json:"is_chirpy_red"`
+	FollowerCount  int64           `json:"follower_count"`
+	FollowingCount int64           `json:"following_count"`
+	ChirpCount     int64           `json:"chirp_count"`
+	PinnedChirps   []ChirpResponse `json:"pinned_chirps"`
+}
+
+// moderationRuleResponse describes a content filter rule. Rules loaded from
+// MODERATION_RULES_FILE are not editable and have IDs that are not UUIDs.
+type moderationRuleResponse struct {
+	ID       string            `json:"id"`
+	Term     string            `json:"term"`
+	Action   moderation.Action `json:"action"`
+	Editable bool              `json:"editable"`
+}
+
+type chirpFlagResponse struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	RuleID    string    `json:"rule_id"`
+	Term      string    `json:"term"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type reportResponse struct {
+	ID             uuid.UUID      `json:"id"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	ReporterID     uuid.UUID      `json:"reporter_id"`
+	ReportedUserID uuid.UUID      `json:"reported_user_id"`
+	ChirpID        *uuid.UUID     `json:"chirp_id"`
+	Reason         string         `json:"reason"`
+	Details        string         `json:"details"`
+	Status         string         `json:"status"`
+	AssigneeID     *uuid.UUID     `json:"assignee_id"`
+	Resolution     string         `json:"resolution,omitempty"`
+	ResolutionNote string         `json:"resolution_note,omitempty"`
+	ResolvedBy     *uuid.UUID     `json:"resolved_by,omitempty"`
+	ResolvedAt     *time.Time     `json:"resolved_at,omitempty"`
+	Chirp          *ChirpResponse `json:"chirp,omitempty"`
+}
+
+// auditLogEntryResponse is an entry in the moderation audit log. ActorID is
+// nil for actions taken with the admin API key.
+type auditLogEntryResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ActorID      *uuid.UUID `json:"actor_id"`
+	Action       string     `json:"action"`
+	ReportID     *uuid.UUID `json:"report_id,omitempty"`
+	ChirpID      *uuid.UUID `json:"chirp_id,omitempty"`
+	TargetUserID *uuid.UUID `json:"target_user_id,omitempty"`
+	Details      string     `json:"details,omitempty"`
+}
+
+// suspensionResponse describes a suspension. A permanent suspension is a ban
+// and has no ExpiresAt.
+type suspensionResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserID    uuid.UUID  `json:"user_id"`
+	CreatedBy *uuid.UUID `json:"created_by"`
+	Reason    string     `json:"reason"`
+	Permanent bool       `json:"permanent"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	LiftedAt  *time.Time `json:"lifted_at,omitempty"`
+}
+
+// spamScoreResponse is the result of a chirp's spam check. ChirpID is nil
+// when the chirp was rejected or has since been purged.
+type spamScoreResponse struct {
+	ID         uuid.UUID       `json:"id"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UserID     uuid.UUID       `json:"user_id"`
+	ChirpID    *uuid.UUID      `json:"chirp_id"`
+	Body       string          `json:"body"`
+	Score      float64         `json:"score"`
+	Decision   string          `json:"decision"`
+	Reasons    json.RawMessage `json:"reasons"`
+	Review     string          `json:"review,omitempty"`
+	ReviewedBy *uuid.UUID      `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time      `json:"reviewed_at,omitempty"`
+}
+
+// mutedKeywordResponse is a keyword the user has muted. ExpiresAt is nil for
+// keywords muted indefinitely.
+type mutedKeywordResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Keyword   string     `json:"keyword"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// subscriptionResponse is the user's Chirpy Red subscription and its most
+// recent changes, newest first. CurrentPeriodEnd is nil for open-ended
+// subscriptions.
+type subscriptionResponse struct {
+	Plan               string                      `json:"plan"`
+	Status             string                      `json:"status"`
+	IsChirpyRed        bool                        `json:"is_chirpy_red"`
+	CurrentPeriodStart time.Time                   `json:"current_period_start"`
+	CurrentPeriodEnd   *time.Time                  `json:"current_period_end"`
+	GraceUntil         *time.Time                  `json:"grace_until,omitempty"`
+	CancelledAt        *time.Time                  `json:"cancelled_at,omitempty"`
+	History            []subscriptionEventResponse `json:"history"`
+}
+
+type subscriptionEventResponse struct {
+	CreatedAt        time.Time  `json:"created_at"`
+	Event            string     `json:"event"`
+	Status           string     `json:"status"`
+	CurrentPeriodEnd *time.Time `json:"current_period_end"`
+}
+
+// entitlementsResponse is what the caller's plan allows. EditWindowSeconds is
+// 0 if the plan cannot edit chirps.
+type entitlementsResponse struct {
+	Plan              string                       `json:"plan"`
+	IsChirpyRed       bool                         `json:"is_chirpy_red"`
+	MaxChirpLength    int                          `json:"max_chirp_length"`
+	MaxMediaPerChirp  int                          `json:"max_media_per_chirp"`
+	MaxUploadBytes    int64                        `json:"max_upload_bytes"`
+	MaxPinnedChirps   int                          `json:"max_pinned_chirps"`
+	EditWindowSeconds int                          `json:"edit_window_seconds"`
+	RateLimits        map[string]rateLimitResponse `json:"rate_limits"`
+	Features          []string                     `json:"features"`
+}
+
+type rateLimitResponse struct {
+	Burst         int `json:"burst"`
+	PeriodSeconds int `json:"period_seconds"`
+}
+
+// webhookEndpointResponse is an outgoing webhook endpoint. Secret is only
+// returned when the endpoint is created.
+type webhookEndpointResponse struct {
 	ID          uuid.UUID `json:"id"`
 	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Email       string    `json:"email"`
-	IsChirpyRed bool      `json:"is_chirpy_red"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Description string    `json:"description"`
+	Secret      string    `json:"secret,omitempty"`
+}
+
+// webhookDeliveryResponse is one event sent, or being sent, to an endpoint.
+// Payload and AttemptLog are only filled in when a single delivery is
+// requested.
+type webhookDeliveryResponse struct {
+	ID             uuid.UUID                        `json:"id"`
+	CreatedAt      time.Time                        `json:"created_at"`
+	EventID        uuid.UUID                        `json:"event_id"`
+	EventType      string                           `json:"event_type"`
+	Status         string                           `json:"status"`
+	Attempts       int32                            `json:"attempts"`
+	NextAttemptAt  *time.Time                       `json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time                       `json:"delivered_at,omitempty"`
+	LastStatusCode *int32                           `json:"last_status_code,omitempty"`
+	LastError      string                           `json:"last_error,omitempty"`
+	Payload        json.RawMessage                  `json:"payload,omitempty"`
+	AttemptLog     []webhookDeliveryAttemptResponse `json:"attempt_log,omitempty"`
+}
+
+type webhookDeliveryAttemptResponse struct {
+	AttemptedAt time.Time `json:"attempted_at"`
+	StatusCode  *int32    `json:"status_code"`
+	Error       string    `json:"error,omitempty"`
+	DurationMs  int32     `json:"duration_ms"`
+}
+
+// notificationResponse is one notification, which may stand for several
+// events of the same kind. Actors are the most recent few of ActorCount
+// users behind it, newest first.
+type notificationResponse struct {
+	ID         uuid.UUID                   `json:"id"`
+	CreatedAt  time.Time                   `json:"created_at"`
+	UpdatedAt  time.Time                   `json:"updated_at"`
+	Type       string                      `json:"type"`
+	Summary    string                      `json:"summary"`
+	ChirpID    *uuid.UUID                  `json:"chirp_id,omitempty"`
+	Actors     []notificationActorResponse `json:"actors"`
+	ActorCount int64                       `json:"actor_count"`
+	ReadAt     *time.Time                  `json:"read_at"`
+}
+
+type notificationActorResponse struct {
+	ID     uuid.UUID `json:"id"`
+	Handle string    `json:"handle"`
+}
+
+// notificationListResponse is a page of notifications. NextCursor is empty
+// on the last page.
+type notificationListResponse struct {
+	Notifications []notificationResponse `json:"notifications"`
+	UnreadCount   int64                  `json:"unread_count"`
+	NextCursor    string                 `json:"next_cursor,omitempty"`
+}
+
+type digestPreferenceResponse struct {
+	Frequency  string     `json:"frequency"`
+	LastSentAt *time.Time `json:"last_sent_at"`
+}
+
+// chirpSearchResult is a chirp found by search. Snippet is HTML: the parts
+// of the body that matched, escaped, with the matched words in <mark> tags.
+type chirpSearchResult struct {
+	ChirpResponse
+	Snippet string `json:"snippet"`
+}
+
+// chirpSearchResponse is a page of search results. NextCursor is only set
+// for results sorted by recency, and is empty on the last page.
+type chirpSearchResponse struct {
+	Chirps     []chirpSearchResult `json:"chirps"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+type userSearchResult struct {
+	ID             uuid.UUID `json:"id"`
+	Handle         string    `json:"handle"`
+	DisplayName    string    `json:"display_name"`
+	Bio            string    `json:"bio"`
+	AvatarURL      string    `json:"avatar_url,omitempty"`
+	AvatarThumbURL string    `json:"avatar_thumbnail_url,omitempty"`
+	IsChirpyRed    bool      `json:"is_chirpy_red"`
+}
+
+type userSearchResponse struct {
+	Users []userSearchResult `json:"users"`
 }
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/events"
+	"github.com/Throne-of-Doom/chirpy/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+const (
+	webhookDeliveryInterval  = 5 * time.Second
+	webhookDeliveryBatchSize = 20
+	webhookDeliveryTimeout   = 10 * time.Second
+	// webhookDeliveryLease is how long a claimed delivery is left alone by
+	// other workers. It must outlast a whole batch of timeouts; a worker that
+	// dies mid-batch leaves its deliveries to be retried once it expires.
+	webhookDeliveryLease = 5 * time.Minute
+)
+
+const (
+	webhookStatusPending   = "pending"
+	webhookStatusDelivered = "delivered"
+	webhookStatusDead      = "dead"
+)
+
+// webhookConsumer is the name the outgoing webhooks checkpoint under.
+const webhookConsumer = "webhooks"
+
+// enqueueWebhookDeliveries queues ev for every endpoint its user has
+// subscribed to it. Events are offered at least once, so an endpoint that
+// already has a delivery for ev is skipped.
+func (cfg *apiConfig) enqueueWebhookDeliveries(ctx context.Context, ev events.Event) error {
+	if !webhooks.ValidEventType(ev.Type) {
+		return nil
+	}
+	payload, err := json.Marshal(webhooks.Envelope{
+		ID:        ev.ID,
+		Type:      ev.Type,
+		CreatedAt: ev.CreatedAt,
+		Data:      ev.Payload,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = cfg.dbQueries.EnqueueWebhookDeliveries(ctx, database.EnqueueWebhookDeliveriesParams{
+		EventID:   ev.ID,
+		EventType: ev.Type,
+		Payload:   payload,
+		UserID:    ev.UserID,
+	})
+	return err
+}
+
+// runWebhookDelivery sends due webhook deliveries every interval until ctx
+// is cancelled.
+func (cfg *apiConfig) runWebhookDelivery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for {
+			sent, err := cfg.deliverDueWebhooks(ctx)
+			if err != nil {
+				log.Printf("couldn't deliver webhooks: %v", err)
+				break
+			}
+			if sent < webhookDeliveryBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// deliverDueWebhooks makes one attempt at each of a batch of due deliveries
+// and returns how many it claimed. Claiming pushes next_attempt_at past the
+// lease with FOR UPDATE SKIP LOCKED, so concurrent workers never claim the
+// same delivery and no transaction is held open while endpoints respond.
+func (cfg *apiConfig) deliverDueWebhooks(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	claimed, err := cfg.dbQueries.ClaimDueWebhookDeliveries(ctx, database.ClaimDueWebhookDeliveriesParams{
+		LeaseUntil: now.Add(webhookDeliveryLease),
+		Now:        now,
+		RowLimit:   webhookDeliveryBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+	endpoints := map[uuid.UUID]database.WebhookEndpoint{}
+	for _, delivery := range claimed {
+		endpoint, ok := endpoints[delivery.EndpointID]
+		if !ok {
+			endpoint, err = cfg.dbQueries.GetWebhookEndpointByID(ctx, delivery.EndpointID)
+			if errors.Is(err, sql.ErrNoRows) {
+				// The endpoint was deleted and its deliveries went with it.
+				continue
+			}
+			if err != nil {
+				return 0, err
+			}
+			endpoints[endpoint.ID] = endpoint
+		}
+		result := cfg.webhookSender.Send(ctx, webhooks.Delivery{
+			ID:        delivery.ID,
+			EventType: delivery.EventType,
+			URL:       endpoint.Url,
+			Secret:    endpoint.Secret,
+			Body:      delivery.Payload,
+		}, time.Now())
+		err = cfg.recordWebhookAttempt(ctx, delivery, result)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(claimed), nil
+}
+
+// recordWebhookAttempt logs an attempt and moves the delivery on: to
+// delivered on success, to another attempt after a backoff on failure, or to
+// dead once it has used up its attempts.
+func (cfg *apiConfig) recordWebhookAttempt(ctx context.Context, delivery database.WebhookDelivery, result webhooks.Result) error {
+	now := time.Now().UTC()
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	statusCode := sql.NullInt32{Int32: int32(result.StatusCode), Valid: result.StatusCode != 0}
+	errMsg := ""
+	if result.Err != nil {
+		errMsg = result.Err.Error()
+	}
+	err = qtx.CreateWebhookDeliveryAttempt(ctx, database.CreateWebhookDeliveryAttemptParams{
+		DeliveryID:  delivery.ID,
+		AttemptedAt: now,
+		StatusCode:  statusCode,
+		Error:       errMsg,
+		DurationMs:  int32(result.Duration.Milliseconds()),
+	})
+	if err != nil {
+		return err
+	}
+
+	attempts := delivery.Attempts + 1
+	update := database.UpdateWebhookDeliveryParams{
+		ID:             delivery.ID,
+		Status:         webhookStatusPending,
+		Attempts:       attempts,
+		NextAttemptAt:  now.Add(webhooks.Backoff(int(attempts))),
+		LastStatusCode: statusCode,
+		LastError:      errMsg,
+	}
+	switch {
+	case result.OK():
+		update.Status = webhookStatusDelivered
+		update.NextAttemptAt = now
+		update.DeliveredAt = sql.NullTime{Time: now, Valid: true}
+	case attempts >= webhooks.MaxAttempts:
+		update.Status = webhookStatusDead
+		update.NextAttemptAt = now
+	}
+	err = qtx.UpdateWebhookDelivery(ctx, update)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,644 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Throne-of-Doom/chirpy/internal/auth"
+	"github.com/Throne-of-Doom/chirpy/internal/database"
+	"github.com/Throne-of-Doom/chirpy/internal/ratelimit"
+	"github.com/Throne-of-Doom/chirpy/internal/stream"
+	"github.com/Throne-of-Doom/chirpy/internal/websocket"
+	"github.com/google/uuid"
+)
+
+const (
+	socketReadLimit    = 16 << 10
+	socketWriteTimeout = 10 * time.Second
+	// Clients are pinged every socketPingInterval and dropped if nothing,
+	// not even a pong, arrives for socketIdleTimeout.
+	socketPingInterval = 30 * time.Second
+	socketIdleTimeout  = 75 * time.Second
+	// socketCloseTimeout is how long a client has to answer a close before
+	// its connection is dropped.
+	socketCloseTimeout = 5 * time.Second
+	// socketSendBuffer is how many replies can be waiting for a client
+	// before it is disconnected for not reading them.
+	socketSendBuffer = 64
+	// socketRecentIDs is how many sent event IDs are remembered to skip
+	// events that are both replayed and published live.
+	socketRecentIDs = 2 * streamReplayLimit
+	// Clients are warned socketExpiryWarning before their access token
+	// expires, so they can send a new one.
+	socketExpiryWarning  = time.Minute
+	socketTypingInterval = 3 * time.Second
+
+	// socketCloseTokenExpired is sent when a client's access token expires
+	// without being replaced.
+	socketCloseTokenExpired = 4001
+)
+
+// socketMessageLimit limits how many messages a client can send, so that one
+// connection cannot keep the server busy.
+var socketMessageLimit = ratelimit.Limit{Burst: 20, Period: 10 * time.Second}
+
+// socketRequest is a message from a client. Ref is echoed back in the reply
+// so clients can match them up.
+type socketRequest struct {
+	Type         string          `json:"type"`
+	Ref          string          `json:"ref"`
+	Topic        string          `json:"topic"`
+	LastEventID  int64           `json:"last_event_id"`
+	Chirp        json.RawMessage `json:"chirp"`
+	ChirpID      uuid.UUID       `json:"chirp_id"`
+	Status       string          `json:"status"`
+	Token        string          `json:"token"`
+	RefreshToken string          `json:"refresh_token"`
+}
+
+// socketMessage is a message to a client: a reply, an event from a
+// subscribed topic, or a notice about the connection.
+type socketMessage struct {
+	Type      string          `json:"type"`
+	Ref       string          `json:"ref,omitempty"`
+	ID        int64           `json:"id,omitempty"`
+	Event     string          `json:"event,omitempty"`
+	Topic     string          `json:"topic,omitempty"`
+	Status    int             `json:"status,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	UserID    *uuid.UUID      `json:"user_id,omitempty"`
+	Token     string          `json:"token,omitempty"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// typingEvent and presenceEvent are sent straight to connected clients and
+// never stored, so they have no event ID and are not replayed.
+type typingEvent struct {
+	ChirpID uuid.UUID `json:"chirp_id"`
+	UserID  uuid.UUID `json:"user_id"`
+	Handle  string    `json:"handle"`
+}
+
+type presenceEvent struct {
+	UserID uuid.UUID `json:"user_id"`
+	Handle string    `json:"handle"`
+	Status string    `json:"status"`
+}
+
+// socketError is a failed request, reported to the client with status
+// instead of closing the connection.
+type socketError struct {
+	status int
+	msg    string
+}
+
+func (e *socketError) Error() string {
+	return e.msg
+}
+
+// socketFrame is a message waiting to be written. ID is the event ID for
+// replayed events and zero otherwise.
+type socketFrame struct {
+	id   int64
+	data []byte
+}
+
+type socketClose struct {
+	code   int
+	reason string
+}
+
+// socketSession is one WebSocket connection. Its reader, which runs in the
+// handler, answers the client's requests; its writer sends events, replies
+// and pings, and is the only one to close the connection on the server's
+// side.
+type socketSession struct {
+	cfg  *apiConfig
+	conn *websocket.Conn
+	// r is the upgrade request, kept for its context and client address.
+	r     *http.Request
+	user  database.User
+	mutes keywordMutes
+	sub   *stream.Subscription
+
+	out     chan socketFrame
+	closing chan socketClose
+	reauth  chan time.Time
+	done    chan struct{}
+
+	// Only the reader uses these.
+	token   string
+	topics  map[string][]string
+	bucket  ratelimit.Bucket
+	typedAt map[uuid.UUID]time.Time
+}
+
+// run serves the connection until either side closes it.
+func (s *socketSession) run(expiresAt time.Time) {
+	s.sub = s.cfg.broker.Subscribe()
+	defer s.cfg.broker.Unsubscribe(s.sub)
+	if s.cfg.presence.join(s.user.ID) {
+		s.publishPresence("online")
+	}
+	defer func() {
+		if s.cfg.presence.leave(s.user.ID) {
+			s.publishPresence("offline")
+		}
+	}()
+
+	s.conn.SetReadLimit(socketReadLimit)
+	s.conn.SetIdleTimeout(socketIdleTimeout)
+	s.conn.SetWriteTimeout(socketWriteTimeout)
+	userID := s.user.ID
+	s.reply(socketMessage{Type: "ready", UserID: &userID, ExpiresAt: &expiresAt})
+
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		s.writeLoop(expiresAt)
+	}()
+	s.readLoop()
+	close(s.done)
+	<-writerDone
+	s.conn.CloseNow()
+}
+
+func (s *socketSession) readLoop() {
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req socketRequest
+		err = json.Unmarshal(data, &req)
+		if err != nil {
+			s.reply(socketMessage{Type: "error", Status: 400, Error: "couldn't decode message"})
+			continue
+		}
+		var result ratelimit.Result
+		s.bucket, result = s.bucket.Take(socketMessageLimit, time.Now().UTC())
+		if !result.Allowed {
+			s.reply(socketMessage{Type: "error", Ref: req.Ref, Status: 429, Error: "rate limit exceeded"})
+			continue
+		}
+		err = s.handle(req)
+		if err != nil {
+			resp := socketMessage{Type: "error", Ref: req.Ref, Status: 500, Error: "internal error"}
+			var reqErr *socketError
+			if errors.As(err, &reqErr) {
+				resp.Status, resp.Error = reqErr.status, reqErr.msg
+			} else {
+				log.Printf("websocket request %q failed: %v", req.Type, err)
+			}
+			s.reply(resp)
+		}
+	}
+}
+
+func (s *socketSession) handle(req socketRequest) error {
+	switch req.Type {
+	case "ping":
+		s.reply(socketMessage{Type: "pong", Ref: req.Ref})
+		return nil
+	case "subscribe":
+		return s.subscribe(req)
+	case "unsubscribe":
+		return s.unsubscribe(req)
+	case "post":
+		return s.post(req)
+	case "typing":
+		return s.typing(req)
+	case "presence":
+		if req.Status != "online" && req.Status != "away" {
+			return &socketError{400, "status must be online or away"}
+		}
+		s.publishPresence(req.Status)
+		s.reply(socketMessage{Type: "ack", Ref: req.Ref})
+		return nil
+	case "auth":
+		return s.authenticate(req)
+	case "refresh":
+		return s.refresh(req)
+	}
+	return &socketError{400, "unknown message type"}
+}
+
+// subscribe adds a topic, first sending the events on it after
+// last_event_id if one is given.
+func (s *socketSession) subscribe(req socketRequest) error {
+	name := canonicalTopic(req.Topic)
+	if _, ok := s.topics[name]; ok {
+		return &socketError{409, "already subscribed"}
+	}
+	topics, err := s.resolveTopic(name)
+	if err != nil {
+		return err
+	}
+	// Joining before replaying means nothing published during the replay
+	// is missed; the writer skips anything sent twice.
+	s.cfg.broker.Join(s.sub, topics...)
+	s.topics[name] = topics
+	s.reply(socketMessage{Type: "ack", Ref: req.Ref, Topic: name})
+	if req.LastEventID <= 0 {
+		return nil
+	}
+	subscribed := make(map[string]bool, len(topics))
+	for _, topic := range topics {
+		subscribed[topic] = true
+	}
+	_, err = s.cfg.replayStream(s.r.Context(), s.sendReplayed, subscribed, s.mutes, req.LastEventID)
+	return err
+}
+
+// resolveTopic turns a topic a client asks for into the stream topics it
+// covers.
+func (s *socketSession) resolveTopic(topic string) ([]string, error) {
+	switch topic {
+	case "chirps":
+		return []string{streamTopicPublic}, nil
+	case "notifications":
+		return []string{notificationTopic(s.user.ID)}, nil
+	case "timeline":
+		// Follows made after subscribing apply from the next subscription.
+		followees, err := s.cfg.dbQueries.ListFolloweeIDs(s.r.Context(), s.user.ID)
+		if err != nil {
+			return nil, err
+		}
+		topics := []string{authorTopic(s.user.ID)}
+		for _, followee := range followees {
+			topics = append(topics, authorTopic(followee))
+		}
+		return topics, nil
+	}
+	idPart, ok := strings.CutPrefix(topic, "thread:")
+	if !ok {
+		return nil, &socketError{400, "unknown topic"}
+	}
+	chirpID, err := uuid.Parse(idPart)
+	if err != nil {
+		return nil, &socketError{400, "invalid chirp ID"}
+	}
+	_, err = s.cfg.dbQueries.GetChirp(s.r.Context(), database.GetChirpParams{
+		ID:       chirpID,
+		ViewerID: s.user.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &socketError{404, "chirp not found"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []string{threadTopic(chirpID)}, nil
+}
+
+func (s *socketSession) unsubscribe(req socketRequest) error {
+	name := canonicalTopic(req.Topic)
+	topics, ok := s.topics[name]
+	if !ok {
+		return &socketError{404, "not subscribed"}
+	}
+	s.cfg.broker.Leave(s.sub, topics...)
+	delete(s.topics, name)
+	s.reply(socketMessage{Type: "ack", Ref: req.Ref, Topic: name})
+	return nil
+}
+
+// canonicalTopic spells thread topics the way the server does, so a chirp
+// ID in another case still names the same thread.
+func canonicalTopic(topic string) string {
+	idPart, ok := strings.CutPrefix(topic, "thread:")
+	if !ok {
+		return topic
+	}
+	chirpID, err := uuid.Parse(idPart)
+	if err != nil {
+		return topic
+	}
+	return threadTopic(chirpID)
+}
+
+// post creates a chirp through the same handlers as POST /api/chirps, so it
+// is checked and rate limited exactly the same way, and replies with their
+// response.
+func (s *socketSession) post(req socketRequest) error {
+	httpReq, err := http.NewRequestWithContext(s.r.Context(), "POST", "/api/chirps", bytes.NewReader(req.Chirp))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+s.token)
+	httpReq.Header.Set("Content-Type", "application/json")
+	if forwarded := s.r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		httpReq.Header.Set("X-Forwarded-For", forwarded)
+	}
+	httpReq.RemoteAddr = s.r.RemoteAddr
+
+	resp := &bufferedResponse{header: http.Header{}}
+	s.cfg.middlewareRateLimit(rateLimitPost, http.HandlerFunc(s.cfg.createChirpsHandler)).ServeHTTP(resp, httpReq)
+	if resp.status >= 400 {
+		var body struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(resp.body.Bytes(), &body)
+		return &socketError{resp.status, body.Error}
+	}
+	s.reply(socketMessage{Type: "ack", Ref: req.Ref, Status: resp.status, Data: resp.body.Bytes()})
+	return nil
+}
+
+// typing tells the other clients following a thread that the user is
+// writing a reply. Clients must be subscribed to the thread, which shows
+// they can see it, and repeats within socketTypingInterval are dropped.
+func (s *socketSession) typing(req socketRequest) error {
+	topic := threadTopic(req.ChirpID)
+	if _, ok := s.topics[topic]; !ok {
+		return &socketError{400, "subscribe to the thread first"}
+	}
+	now := time.Now()
+	if now.Sub(s.typedAt[req.ChirpID]) < socketTypingInterval {
+		return nil
+	}
+	s.typedAt[req.ChirpID] = now
+	data, err := json.Marshal(typingEvent{ChirpID: req.ChirpID, UserID: s.user.ID, Handle: s.user.Handle})
+	if err != nil {
+		return err
+	}
+	s.cfg.broker.Publish(stream.Message{Event: "typing", Data: data}, topic)
+	return nil
+}
+
+// publishPresence tells the user's followers who are watching their timeline
+// on this instance that the user's status changed.
+func (s *socketSession) publishPresence(status string) {
+	data, err := json.Marshal(presenceEvent{UserID: s.user.ID, Handle: s.user.Handle, Status: status})
+	if err != nil {
+		return
+	}
+	s.cfg.broker.Publish(stream.Message{Event: "presence", Data: data}, authorTopic(s.user.ID))
+}
+
+// authenticate replaces the connection's access token with a newer one for
+// the same user, such as one from POST /api/refresh.
+func (s *socketSession) authenticate(req socketRequest) error {
+	userID, expiresAt, err := auth.ValidateJWTWithExpiry(req.Token, s.cfg.SECRET)
+	if err != nil {
+		return &socketError{401, "invalid or expired token"}
+	}
+	if userID != s.user.ID {
+		return &socketError{403, "token is for another user"}
+	}
+	s.setToken(req.Token, expiresAt)
+	s.reply(socketMessage{Type: "ack", Ref: req.Ref, ExpiresAt: &expiresAt})
+	return nil
+}
+
+// refresh issues a new access token from a refresh token, like POST
+// /api/refresh, and uses it for the connection.
+func (s *socketSession) refresh(req socketRequest) error {
+	user, err := s.cfg.dbQueries.GetUserFromRefreshToken(s.r.Context(), req.RefreshToken)
+	if err != nil {
+		return &socketError{401, "invalid or expired refresh token"}
+	}
+	if user.ID != s.user.ID {
+		return &socketError{403, "token is for another user"}
+	}
+	suspension, err := s.cfg.dbQueries.GetActiveSuspension(s.r.Context(), user.ID)
+	if err == nil {
+		return &socketError{403, suspensionMessage(suspension)}
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	token, err := auth.MakeJWT(user.ID, s.cfg.SECRET, time.Hour)
+	if err != nil {
+		return err
+	}
+	s.cfg.touchUserActivity(s.r.Context(), user.ID)
+	_, expiresAt, err := auth.ValidateJWTWithExpiry(token, s.cfg.SECRET)
+	if err != nil {
+		return err
+	}
+	s.setToken(token, expiresAt)
+	s.reply(socketMessage{Type: "token", Ref: req.Ref, Token: token, ExpiresAt: &expiresAt})
+	return nil
+}
+
+func (s *socketSession) setToken(token string, expiresAt time.Time) {
+	s.token = token
+	select {
+	case <-s.reauth:
+	default:
+	}
+	s.reauth <- expiresAt
+}
+
+// reply queues msg for the client. A client that lets socketSendBuffer
+// replies pile up is not reading, and is disconnected.
+func (s *socketSession) reply(msg socketMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("couldn't encode websocket message: %v", err)
+		return
+	}
+	select {
+	case s.out <- socketFrame{data: data}:
+	default:
+		select {
+		case s.closing <- socketClose{websocket.CloseTryAgainLater, "too many unread messages"}:
+		default:
+		}
+	}
+}
+
+// sendReplayed queues a replayed event, waiting for room so that a long
+// replay is written at the pace the client reads it.
+func (s *socketSession) sendReplayed(msg stream.Message) error {
+	data, err := json.Marshal(eventMessage(msg))
+	if err != nil {
+		return err
+	}
+	select {
+	case s.out <- socketFrame{id: msg.ID, data: data}:
+		return nil
+	case <-s.done:
+		return net.ErrClosed
+	}
+}
+
+func eventMessage(msg stream.Message) socketMessage {
+	return socketMessage{Type: "event", ID: msg.ID, Event: msg.Event, Data: msg.Data}
+}
+
+func (s *socketSession) writeLoop(expiresAt time.Time) {
+	ping := time.NewTicker(socketPingInterval)
+	defer ping.Stop()
+	warn := time.NewTimer(time.Until(expiresAt.Add(-socketExpiryWarning)))
+	defer warn.Stop()
+	expire := time.NewTimer(time.Until(expiresAt))
+	defer expire.Stop()
+	sent := newRecentIDs(socketRecentIDs)
+
+	for {
+		var err error
+		select {
+		case <-s.done:
+			return
+		case <-ping.C:
+			err = s.conn.Ping()
+		case msg, ok := <-s.sub.C:
+			if !ok {
+				if s.sub.Lagged() {
+					// The client resubscribes with last_event_id to catch up.
+					s.close(websocket.CloseTryAgainLater, "fell behind")
+				} else {
+					s.close(websocket.CloseGoingAway, "server shutting down")
+				}
+				return
+			}
+			if (msg.ID != 0 && !sent.add(msg.ID)) || streamHides(s.mutes, msg) {
+				continue
+			}
+			var data []byte
+			data, err = json.Marshal(eventMessage(msg))
+			if err == nil {
+				err = s.conn.WriteMessage(websocket.TextMessage, data)
+			}
+		case frame := <-s.out:
+			if frame.id != 0 && !sent.add(frame.id) {
+				continue
+			}
+			err = s.conn.WriteMessage(websocket.TextMessage, frame.data)
+		case req := <-s.closing:
+			s.close(req.code, req.reason)
+			return
+		case expiresAt = <-s.reauth:
+			warn.Reset(time.Until(expiresAt.Add(-socketExpiryWarning)))
+			expire.Reset(time.Until(expiresAt))
+		case <-warn.C:
+			var data []byte
+			data, err = json.Marshal(socketMessage{Type: "auth_expiring", ExpiresAt: &expiresAt})
+			if err == nil {
+				err = s.conn.WriteMessage(websocket.TextMessage, data)
+			}
+		case <-expire.C:
+			s.close(socketCloseTokenExpired, "token expired")
+			return
+		}
+		if err != nil {
+			s.conn.CloseNow()
+			return
+		}
+	}
+}
+
+// close sends a close frame and waits for the reader to see the client's
+// answer, dropping the connection if none comes.
+func (s *socketSession) close(code int, reason string) {
+	s.conn.Close(code, reason)
+	select {
+	case <-s.done:
+	case <-time.After(socketCloseTimeout):
+		s.conn.CloseNow()
+	}
+}
+
+// recentIDs remembers the last few IDs added to it.
+type recentIDs struct {
+	seen  map[int64]bool
+	order []int64
+	size  int
+}
+
+func newRecentIDs(size int) *recentIDs {
+	return &recentIDs{seen: make(map[int64]bool, size), size: size}
+}
+
+// add records id and reports whether it was new.
+func (r *recentIDs) add(id int64) bool {
+	if r.seen[id] {
+		return false
+	}
+	if len(r.order) == r.size {
+		delete(r.seen, r.order[0])
+		r.order = r.order[1:]
+	}
+	r.seen[id] = true
+	r.order = append(r.order, id)
+	return true
+}
+
+// presenceTracker counts each user's open connections on this instance.
+type presenceTracker struct {
+	mu    sync.Mutex
+	conns map[uuid.UUID]int
+}
+
+// join records a connection and reports whether it is the user's first.
+func (p *presenceTracker) join(userID uuid.UUID) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conns == nil {
+		p.conns = map[uuid.UUID]int{}
+	}
+	p.conns[userID]++
+	return p.conns[userID] == 1
+}
+
+// leave records a closed connection and reports whether it was the user's
+// last.
+func (p *presenceTracker) leave(userID uuid.UUID) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.conns[userID]--
+	if p.conns[userID] > 0 {
+		return false
+	}
+	delete(p.conns, userID)
+	return true
+}
+
+// bufferedResponse collects a response from handlers run for a WebSocket
+// request.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.WriteHeader(200)
+	return b.body.Write(p)
+}
+
+// waitForSockets waits for WebSocket connections to close after the server
+// has started shutting down, or until ctx is done.
+func (cfg *apiConfig) waitForSockets(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		cfg.sockets.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}